@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/typescript-any/llm-playground/internal/config"
+	"github.com/typescript-any/llm-playground/internal/db"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	"github.com/typescript-any/llm-playground/internal/secrets"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+// Re-encrypts every provider key under the first, active key of PROVIDER_KEY_MASTER_KEYS.
+// Run it after putting a new key first; the old key can be dropped once it is done.
+func main() {
+	cfg := config.LoadConfig()
+	if cfg.ProviderKeyMasterKeys == "" {
+		log.Fatal("PROVIDER_KEY_MASTER_KEYS is required")
+	}
+	keyring, err := secrets.ParseKeyring(cfg.ProviderKeyMasterKeys)
+	if err != nil {
+		log.Fatalf("Invalid PROVIDER_KEY_MASTER_KEYS: %v", err)
+	}
+
+	db.Init(cfg)
+	defer db.Close()
+
+	keys := service.NewProviderKeyService(repository.NewProviderKeyRepo(db.GetPool()), nil, keyring)
+	rotated, err := keys.Rotate(context.Background())
+	if err != nil {
+		log.Fatalf("Rotation failed after %d key(s): %v", rotated, err)
+	}
+	fmt.Printf("Re-encrypted %d provider key(s) under master key %q\n", rotated, keyring.ActiveKeyID())
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
 
 	"github.com/typescript-any/llm-playground/internal/app"
 	"github.com/typescript-any/llm-playground/internal/config"
@@ -11,7 +12,7 @@ import (
 func main() {
 	// Load config
 	cfg := config.LoadConfig()
-	fiberApp, pool := app.SetupApp(cfg)
+	fiberApp, grpcServer, pool := app.SetupApp(cfg)
 
 	addr := fmt.Sprintf(":%s", cfg.Port)
 	started := make(chan bool)
@@ -26,5 +27,18 @@ func main() {
 	<-started
 	log.Printf("🚀 Server is running on http://localhost:%s", cfg.Port)
 
-	app.GracefulShutdown(fiberApp, pool)
+	if grpcServer != nil {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.GRPCPort))
+		if err != nil {
+			log.Fatalf("❌ Failed to listen for gRPC: %v", err)
+		}
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("❌ Failed to start gRPC server: %v", err)
+			}
+		}()
+		log.Printf("🚀 gRPC server is running on localhost:%s", cfg.GRPCPort)
+	}
+
+	app.GracefulShutdown(fiberApp, grpcServer, pool)
 }
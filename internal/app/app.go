@@ -5,23 +5,33 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/auth"
 	"github.com/typescript-any/llm-playground/internal/config"
 	"github.com/typescript-any/llm-playground/internal/db"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/grpcapi"
 	"github.com/typescript-any/llm-playground/internal/handler"
 	"github.com/typescript-any/llm-playground/internal/llm"
 	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/pubsub"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
 	"github.com/typescript-any/llm-playground/internal/repository"
 	"github.com/typescript-any/llm-playground/internal/routes"
+	"github.com/typescript-any/llm-playground/internal/secrets"
 	service "github.com/typescript-any/llm-playground/internal/services"
+	"github.com/typescript-any/llm-playground/internal/streaming"
+	"google.golang.org/grpc"
 )
 
-func SetupApp(cfg *config.Config) (*fiber.App, *pgxpool.Pool) {
+// SetupApp builds the HTTP app and, unless GRPC_PORT is empty, the gRPC server that runs alongside it
+func SetupApp(cfg *config.Config) (*fiber.App, *grpc.Server, *pgxpool.Pool) {
 	db.Init(cfg)
 	pool := db.GetPool()
 	if pool == nil {
@@ -32,21 +42,162 @@ func SetupApp(cfg *config.Config) (*fiber.App, *pgxpool.Pool) {
 
 	convRepo := repository.NewConversationRepo(pool)
 	messageRepo := repository.NewMessageRepo(pool)
+	summaryRepo := repository.NewSummaryRepo(pool)
+	userRepo := repository.NewUserRepo(pool)
+	apiKeyRepo := repository.NewAPIKeyRepo(pool)
+	orgRepo := repository.NewOrganizationRepo(pool)
+	workspaceRepo := repository.NewWorkspaceRepo(pool)
 
-	convService := service.NewConversationService(convRepo)
-	messageService := service.NewMessageService(messageRepo, openAiClient)
+	userService := service.NewUserService(userRepo, cfg.SessionTTL)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo)
+	orgService := service.NewOrganizationService(orgRepo, workspaceRepo, userRepo)
 
-	convHandler := handler.NewConversationHandler(convService, messageService)
-	messageHandler := handler.NewMessageHandler(messageService)
+	// Accept JWTs from an identity provider besides our own login tokens
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.JWTAlgorithm != "" {
+		var err error
+		jwtVerifier, err = auth.NewJWTVerifier(auth.JWTConfig{
+			Algorithm:   cfg.JWTAlgorithm,
+			Secret:      cfg.JWTSecret,
+			JWKSURL:     cfg.JWKSURL,
+			JWKSFile:    cfg.JWKSFile,
+			JWKSRefresh: cfg.JWKSRefresh,
+			Issuer:      cfg.JWTIssuer,
+			Audience:    cfg.JWTAudience,
+			Leeway:      cfg.JWTLeeway,
+			RolesClaim:  cfg.JWTRolesClaim,
+		})
+		if err != nil {
+			log.Fatalf("❌ Invalid JWT configuration: %v", err)
+		}
+	}
+	authService := service.NewAuthService(userRepo, userService, apiKeyService, jwtVerifier, cfg.AdminUserIDs)
+
+	// Let employees sign in with the company identity provider
+	var oidcHandler *handler.OIDCHandler
+	if cfg.OIDCIssuerURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		oidcClient, err := auth.NewOIDC(ctx, auth.OIDCConfig{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+			LoginTTL:     cfg.OIDCLoginTTL,
+		}, repository.NewOIDCLoginRepo(pool))
+		cancel()
+		if err != nil {
+			log.Fatalf("❌ Invalid OIDC configuration: %v", err)
+		}
+		secureCookie := strings.HasPrefix(cfg.OIDCRedirectURL, "https://")
+		oidcHandler = handler.NewOIDCHandler(oidcClient, authService, cfg.OIDCPostLoginURL, secureCookie)
+	}
+
+	access := service.NewAccess(convRepo, workspaceRepo)
+	modelService := service.NewModelService(repository.NewModelRepo(pool))
+	usageService := service.NewUsageService(repository.NewUsageRepo(pool), modelService)
+	// Let users and organizations pay for their own calls
+	var providerKeyService *service.ProviderKeyService
+	if cfg.ProviderKeyMasterKeys != "" {
+		keyring, err := secrets.ParseKeyring(cfg.ProviderKeyMasterKeys)
+		if err != nil {
+			log.Fatalf("❌ Invalid PROVIDER_KEY_MASTER_KEYS: %v", err)
+		}
+		providerKeyService = service.NewProviderKeyService(repository.NewProviderKeyRepo(pool), orgRepo, keyring)
+	}
+	quotaService := service.NewQuotaService(repository.NewQuotaRepo(pool), modelService)
+	summaryService := service.NewSummaryService(summaryRepo, messageRepo, access, providerKeyService, quotaService, usageService, openAiClient, service.SummaryConfig{
+		Model:      cfg.SummaryModel,
+		KeepRecent: cfg.SummaryKeepRecent,
+		BatchSize:  cfg.SummaryBatchSize,
+	})
+	convService := service.NewConversationService(convRepo, messageRepo, access, providerKeyService, modelService, quotaService, usageService, openAiClient, cfg.TitleModel)
+	messageService := service.NewMessageService(messageRepo, access, summaryService, providerKeyService, modelService, quotaService, usageService, openAiClient)
+
+	generations := generation.NewManager(generation.Config{
+		ReplayWindow:         cfg.StreamReplayWindow,
+		ContinueOnDisconnect: cfg.StreamDisconnectMode == "background",
+		DisconnectGrace:      cfg.StreamDisconnectGrace,
+		MaxGenerations:       cfg.StreamMaxGenerations,
+		MaxSubscribers:       cfg.StreamMaxSubscribers,
+	})
+
+	// Share generations with the other replicas so any of them can serve any stream
+	var relay *pubsub.Postgres
+	if cfg.StreamRelay == "postgres" {
+		relay = pubsub.NewPostgres(pool, repository.NewStreamPayloadRepo(pool), generations, pubsub.Config{
+			Channel:          cfg.StreamRelayChannel,
+			InstanceID:       cfg.InstanceID,
+			PayloadRetention: cfg.StreamReplayWindow,
+		})
+		relay.Start()
+	}
+
+	// Keep any one user, API key or IP from flooding the provider
+	var limiter *ratelimit.Limiter
+	var limitStore *ratelimit.Postgres
+	switch cfg.RateLimitStore {
+	case "memory":
+		limiter = ratelimit.New(ratelimit.NewMemory(), rateLimitConfig(cfg))
+	case "postgres":
+		limitStore = ratelimit.NewPostgres(pool, cfg.RateLimitStreamLease)
+		limitStore.Start()
+		limiter = ratelimit.New(limitStore, rateLimitConfig(cfg))
+	}
+
+	engine := streaming.NewEngine(messageService, generations, limiter, streaming.Options{
+		UpstreamIdleTimeout: cfg.StreamUpstreamIdleTimeout,
+		HeartbeatInterval:   cfg.StreamHeartbeatInterval,
+		CoalesceWindow:      cfg.StreamCoalesceWindow,
+		CoalesceBytes:       cfg.StreamCoalesceBytes,
+	})
+
+	userHandler := handler.NewUserHandler(userService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	orgHandler := handler.NewOrganizationHandler(orgService)
+	quotaHandler := handler.NewQuotaHandler(quotaService)
+	modelHandler := handler.NewModelHandler(modelService)
+	usageHandler := handler.NewUsageHandler(usageService)
+	var providerKeyHandler *handler.ProviderKeyHandler
+	if providerKeyService != nil {
+		providerKeyHandler = handler.NewProviderKeyHandler(providerKeyService)
+	}
+	convHandler := handler.NewConversationHandler(convService, messageService, engine)
+	messageHandler := handler.NewMessageHandler(messageService, convService, engine)
+	generationHandler := handler.NewGenerationHandler(convService, engine)
+	wsHandler := handler.NewWebSocketHandler(messageService, convService, engine, limiter)
+	summaryHandler := handler.NewSummaryHandler(summaryService, engine)
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: middleware.ErrorHandler,
+		// Behind a proxy, limit the client IP rather than the proxy's
+		ProxyHeader:        cfg.ProxyHeader,
+		EnableIPValidation: cfg.ProxyHeader != "",
 	})
+	if jwtVerifier != nil {
+		app.Hooks().OnShutdown(func() error {
+			jwtVerifier.Close()
+			return nil
+		})
+	}
+	if relay != nil {
+		// Release the LISTEN connection before the pool is closed
+		app.Hooks().OnShutdown(func() error {
+			relay.Close()
+			return nil
+		})
+	}
+	if limitStore != nil {
+		app.Hooks().OnShutdown(func() error {
+			limitStore.Close()
+			return nil
+		})
+	}
 	// app.Use(middleware.RequestResponseLogger)
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*", // or "http://localhost:3000" for your frontend
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, Last-Event-ID",
+		AllowMethods: "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 	}))
 
 	// Health check endpoint (without /api prefix)
@@ -55,12 +206,45 @@ func SetupApp(cfg *config.Config) (*fiber.App, *pgxpool.Pool) {
 
 	// Create API group with /api prefix
 	api := app.Group("/api")
-	routes.RegisterConversationRoutes(api, convHandler, messageHandler)
+	if limiter != nil {
+		api.Use(middleware.RateLimitIP(limiter))
+	}
+	authMiddleware := middleware.AuthMiddleware(authService, limiter)
+	routes.RegisterUserRoutes(api, authMiddleware, userHandler)
+	if oidcHandler != nil {
+		routes.RegisterOIDCRoutes(api, oidcHandler)
+	}
+	routes.RegisterAPIKeyRoutes(api, authMiddleware, apiKeyHandler)
+	if providerKeyHandler != nil {
+		routes.RegisterProviderKeyRoutes(api, authMiddleware, providerKeyHandler)
+	}
+	routes.RegisterOrganizationRoutes(api, authMiddleware, orgHandler)
+	routes.RegisterQuotaRoutes(api, authMiddleware, quotaHandler, modelHandler)
+	routes.RegisterUsageRoutes(api, authMiddleware, usageHandler)
+	routes.RegisterConversationRoutes(api, authMiddleware, convHandler, messageHandler, summaryHandler)
+	routes.RegisterGenerationRoutes(api, authMiddleware, generationHandler)
+	routes.RegisterWebSocketRoutes(api, authMiddleware, wsHandler)
 
-	return app, pool
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		grpcServer = grpcapi.NewGRPCServer(grpcapi.NewServer(authService, convService, messageService, engine, limiter))
+	}
+
+	return app, grpcServer, pool
+}
+
+func rateLimitConfig(cfg *config.Config) ratelimit.Config {
+	return ratelimit.Config{
+		UserRequests: cfg.RateLimitUserRequests,
+		KeyRequests:  cfg.RateLimitKeyRequests,
+		IPRequests:   cfg.RateLimitIPRequests,
+		UserStreams:  cfg.RateLimitUserStreams,
+		KeyStreams:   cfg.RateLimitKeyStreams,
+		IPStreams:    cfg.RateLimitIPStreams,
+	}
 }
 
-func GracefulShutdown(app *fiber.App, pool *pgxpool.Pool) {
+func GracefulShutdown(app *fiber.App, grpcServer *grpc.Server, pool *pgxpool.Pool) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -69,10 +253,27 @@ func GracefulShutdown(app *fiber.App, pool *pgxpool.Pool) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	grpcStopped := make(chan struct{})
+	go func() {
+		defer close(grpcStopped)
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+	}()
+
 	if err := app.ShutdownWithContext(ctx); err != nil {
 		log.Fatalf("❌ Server shutdown failed: %v", err)
 	}
 
+	// gRPC streams still open when the deadline passes are cut off
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+	}
+
 	if pool != nil {
 		pool.Close()
 	}
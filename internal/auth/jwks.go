@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// minRefetchInterval keeps tokens with unknown key ids from hammering the JWKS endpoint
+const minRefetchInterval = 30 * time.Second
+
+// jwk is one key of a JSON Web Key Set. Only RSA keys are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keySet holds the public keys of a JWKS URL or file and reloads them periodically
+type keySet struct {
+	url    string
+	file   string
+	client *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	lastFetched time.Time
+}
+
+func newKeySet(url, file string) *keySet {
+	return &keySet{
+		url:    url,
+		file:   file,
+		client: &http.Client{Timeout: 10 * time.Second},
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+// key returns the key with id kid. A token without kid may use the only key of the set.
+// An unknown kid triggers a reload, as the issuer may have rotated its keys.
+func (s *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if k, ok := s.lookup(kid); ok {
+		return k, nil
+	}
+
+	s.mu.RLock()
+	recent := time.Since(s.lastFetched) < minRefetchInterval
+	s.mu.RUnlock()
+	if !recent {
+		if err := s.load(ctx); err != nil {
+			return nil, err
+		}
+		if k, ok := s.lookup(kid); ok {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("no signing key with kid %q", kid)
+}
+
+func (s *keySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+// load fetches the key set and replaces the current keys
+func (s *keySet) load(ctx context.Context) error {
+	s.mu.Lock()
+	s.lastFetched = time.Now()
+	s.mu.Unlock()
+
+	raw, err := s.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return fmt.Errorf("decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pub, err := k.rsaKey()
+		if err != nil {
+			log.Printf("Skipping JWKS key %q: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS has no usable RSA signing keys")
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *keySet) read(ctx context.Context) ([]byte, error) {
+	if s.file != "" {
+		return os.ReadFile(s.file)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// refresh reloads the keys every interval until ctx is done. A failed reload keeps the previous keys.
+func (s *keySet) refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.load(ctx); err != nil {
+				log.Printf("Error refreshing JWKS: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
@@ -0,0 +1,163 @@
+// Package auth verifies the credentials of external identity providers: JWTs sent as
+// bearer tokens and OpenID Connect sign-ins.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken wraps every reason a JWT is rejected
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWTConfig configures JWT verification
+type JWTConfig struct {
+	// Algorithm is HS256, verified with Secret, or RS256, verified with the keys of JWKSURL or JWKSFile
+	Algorithm string
+	Secret    string
+	JWKSURL   string
+	JWKSFile  string
+	// JWKSRefresh is how often the key set is reloaded
+	JWKSRefresh time.Duration
+	// Issuer and Audience must match the iss and aud claims when set
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew tolerated on exp, nbf and iat
+	Leeway time.Duration
+	// RolesClaim names the claim holding the roles, with dots for nested claims (realm_access.roles)
+	RolesClaim string
+}
+
+// Claims is the identity a verified JWT carries
+type Claims struct {
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+	Roles   []string
+}
+
+// JWTVerifier checks the signature and registered claims of JWTs
+type JWTVerifier struct {
+	cfg    JWTConfig
+	parser *jwt.Parser
+	keys   *keySet
+	cancel context.CancelFunc
+}
+
+// NewJWTVerifier validates cfg and, for RS256, loads the key set and starts refreshing it
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{cfg.Algorithm}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v := &JWTVerifier{cfg: cfg, parser: jwt.NewParser(opts...), cancel: func() {}}
+
+	switch cfg.Algorithm {
+	case "HS256":
+		if len(cfg.Secret) < 32 {
+			return nil, errors.New("HS256 needs a secret of at least 32 bytes")
+		}
+	case "RS256":
+		if (cfg.JWKSURL == "") == (cfg.JWKSFile == "") {
+			return nil, errors.New("RS256 needs exactly one of a JWKS URL or a JWKS file")
+		}
+		v.keys = newKeySet(cfg.JWKSURL, cfg.JWKSFile)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := v.keys.load(ctx); err != nil {
+			return nil, err
+		}
+		if cfg.JWKSRefresh > 0 {
+			var refreshCtx context.Context
+			refreshCtx, v.cancel = context.WithCancel(context.Background())
+			go v.keys.refresh(refreshCtx, cfg.JWKSRefresh)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+	return v, nil
+}
+
+// Close stops refreshing the key set
+func (v *JWTVerifier) Close() {
+	v.cancel()
+}
+
+// LooksLikeJWT tells a JWT apart from the opaque tokens the API issues
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify checks a JWT and returns its identity
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (Claims, error) {
+	var claims jwt.MapClaims
+	_, err := v.parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		if v.keys == nil {
+			return []byte(v.cfg.Secret), nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	out := Claims{Roles: stringsClaim(lookupClaim(claims, v.cfg.RolesClaim))}
+	out.Issuer, _ = claims.GetIssuer()
+	out.Subject, _ = claims.GetSubject()
+	out.Email, _ = claims["email"].(string)
+	out.Name, _ = claims["name"].(string)
+	if out.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+	return out, nil
+}
+
+// lookupClaim follows a dotted path through nested claims
+func lookupClaim(claims map[string]any, path string) any {
+	if path == "" {
+		return nil
+	}
+	var cur any = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// stringsClaim reads a claim that is a list of strings or a space separated string
+func stringsClaim(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://id.example.com"
+	testAudience = "playground-api"
+)
+
+// mockJWKS serves a key set that tests can rotate, counting the fetches
+type mockJWKS struct {
+	server  *httptest.Server
+	fetches atomic.Int32
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey
+}
+
+func newMockJWKS(t *testing.T, kids ...string) *mockJWKS {
+	t.Helper()
+	s := &mockJWKS{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		s.add(t, kid)
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var keys []map[string]string
+		for kid, key := range s.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// add generates a key and publishes it under kid
+func (s *mockJWKS) add(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = key
+	return key
+}
+
+func (s *mockJWKS) key(kid string) *rsa.PrivateKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[kid]
+}
+
+// newTestJWT returns an RS256 verifier of the mock key set
+func newTestJWT(t *testing.T, s *mockJWKS) *JWTVerifier {
+	t.Helper()
+	v, err := NewJWTVerifier(JWTConfig{
+		Algorithm:  "RS256",
+		JWKSURL:    s.server.URL,
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		Leeway:     time.Minute,
+		RolesClaim: "realm_access.roles",
+	})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+	t.Cleanup(v.Close)
+	return v
+}
+
+// testClaims are the claims of a valid token, with overrides applied; a nil override
+// removes the claim
+func testClaims(overrides jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":          testIssuer,
+		"sub":          "employee-42",
+		"aud":          testAudience,
+		"exp":          now.Add(time.Hour).Unix(),
+		"iat":          now.Unix(),
+		"email":        "ada@example.com",
+		"name":         "Ada Lovelace",
+		"realm_access": map[string]any{"roles": []any{"admin", "member"}},
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+// signRS256 signs claims with key, naming kid in the header unless it is empty
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWTVerify(t *testing.T) {
+	s := newMockJWKS(t, "key-1")
+	v := newTestJWT(t, s)
+
+	claims, err := v.Verify(context.Background(), signRS256(t, s.key("key-1"), "key-1", testClaims(nil)))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	want := Claims{Issuer: testIssuer, Subject: "employee-42", Email: "ada@example.com", Name: "Ada Lovelace", Roles: []string{"admin", "member"}}
+	if claims.Issuer != want.Issuer || claims.Subject != want.Subject || claims.Email != want.Email ||
+		claims.Name != want.Name || !slices.Equal(claims.Roles, want.Roles) {
+		t.Fatalf("got claims %+v, want %+v", claims, want)
+	}
+}
+
+func TestJWTRoles(t *testing.T) {
+	s := newMockJWKS(t, "key-1")
+	v := newTestJWT(t, s)
+
+	cases := []struct {
+		name  string
+		realm any
+		want  []string
+	}{
+		{"list", map[string]any{"roles": []any{"admin", 7, "member"}}, []string{"admin", "member"}},
+		{"space separated", map[string]any{"roles": "admin member"}, []string{"admin", "member"}},
+		{"not nested", []any{"admin"}, nil},
+		{"missing", map[string]any{}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := signRS256(t, s.key("key-1"), "key-1", testClaims(jwt.MapClaims{"realm_access": tc.realm}))
+			claims, err := v.Verify(context.Background(), token)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if !slices.Equal(claims.Roles, tc.want) {
+				t.Fatalf("got roles %v, want %v", claims.Roles, tc.want)
+			}
+		})
+	}
+}
+
+func TestJWTLeeway(t *testing.T) {
+	s := newMockJWKS(t, "key-1")
+	v := newTestJWT(t, s)
+
+	cases := []struct {
+		name  string
+		exp   time.Duration // from now
+		valid bool
+	}{
+		{"expired within the leeway", -30 * time.Second, true},
+		{"expired past the leeway", -2 * time.Minute, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := signRS256(t, s.key("key-1"), "key-1", testClaims(jwt.MapClaims{"exp": time.Now().Add(tc.exp).Unix()}))
+			_, err := v.Verify(context.Background(), token)
+			if tc.valid && err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if !tc.valid && !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("got error %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestJWTRejected(t *testing.T) {
+	s := newMockJWKS(t, "key-1")
+	v := newTestJWT(t, s)
+
+	cases := []struct {
+		name  string
+		token func(t *testing.T) string
+	}{
+		{"alg none", func(t *testing.T) string {
+			token := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims(nil))
+			token.Header["kid"] = "key-1"
+			signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signed
+		}},
+		{"HS256 keyed with the public key", func(t *testing.T) string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims(nil))
+			token.Header["kid"] = "key-1"
+			signed, err := token.SignedString(s.key("key-1").N.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signed
+		}},
+		{"signed by another key", func(t *testing.T) string {
+			other, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signRS256(t, other, "key-1", testClaims(nil))
+		}},
+		{"missing exp", func(t *testing.T) string {
+			return signRS256(t, s.key("key-1"), "key-1", testClaims(jwt.MapClaims{"exp": nil}))
+		}},
+		{"wrong issuer", func(t *testing.T) string {
+			return signRS256(t, s.key("key-1"), "key-1", testClaims(jwt.MapClaims{"iss": "https://evil.example.com"}))
+		}},
+		{"wrong audience", func(t *testing.T) string {
+			return signRS256(t, s.key("key-1"), "key-1", testClaims(jwt.MapClaims{"aud": "another-api"}))
+		}},
+		{"missing sub", func(t *testing.T) string {
+			return signRS256(t, s.key("key-1"), "key-1", testClaims(jwt.MapClaims{"sub": nil}))
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tc.token(t))
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("got error %v, want ErrInvalidToken", err)
+			}
+			if claims.Subject != "" {
+				t.Fatalf("verified as %q", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestJWKSUnknownKid(t *testing.T) {
+	s := newMockJWKS(t, "key-1")
+	v := newTestJWT(t, s)
+	ctx := context.Background()
+
+	// The issuer rotates in a key right after the verifier loaded the set
+	token := signRS256(t, s.add(t, "key-2"), "key-2", testClaims(nil))
+	if _, err := v.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("got error %v, want ErrInvalidToken within minRefetchInterval", err)
+	}
+	if got := s.fetches.Load(); got != 1 {
+		t.Fatalf("fetched the key set %d times, want no reload within minRefetchInterval", got)
+	}
+
+	// Once minRefetchInterval has passed, the unknown kid reloads the set
+	v.keys.mu.Lock()
+	v.keys.lastFetched = time.Now().Add(-minRefetchInterval)
+	v.keys.mu.Unlock()
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify after the reload: %v", err)
+	}
+	if got := s.fetches.Load(); got != 2 {
+		t.Fatalf("fetched the key set %d times, want 2", got)
+	}
+
+	// Forged kids do not reload it again
+	for i := 0; i < 3; i++ {
+		forged := signRS256(t, s.key("key-1"), "forged", testClaims(nil))
+		if _, err := v.Verify(ctx, forged); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("got error %v, want ErrInvalidToken", err)
+		}
+	}
+	if got := s.fetches.Load(); got != 2 {
+		t.Fatalf("fetched the key set %d times, want no reload within minRefetchInterval", got)
+	}
+}
+
+func TestJWTWithoutKid(t *testing.T) {
+	s := newMockJWKS(t, "key-1")
+	v := newTestJWT(t, s)
+	ctx := context.Background()
+
+	// The only key of the set signs tokens without kid
+	if _, err := v.Verify(ctx, signRS256(t, s.key("key-1"), "", testClaims(nil))); err != nil {
+		t.Fatalf("Verify with a single key: %v", err)
+	}
+
+	// With more keys the verifier cannot tell which one is meant
+	s.add(t, "key-2")
+	if err := v.keys.load(ctx); err != nil {
+		t.Fatalf("reloading the key set: %v", err)
+	}
+	for _, kid := range []string{"key-1", "key-2"} {
+		token := signRS256(t, s.key(kid), "", testClaims(nil))
+		if _, err := v.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("token of %s without kid: got error %v, want ErrInvalidToken", kid, err)
+		}
+	}
+}
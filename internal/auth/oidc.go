@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidLogin wraps every reason an OIDC callback is rejected
+var ErrInvalidLogin = errors.New("invalid sign-in")
+
+// OIDCConfig configures sign-in with an OpenID Connect provider
+type OIDCConfig struct {
+	// IssuerURL is where the provider publishes /.well-known/openid-configuration
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string // empty for public clients, which rely on PKCE alone
+	RedirectURL  string
+	Scopes       []string
+	// LoginTTL is how long a sign-in may take between the redirect and the callback
+	LoginTTL time.Duration
+}
+
+// LoginStore keeps pending sign-ins between the redirect and the callback, so the
+// callback may reach any replica
+type LoginStore interface {
+	SaveLogin(ctx context.Context, login models.OIDCLogin) error
+	// TakeLogin returns and forgets the sign-in of a state hash
+	TakeLogin(ctx context.Context, stateHash string) (models.OIDCLogin, error)
+}
+
+// OIDC runs the authorization code flow with PKCE against an OpenID Connect provider
+type OIDC struct {
+	cfg      OIDCConfig
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	logins   LoginStore
+}
+
+// NewOIDC discovers the endpoints and signing keys of the provider
+func NewOIDC(ctx context.Context, cfg OIDCConfig, logins LoginStore) (*OIDC, error) {
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("OIDC needs a client ID and a redirect URL")
+	}
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	return &OIDC{
+		cfg: cfg,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		logins:   logins,
+	}, nil
+}
+
+// LoginTTL is how long a sign-in may take
+func (o *OIDC) LoginTTL() time.Duration {
+	return o.cfg.LoginTTL
+}
+
+// Begin starts a sign-in and returns the provider URL to send the browser to, with the
+// state the callback has to bring back
+func (o *OIDC) Begin(ctx context.Context) (authURL, state string, err error) {
+	if state, err = randomToken(); err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	err = o.logins.SaveLogin(ctx, models.OIDCLogin{
+		StateHash:    hashState(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(o.cfg.LoginTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return o.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// Finish completes the sign-in of state: it redeems code with the PKCE verifier and
+// verifies the ID token it gets back. Errors of the LoginStore are returned as they are.
+func (o *OIDC) Finish(ctx context.Context, state, code string) (Claims, error) {
+	if state == "" || code == "" {
+		return Claims{}, fmt.Errorf("%w: missing state or code", ErrInvalidLogin)
+	}
+	login, err := o.logins.TakeLogin(ctx, hashState(state))
+	if err != nil {
+		return Claims{}, err
+	}
+	if time.Now().After(login.ExpiresAt) {
+		return Claims{}, fmt.Errorf("%w: sign-in expired", ErrInvalidLogin)
+	}
+
+	token, err := o.oauth.Exchange(ctx, code, oauth2.VerifierOption(login.CodeVerifier))
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: code exchange failed: %v", ErrInvalidLogin, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return Claims{}, fmt.Errorf("%w: no id_token in token response", ErrInvalidLogin)
+	}
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidLogin, err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return Claims{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidLogin)
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidLogin, err)
+	}
+	out := Claims{Issuer: idToken.Issuer, Subject: idToken.Subject, Name: claims.Name}
+	// An address the provider says is unverified is not taken on
+	if claims.EmailVerified == nil || *claims.EmailVerified {
+		out.Email = claims.Email
+	}
+	return out, nil
+}
+
+// randomToken returns 32 random bytes, base64url encoded
+func randomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,350 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+const (
+	testClientID     = "playground"
+	testClientSecret = "playground-secret"
+	testRedirectURL  = "http://localhost:4000/api/auth/oidc/callback"
+)
+
+// errLoginNotFound is what memoryLogins reports for an unknown or used state
+var errLoginNotFound = errors.New("login not found")
+
+// memoryLogins keeps pending sign-ins in memory
+type memoryLogins struct {
+	mu     sync.Mutex
+	logins map[string]models.OIDCLogin
+}
+
+func (m *memoryLogins) SaveLogin(ctx context.Context, login models.OIDCLogin) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logins[login.StateHash] = login
+	return nil
+}
+
+func (m *memoryLogins) TakeLogin(ctx context.Context, stateHash string) (models.OIDCLogin, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	login, ok := m.logins[stateHash]
+	if !ok {
+		return models.OIDCLogin{}, errLoginNotFound
+	}
+	delete(m.logins, stateHash)
+	return login, nil
+}
+
+// update changes the pending sign-in of state in place
+func (m *memoryLogins) update(state string, change func(*models.OIDCLogin)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	login := m.logins[hashState(state)]
+	change(&login)
+	m.logins[hashState(state)] = login
+}
+
+// authRequest is what the mock provider remembers about an authorization code
+type authRequest struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+// mockProvider is an in-process OpenID Connect provider that signs in one user
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+	// claims are added to every ID token and override the defaults
+	claims jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockProvider{key: key, codes: map[string]authRequest{}, claims: jwt.MapClaims{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := p.server.URL
+	json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize signs the user in straight away and redirects back with a code
+func (p *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != testClientID ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" ||
+		!strings.Contains(q.Get("scope"), "openid") {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code, _ := randomToken()
+	p.mu.Lock()
+	p.codes[code] = authRequest{redirectURI: q.Get("redirect_uri"), challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	p.mu.Unlock()
+
+	back, _ := url.Parse(q.Get("redirect_uri"))
+	back.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, back.String(), http.StatusFound)
+}
+
+// token redeems a code once, checking the client and the PKCE verifier
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	tokenError := func(code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+
+	r.ParseForm()
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != testClientID || secret != testClientSecret {
+		tokenError("invalid_client")
+		return
+	}
+
+	p.mu.Lock()
+	req, found := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if r.PostForm.Get("grant_type") != "authorization_code" || !found ||
+		r.PostForm.Get("redirect_uri") != req.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		tokenError("invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            "employee-42",
+		"aud":            testClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          req.nonce,
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"name":           "Ada Lovelace",
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// newTestOIDC discovers the mock provider
+func newTestOIDC(t *testing.T, p *mockProvider) (*OIDC, *memoryLogins) {
+	t.Helper()
+	logins := &memoryLogins{logins: map[string]models.OIDCLogin{}}
+	o, err := NewOIDC(context.Background(), OIDCConfig{
+		IssuerURL:    p.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		LoginTTL:     time.Minute,
+	}, logins)
+	if err != nil {
+		t.Fatalf("NewOIDC: %v", err)
+	}
+	return o, logins
+}
+
+// authorize starts a sign-in, follows the browser to the provider and returns the
+// state and code the provider sends back to the callback
+func authorize(t *testing.T, o *OIDC) (state, code string) {
+	t.Helper()
+	authURL, state, err := o.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := browser.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize answered %d", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := callback.Scheme + "://" + callback.Host + callback.Path; got != testRedirectURL {
+		t.Fatalf("redirected to %s, want %s", got, testRedirectURL)
+	}
+	if callback.Query().Get("state") != state {
+		t.Fatalf("provider returned state %q, want %q", callback.Query().Get("state"), state)
+	}
+	return state, callback.Query().Get("code")
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	p := newMockProvider(t)
+	o, _ := newTestOIDC(t, p)
+	ctx := context.Background()
+
+	state, code := authorize(t, o)
+	claims, err := o.Finish(ctx, state, code)
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	want := Claims{Issuer: p.server.URL, Subject: "employee-42", Email: "ada@example.com", Name: "Ada Lovelace"}
+	if claims.Issuer != want.Issuer || claims.Subject != want.Subject || claims.Email != want.Email || claims.Name != want.Name {
+		t.Fatalf("got claims %+v, want %+v", claims, want)
+	}
+
+	// The state is single use
+	if _, err := o.Finish(ctx, state, code); !errors.Is(err, errLoginNotFound) {
+		t.Fatalf("replayed callback: got error %v, want errLoginNotFound", err)
+	}
+}
+
+func TestOIDCUnverifiedEmail(t *testing.T) {
+	p := newMockProvider(t)
+	p.claims["email_verified"] = false
+	o, _ := newTestOIDC(t, p)
+
+	state, code := authorize(t, o)
+	claims, err := o.Finish(context.Background(), state, code)
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if claims.Email != "" {
+		t.Fatalf("took on unverified email %q", claims.Email)
+	}
+}
+
+func TestOIDCRejectedCallbacks(t *testing.T) {
+	cases := []struct {
+		name string
+		// setup runs between the redirect to the provider and the callback
+		setup func(p *mockProvider, logins *memoryLogins, state, code *string)
+		want  error
+	}{
+		{"unknown state", func(p *mockProvider, logins *memoryLogins, state, code *string) {
+			*state = "forged"
+		}, errLoginNotFound},
+		{"missing code", func(p *mockProvider, logins *memoryLogins, state, code *string) {
+			*code = ""
+		}, ErrInvalidLogin},
+		{"unknown code", func(p *mockProvider, logins *memoryLogins, state, code *string) {
+			*code = "forged"
+		}, ErrInvalidLogin},
+		{"wrong PKCE verifier", func(p *mockProvider, logins *memoryLogins, state, code *string) {
+			logins.update(*state, func(l *models.OIDCLogin) { l.CodeVerifier = strings.Repeat("x", 43) })
+		}, ErrInvalidLogin},
+		{"expired sign-in", func(p *mockProvider, logins *memoryLogins, state, code *string) {
+			logins.update(*state, func(l *models.OIDCLogin) { l.ExpiresAt = time.Now().Add(-time.Second) })
+		}, ErrInvalidLogin},
+		{"nonce mismatch", func(p *mockProvider, logins *memoryLogins, state, code *string) {
+			p.claims["nonce"] = "replayed"
+		}, ErrInvalidLogin},
+		{"wrong audience", func(p *mockProvider, logins *memoryLogins, state, code *string) {
+			p.claims["aud"] = "another-client"
+		}, ErrInvalidLogin},
+		{"wrong issuer", func(p *mockProvider, logins *memoryLogins, state, code *string) {
+			p.claims["iss"] = "https://evil.example.com"
+		}, ErrInvalidLogin},
+		{"expired ID token", func(p *mockProvider, logins *memoryLogins, state, code *string) {
+			p.claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		}, ErrInvalidLogin},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newMockProvider(t)
+			o, logins := newTestOIDC(t, p)
+
+			state, code := authorize(t, o)
+			tc.setup(p, logins, &state, &code)
+			claims, err := o.Finish(context.Background(), state, code)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+			if claims.Subject != "" {
+				t.Fatalf("signed in as %q", claims.Subject)
+			}
+		})
+	}
+}
@@ -1,17 +1,68 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
 )
 
 type Config struct {
-	Port                  string
-	DatabaseURL           string
-	OpenRouterApiEndpoint string
-	OpenRouterApiKey      string
+	Port                      string
+	GRPCPort                  string
+	DatabaseURL               string
+	SessionTTL                time.Duration
+	AdminUserIDs              []uuid.UUID
+	JWTAlgorithm              string
+	JWTSecret                 string
+	JWKSURL                   string
+	JWKSFile                  string
+	JWKSRefresh               time.Duration
+	JWTIssuer                 string
+	JWTAudience               string
+	JWTLeeway                 time.Duration
+	JWTRolesClaim             string
+	OIDCIssuerURL             string
+	OIDCClientID              string
+	OIDCClientSecret          string
+	OIDCRedirectURL           string
+	OIDCScopes                []string
+	OIDCLoginTTL              time.Duration
+	OIDCPostLoginURL          string
+	OpenRouterApiEndpoint     string
+	OpenRouterApiKey          string
+	ProviderKeyMasterKeys     string
+	TitleModel                string
+	SummaryModel              string
+	SummaryKeepRecent         int
+	SummaryBatchSize          int
+	StreamDisconnectMode      string
+	StreamReplayWindow        time.Duration
+	StreamDisconnectGrace     time.Duration
+	StreamMaxGenerations      int
+	StreamMaxSubscribers      int
+	StreamHeartbeatInterval   time.Duration
+	StreamUpstreamIdleTimeout time.Duration
+	StreamCoalesceWindow      time.Duration
+	StreamCoalesceBytes       int
+	StreamRelay               string
+	StreamRelayChannel        string
+	InstanceID                string
+	ProxyHeader               string
+	RateLimitStore            string
+	RateLimitUserRequests     ratelimit.Limit
+	RateLimitKeyRequests      ratelimit.Limit
+	RateLimitIPRequests       ratelimit.Limit
+	RateLimitUserStreams      int
+	RateLimitKeyStreams       int
+	RateLimitIPStreams        int
+	RateLimitStreamLease      time.Duration
 }
 
 func getEnv(key, fallback string) string {
@@ -22,19 +73,137 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvInt(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("%s must be an integer: %v", key, err)
+	}
+	return n
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("%s must be a duration such as 30s or 5m: %v", key, err)
+	}
+	return d
+}
+
+func getEnvLimit(key, fallback string) ratelimit.Limit {
+	limit, err := ratelimit.ParseLimit(getEnv(key, fallback))
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return limit
+}
+
+// getEnvUUIDs reads a comma-separated list of UUIDs
+func getEnvUUIDs(key string) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, raw := range strings.Split(getEnv(key, ""), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			log.Fatalf("%s must list UUIDs: %v", key, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// defaultInstanceID names this process uniquely, so replicas sharing a hostname stay apart
+func defaultInstanceID() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
 func LoadConfig() *Config {
 	// Load .env only if present (for local dev)
 	_ = godotenv.Load()
 	cfg := &Config{
-		Port:                  getEnv("PORT", "4000"),
-		DatabaseURL:           getEnv("DATABASE_URL", ""),
-		OpenRouterApiEndpoint: getEnv("OPEN_ROUTER_API_ENDPOINT", ""),
-		OpenRouterApiKey:      getEnv("OPEN_ROUTER_API_KEY", ""),
+		Port:                      getEnv("PORT", "4000"),
+		GRPCPort:                  getEnv("GRPC_PORT", "9090"),
+		DatabaseURL:               getEnv("DATABASE_URL", ""),
+		SessionTTL:                getEnvDuration("SESSION_TTL", 30*24*time.Hour),
+		AdminUserIDs:              getEnvUUIDs("ADMIN_USER_IDS"),
+		JWTAlgorithm:              getEnv("JWT_ALGORITHM", ""),
+		JWTSecret:                 getEnv("JWT_SECRET", ""),
+		JWKSURL:                   getEnv("JWKS_URL", ""),
+		JWKSFile:                  getEnv("JWKS_FILE", ""),
+		JWKSRefresh:               getEnvDuration("JWKS_REFRESH", 15*time.Minute),
+		JWTIssuer:                 getEnv("JWT_ISSUER", ""),
+		JWTAudience:               getEnv("JWT_AUDIENCE", ""),
+		JWTLeeway:                 getEnvDuration("JWT_LEEWAY", 30*time.Second),
+		JWTRolesClaim:             getEnv("JWT_ROLES_CLAIM", "roles"),
+		OIDCIssuerURL:             getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:              getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:          getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:           getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:                strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+		OIDCLoginTTL:              getEnvDuration("OIDC_LOGIN_TTL", 10*time.Minute),
+		OIDCPostLoginURL:          getEnv("OIDC_POST_LOGIN_URL", ""),
+		OpenRouterApiEndpoint:     getEnv("OPEN_ROUTER_API_ENDPOINT", ""),
+		OpenRouterApiKey:          getEnv("OPEN_ROUTER_API_KEY", ""),
+		ProviderKeyMasterKeys:     getEnv("PROVIDER_KEY_MASTER_KEYS", ""),
+		TitleModel:                getEnv("TITLE_MODEL", "gpt-4o-mini"),
+		SummaryModel:              getEnv("SUMMARY_MODEL", "gpt-4o-mini"),
+		SummaryKeepRecent:         getEnvInt("SUMMARY_KEEP_RECENT", 12),
+		SummaryBatchSize:          getEnvInt("SUMMARY_BATCH_SIZE", 8),
+		StreamDisconnectMode:      getEnv("STREAM_DISCONNECT_MODE", "cancel"),
+		StreamReplayWindow:        getEnvDuration("STREAM_REPLAY_WINDOW", 5*time.Minute),
+		StreamDisconnectGrace:     getEnvDuration("STREAM_DISCONNECT_GRACE", 15*time.Second),
+		StreamMaxGenerations:      getEnvInt("STREAM_MAX_GENERATIONS", 100),
+		StreamMaxSubscribers:      getEnvInt("STREAM_MAX_SUBSCRIBERS", 10),
+		StreamHeartbeatInterval:   getEnvDuration("STREAM_HEARTBEAT_INTERVAL", 15*time.Second),
+		StreamUpstreamIdleTimeout: getEnvDuration("STREAM_UPSTREAM_IDLE_TIMEOUT", 90*time.Second),
+		StreamCoalesceWindow:      getEnvDuration("STREAM_COALESCE_WINDOW", 25*time.Millisecond),
+		StreamCoalesceBytes:       getEnvInt("STREAM_COALESCE_BYTES", 4096),
+		StreamRelay:               getEnv("STREAM_RELAY", "local"),
+		StreamRelayChannel:        getEnv("STREAM_RELAY_CHANNEL", "llm_playground_streams"),
+		InstanceID:                getEnv("INSTANCE_ID", defaultInstanceID()),
+		ProxyHeader:               getEnv("PROXY_HEADER", ""),
+		RateLimitStore:            getEnv("RATE_LIMIT_STORE", "memory"),
+		RateLimitUserRequests:     getEnvLimit("RATE_LIMIT_USER_REQUESTS", "120/1m"),
+		RateLimitKeyRequests:      getEnvLimit("RATE_LIMIT_KEY_REQUESTS", "60/1m"),
+		RateLimitIPRequests:       getEnvLimit("RATE_LIMIT_IP_REQUESTS", "300/1m"),
+		RateLimitUserStreams:      getEnvInt("RATE_LIMIT_USER_STREAMS", 4),
+		RateLimitKeyStreams:       getEnvInt("RATE_LIMIT_KEY_STREAMS", 2),
+		RateLimitIPStreams:        getEnvInt("RATE_LIMIT_IP_STREAMS", 10),
+		RateLimitStreamLease:      getEnvDuration("RATE_LIMIT_STREAM_LEASE", time.Minute),
 	}
 
 	if cfg.DatabaseURL == "" {
 		log.Fatal("DATABASE_URL is required")
 	}
+	if cfg.StreamDisconnectMode != "cancel" && cfg.StreamDisconnectMode != "background" {
+		log.Fatal("STREAM_DISCONNECT_MODE must be cancel or background")
+	}
+	if cfg.JWTAlgorithm != "" && cfg.JWTAlgorithm != "HS256" && cfg.JWTAlgorithm != "RS256" {
+		log.Fatal("JWT_ALGORITHM must be HS256 or RS256")
+	}
+	if cfg.OIDCIssuerURL != "" && (cfg.OIDCClientID == "" || cfg.OIDCRedirectURL == "") {
+		log.Fatal("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER_URL is set")
+	}
+	if cfg.StreamRelay != "local" && cfg.StreamRelay != "postgres" {
+		log.Fatal("STREAM_RELAY must be local or postgres")
+	}
+	if cfg.RateLimitStore != "off" && cfg.RateLimitStore != "memory" && cfg.RateLimitStore != "postgres" {
+		log.Fatal("RATE_LIMIT_STORE must be off, memory or postgres")
+	}
+	if cfg.RateLimitStore == "postgres" && cfg.RateLimitStreamLease < 3*time.Second {
+		log.Fatal("RATE_LIMIT_STREAM_LEASE must be at least 3s")
+	}
 
 	return cfg
 }
@@ -0,0 +1,234 @@
+package generation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrCancelled is the cancel cause when a generation is stopped through the API
+	ErrCancelled = errors.New("cancelled by user")
+	// ErrAbandoned is the cancel cause when every client went away and none came back in time
+	ErrAbandoned = errors.New("client disconnected")
+	// ErrEventsLost ends a mirror that missed events relayed from the instance running its generation
+	ErrEventsLost = errors.New("events were lost relaying the generation from another instance; resume to catch up")
+)
+
+// Event is one buffered stream event. IDs start at 1 and increase monotonically per generation.
+type Event struct {
+	ID   uint64          `json:"id"`
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Generation is a running or recently finished generation together with its event log.
+// A remote generation mirrors one that runs on another instance.
+type Generation struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	relay  Relay // set on local generations when events are shared with other instances
+	remote bool
+
+	// relayMu keeps relayed events in order without holding mu while they are sent
+	relayMu sync.Mutex
+
+	mu          sync.Mutex
+	events      []Event
+	offset      uint64        // ID of the event before events[0], for mirrors that joined late
+	wake        chan struct{} // closed and replaced whenever an event is published
+	finished    bool
+	subscribers int
+	idleTimer   *time.Timer
+}
+
+func newGeneration(id, convID uuid.UUID) *Generation {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	return &Generation{
+		ID:             id,
+		ConversationID: convID,
+		ctx:            ctx,
+		cancel:         cancel,
+		wake:           make(chan struct{}),
+	}
+}
+
+// Context is cancelled when the generation is cancelled; context.Cause tells why
+func (g *Generation) Context() context.Context {
+	return g.ctx
+}
+
+// Publish appends an event to the log and wakes up every subscriber
+func (g *Generation) Publish(name string, data interface{}) {
+	jsonData, _ := json.Marshal(data)
+
+	g.mu.Lock()
+	if g.finished {
+		g.mu.Unlock()
+		return
+	}
+	ev := Event{
+		ID:   g.offset + uint64(len(g.events)) + 1,
+		Name: name,
+		Data: jsonData,
+	}
+	g.events = append(g.events, ev)
+	close(g.wake)
+	g.wake = make(chan struct{})
+	if g.relay == nil {
+		g.mu.Unlock()
+		return
+	}
+
+	// Taking relayMu before releasing mu relays the events in the order of their IDs,
+	// while subscribers and cancellation never wait on the relay
+	g.relayMu.Lock()
+	g.mu.Unlock()
+	defer g.relayMu.Unlock()
+	g.relay.Send(Notice{
+		Kind:           NoticeEvent,
+		GenerationID:   g.ID,
+		ConversationID: g.ConversationID,
+		Event:          &ev,
+	})
+}
+
+// mirror appends an event relayed from the instance running the generation.
+// It reports false when events before ev were lost on the way.
+func (g *Generation) mirror(ev Event) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.finished {
+		return true
+	}
+
+	if len(g.events) == 0 {
+		g.offset = ev.ID - 1
+	}
+	next := g.offset + uint64(len(g.events)) + 1
+	if ev.ID > next {
+		return false
+	}
+	if ev.ID < next {
+		return true // duplicate
+	}
+	g.events = append(g.events, ev)
+	close(g.wake)
+	g.wake = make(chan struct{})
+	return true
+}
+
+// lose ends a mirror that missed events with lost, which takes the ID of the last event
+// received so that clients resuming from it catch up on everything after. Without a
+// lost event the mirror is left for finishMirror to end.
+func (g *Generation) lose(lost *Event) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.finished || lost == nil {
+		return
+	}
+	ev := *lost
+	ev.ID = g.lastID()
+	g.events = append(g.events, ev)
+	// Finished along with the append, so that no client reads the event twice
+	g.finishLocked()
+}
+
+// lastID returns the ID of the last event, or of the event before the first for a mirror
+// that has none. The caller must hold mu.
+func (g *Generation) lastID() uint64 {
+	return g.offset + uint64(len(g.events))
+}
+
+// Remote reports whether the generation runs on another instance
+func (g *Generation) Remote() bool {
+	return g.remote
+}
+
+// Events returns the events after lastID, a channel closed on the next publish,
+// and whether the generation has finished and will publish nothing more.
+func (g *Generation) Events(lastID uint64) ([]Event, <-chan struct{}, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Events before a late-joining mirror's first one are lost
+	if lastID < g.offset {
+		lastID = g.offset
+	}
+	var events []Event
+	if i := lastID - g.offset; i < uint64(len(g.events)) {
+		events = g.events[i:]
+	}
+	return events, g.wake, g.finished
+}
+
+// Finished reports whether the generation has ended
+func (g *Generation) Finished() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.finished
+}
+
+func (g *Generation) finish() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.finished {
+		return
+	}
+	g.finishLocked()
+}
+
+// finishLocked ends an unfinished generation. The caller must hold mu.
+func (g *Generation) finishLocked() {
+	g.finished = true
+	if g.idleTimer != nil {
+		g.idleTimer.Stop()
+	}
+	close(g.wake)
+	g.cancel(nil)
+}
+
+// subscribe registers a watching client and stops any pending abandonment.
+// It reports false when max clients, if positive, are already watching.
+func (g *Generation) subscribe(max int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if max > 0 && g.subscribers >= max {
+		return false
+	}
+	g.subscribers++
+	if g.idleTimer != nil {
+		g.idleTimer.Stop()
+		g.idleTimer = nil
+	}
+	return true
+}
+
+// unsubscribe removes a client. When it was the last one and abandon is true,
+// the generation is cancelled unless someone subscribes again within grace.
+func (g *Generation) unsubscribe(abandon bool, grace time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.subscribers--
+	if g.subscribers > 0 || !abandon || g.finished {
+		return
+	}
+	g.idleTimer = time.AfterFunc(grace, func() {
+		g.mu.Lock()
+		idle := g.subscribers == 0
+		g.mu.Unlock()
+		if idle {
+			g.cancel(ErrAbandoned)
+		}
+	})
+}
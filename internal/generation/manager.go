@@ -0,0 +1,433 @@
+package generation
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTooManyGenerations is returned when the instance already runs MaxGenerations generations
+	ErrTooManyGenerations = errors.New("too many generations in progress")
+	// ErrConversationBusy is returned when the conversation already has a generation in progress
+	ErrConversationBusy = errors.New("a generation is already in progress for this conversation")
+	// ErrTooManySubscribers is returned when a generation or conversation has MaxSubscribers watchers
+	ErrTooManySubscribers = errors.New("too many subscribers")
+)
+
+// Config tunes how long generations and their events are kept around
+type Config struct {
+	// ReplayWindow is how long a finished generation's events stay available for resuming
+	ReplayWindow time.Duration
+	// ContinueOnDisconnect lets a generation finish after its last client went away
+	ContinueOnDisconnect bool
+	// DisconnectGrace is how long an abandoned generation waits for a client to resume before it is cancelled
+	DisconnectGrace time.Duration
+	// MaxGenerations caps the generations running at once, 0 for no limit
+	MaxGenerations int
+	// MaxSubscribers caps the clients watching one generation or one conversation, 0 for no limit
+	MaxSubscribers int
+}
+
+// Manager runs generations independently of the HTTP requests that started them
+// and fans their events out to every client watching the conversation.
+type Manager struct {
+	cfg Config
+
+	relay Relay
+	lost  *Event // ends mirrors that missed relayed events
+
+	mu          sync.Mutex
+	generations map[uuid.UUID]*Generation
+	topics      map[uuid.UUID]*topic
+	stale       map[uuid.UUID]*time.Timer // finishes mirrors whose instance went quiet
+	running     int
+}
+
+// maxChanges is how many conversation changes a topic keeps for watchers that are busy
+// following a generation
+const maxChanges = 32
+
+// topic tracks the generations and changes of one conversation for its watchers
+type topic struct {
+	pending  *Generation   // created but not running yet
+	current  *Generation   // most recently started generation
+	wake     chan struct{} // closed and replaced whenever a generation starts or a change is published
+	watchers int
+	changes  []Event
+}
+
+func NewManager(cfg Config) *Manager {
+	return &Manager{
+		cfg:         cfg,
+		generations: make(map[uuid.UUID]*Generation),
+		topics:      make(map[uuid.UUID]*topic),
+		stale:       make(map[uuid.UUID]*time.Timer),
+	}
+}
+
+// SetLostEvent sets the event that ends mirrors which missed relayed events, telling
+// their clients to resume
+func (m *Manager) SetLostEvent(name string, data interface{}) {
+	jsonData, _ := json.Marshal(data)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lost = &Event{Name: name, Data: jsonData}
+}
+
+// SetRelay shares the generations of this instance with other instances through r.
+// It must be called before any generation is created.
+func (m *Manager) SetRelay(r Relay) {
+	m.relay = r
+}
+
+func (t *topic) notify() {
+	close(t.wake)
+	t.wake = make(chan struct{})
+}
+
+// topic returns the topic of a conversation, creating it if needed. m.mu must be held.
+func (m *Manager) topic(convID uuid.UUID) *topic {
+	t, ok := m.topics[convID]
+	if !ok {
+		t = &topic{wake: make(chan struct{})}
+		m.topics[convID] = t
+	}
+	return t
+}
+
+// dropTopic removes a topic nobody uses anymore. m.mu must be held.
+func (m *Manager) dropTopic(convID uuid.UUID) {
+	t, ok := m.topics[convID]
+	if ok && t.pending == nil && t.current == nil && t.watchers == 0 {
+		delete(m.topics, convID)
+	}
+}
+
+// Create registers a generation without running it yet, so its context can be used
+// to set up the work before any event is published. A conversation runs one generation at a time.
+func (m *Manager) Create(convID uuid.UUID) (*Generation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cfg.MaxGenerations > 0 && m.running >= m.cfg.MaxGenerations {
+		return nil, ErrTooManyGenerations
+	}
+	t := m.topic(convID)
+	if t.pending != nil || (t.current != nil && !t.current.Finished()) {
+		return nil, ErrConversationBusy
+	}
+
+	g := newGeneration(uuid.New(), convID)
+	g.relay = m.relay
+	m.generations[g.ID] = g
+	t.pending = g
+	m.running++
+	return g, nil
+}
+
+// Run runs fn for a created generation in its own goroutine and announces it to the
+// conversation's watchers. The generation is finished when fn returns and forgotten
+// once the replay window has passed.
+func (m *Manager) Run(g *Generation, fn func(g *Generation)) {
+	m.mu.Lock()
+	t := m.topic(g.ConversationID)
+	t.pending = nil
+	t.current = g
+	t.notify()
+	m.mu.Unlock()
+
+	go func() {
+		defer func() {
+			g.finish()
+			if m.relay != nil {
+				// Sent after the last event, which mirrors check they received
+				g.mu.Lock()
+				last := g.lastID()
+				g.mu.Unlock()
+				g.relayMu.Lock()
+				m.relay.Send(Notice{Kind: NoticeFinish, GenerationID: g.ID, ConversationID: g.ConversationID, LastEventID: last})
+				g.relayMu.Unlock()
+			}
+			m.mu.Lock()
+			m.running--
+			m.mu.Unlock()
+			time.AfterFunc(m.cfg.ReplayWindow, func() { m.forget(g) })
+		}()
+		fn(g)
+	}()
+}
+
+// Start creates and runs a generation
+func (m *Manager) Start(convID uuid.UUID, fn func(g *Generation)) (*Generation, error) {
+	g, err := m.Create(convID)
+	if err != nil {
+		return nil, err
+	}
+	m.Run(g, fn)
+	return g, nil
+}
+
+// Discard forgets a created generation that will never run
+func (m *Manager) Discard(g *Generation) {
+	g.finish()
+
+	m.mu.Lock()
+	if t, ok := m.topics[g.ConversationID]; ok && t.pending == g {
+		t.pending = nil
+	}
+	m.running--
+	m.mu.Unlock()
+
+	m.forget(g)
+}
+
+func (m *Manager) forget(g *Generation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.generations, g.ID)
+	if t, ok := m.topics[g.ConversationID]; ok && t.current == g {
+		t.current = nil
+	}
+	m.dropTopic(g.ConversationID)
+}
+
+// Get returns a running or recently finished generation
+func (m *Manager) Get(id uuid.UUID) (*Generation, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.generations[id]
+	return g, ok
+}
+
+// Current returns the latest generation of a watched conversation, if it is still kept,
+// and a channel closed when the next one starts.
+func (m *Manager) Current(convID uuid.UUID) (*Generation, <-chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.topic(convID)
+	return t.current, t.wake
+}
+
+// Changes returns the changes of a watched conversation published after the change
+// with id after, and the id of the latest one.
+func (m *Manager) Changes(convID uuid.UUID, after uint64) ([]Event, uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.topic(convID)
+	if len(t.changes) == 0 {
+		return nil, after
+	}
+	var changes []Event
+	for _, ev := range t.changes {
+		if ev.ID > after {
+			changes = append(changes, ev)
+		}
+	}
+	return changes, t.changes[len(t.changes)-1].ID
+}
+
+// PublishChange tells the watchers of a conversation, on every instance, that it
+// changed outside of a generation, e.g. it was renamed.
+func (m *Manager) PublishChange(convID uuid.UUID, name string, data interface{}) {
+	jsonData, _ := json.Marshal(data)
+	ev := Event{Name: name, Data: jsonData}
+
+	m.deliverChange(convID, ev)
+	if m.relay != nil {
+		m.relay.Send(Notice{Kind: NoticeChange, ConversationID: convID, Event: &ev})
+	}
+}
+
+// deliverChange hands a change to the local watchers of the conversation, if any
+func (m *Manager) deliverChange(convID uuid.UUID, ev Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.topics[convID]
+	if !ok || t.watchers == 0 {
+		return
+	}
+	ev.ID = 1
+	if n := len(t.changes); n > 0 {
+		ev.ID = t.changes[n-1].ID + 1
+	}
+	t.changes = append(t.changes, ev)
+	if len(t.changes) > maxChanges {
+		t.changes = t.changes[len(t.changes)-maxChanges:]
+	}
+	t.notify()
+}
+
+// Cancel aborts a running generation, asking its instance to do so if it runs elsewhere.
+// It reports whether a running generation was found.
+func (m *Manager) Cancel(id uuid.UUID) bool {
+	g, ok := m.Get(id)
+	if !ok || g.Finished() {
+		return false
+	}
+	if g.remote {
+		m.relay.Send(Notice{Kind: NoticeCancel, GenerationID: g.ID, ConversationID: g.ConversationID})
+		return true
+	}
+	g.cancel(ErrCancelled)
+	return true
+}
+
+// CancelConversation aborts the generation running in a conversation.
+// It reports whether a running generation was found.
+func (m *Manager) CancelConversation(convID uuid.UUID) bool {
+	m.mu.Lock()
+	var current *Generation
+	if t, ok := m.topics[convID]; ok {
+		current = t.current
+	}
+	m.mu.Unlock()
+
+	return current != nil && m.Cancel(current.ID)
+}
+
+// Subscribe registers a client watching g. The returned func must be called when the
+// client goes away; disconnected tells whether it left before the generation finished.
+func (m *Manager) Subscribe(g *Generation) (func(disconnected bool), error) {
+	if !g.subscribe(m.cfg.MaxSubscribers) {
+		return nil, ErrTooManySubscribers
+	}
+	if g.remote {
+		// The instance running the generation decides whether it was abandoned
+		m.relay.Send(Notice{Kind: NoticeSubscribe, GenerationID: g.ID, ConversationID: g.ConversationID})
+		return func(disconnected bool) {
+			g.unsubscribe(false, 0)
+			m.relay.Send(Notice{Kind: NoticeUnsubscribe, GenerationID: g.ID, ConversationID: g.ConversationID, Disconnected: disconnected})
+		}, nil
+	}
+	return func(disconnected bool) {
+		g.unsubscribe(disconnected && !m.cfg.ContinueOnDisconnect, m.cfg.DisconnectGrace)
+	}, nil
+}
+
+// Receive applies a notice relayed from another instance
+func (m *Manager) Receive(n Notice) {
+	switch n.Kind {
+	case NoticeEvent:
+		if n.Event == nil {
+			return
+		}
+		if g := m.mirror(n.GenerationID, n.ConversationID); !g.mirror(*n.Event) {
+			m.loseMirror(g)
+		}
+	case NoticeFinish:
+		g, ok := m.Get(n.GenerationID)
+		if !ok || !g.remote {
+			return
+		}
+		g.mu.Lock()
+		complete := g.lastID() >= n.LastEventID
+		g.mu.Unlock()
+		if !complete {
+			m.loseMirror(g)
+			return
+		}
+		m.finishMirror(g)
+	case NoticeCancel:
+		if g, ok := m.Get(n.GenerationID); ok && !g.remote {
+			g.cancel(ErrCancelled)
+		}
+	case NoticeSubscribe:
+		if g, ok := m.Get(n.GenerationID); ok && !g.remote {
+			g.subscribe(0)
+		}
+	case NoticeUnsubscribe:
+		if g, ok := m.Get(n.GenerationID); ok && !g.remote {
+			g.unsubscribe(n.Disconnected && !m.cfg.ContinueOnDisconnect, m.cfg.DisconnectGrace)
+		}
+	case NoticeChange:
+		if n.Event != nil {
+			m.deliverChange(n.ConversationID, *n.Event)
+		}
+	}
+}
+
+// mirror returns the local mirror of a generation running on another instance, creating
+// and announcing it on its first event. A mirror that hears nothing for a replay window
+// is finished, in case its instance went away.
+func (m *Manager) mirror(id, convID uuid.UUID) *Generation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.generations[id]
+	if !ok {
+		g = newGeneration(id, convID)
+		g.remote = true
+		m.generations[id] = g
+
+		t := m.topic(convID)
+		t.current = g
+		t.notify()
+	}
+	if g.remote {
+		if timer, ok := m.stale[id]; ok {
+			timer.Reset(m.cfg.ReplayWindow)
+		} else {
+			m.stale[id] = time.AfterFunc(m.cfg.ReplayWindow, func() { m.finishMirror(g) })
+		}
+	}
+	return g
+}
+
+// loseMirror ends a mirror that missed relayed events with the lost event, so that its
+// clients resume and reach the instance running the generation, or its stored reply
+func (m *Manager) loseMirror(g *Generation) {
+	log.Printf("Mirror of generation %s missed relayed events, ending it", g.ID)
+	m.mu.Lock()
+	lost := m.lost
+	m.mu.Unlock()
+	g.lose(lost)
+	m.finishMirror(g)
+}
+
+// finishMirror ends a mirror and forgets it once the replay window has passed
+func (m *Manager) finishMirror(g *Generation) {
+	g.finish()
+
+	m.mu.Lock()
+	if timer, ok := m.stale[g.ID]; ok {
+		timer.Stop()
+		delete(m.stale, g.ID)
+	}
+	m.mu.Unlock()
+
+	time.AfterFunc(m.cfg.ReplayWindow, func() { m.forget(g) })
+}
+
+// Watch registers a client following every generation of a conversation.
+// The returned func must be called when the client goes away.
+func (m *Manager) Watch(convID uuid.UUID) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.topic(convID)
+	if m.cfg.MaxSubscribers > 0 && t.watchers >= m.cfg.MaxSubscribers {
+		m.dropTopic(convID)
+		return nil, ErrTooManySubscribers
+	}
+	t.watchers++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			t.watchers--
+			m.dropTopic(convID)
+		})
+	}, nil
+}
@@ -0,0 +1,87 @@
+package generation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMirrorMissingEvents(t *testing.T) {
+	event := func(id uint64) *Event {
+		return &Event{ID: id, Name: "delta", Data: []byte(`"x"`)}
+	}
+
+	cases := []struct {
+		name    string
+		notices func(genID, convID uuid.UUID) []Notice
+		want    []string // names of the events the mirror ends with
+	}{
+		{
+			name: "complete",
+			notices: func(genID, convID uuid.UUID) []Notice {
+				return []Notice{
+					{Kind: NoticeEvent, GenerationID: genID, ConversationID: convID, Event: event(1)},
+					{Kind: NoticeEvent, GenerationID: genID, ConversationID: convID, Event: event(2)},
+					{Kind: NoticeFinish, GenerationID: genID, ConversationID: convID, LastEventID: 2},
+				}
+			},
+			want: []string{"delta", "delta"},
+		},
+		{
+			name: "gap",
+			notices: func(genID, convID uuid.UUID) []Notice {
+				return []Notice{
+					{Kind: NoticeEvent, GenerationID: genID, ConversationID: convID, Event: event(1)},
+					{Kind: NoticeEvent, GenerationID: genID, ConversationID: convID, Event: event(3)},
+				}
+			},
+			want: []string{"delta", "lost"},
+		},
+		{
+			name: "missing tail",
+			notices: func(genID, convID uuid.UUID) []Notice {
+				return []Notice{
+					{Kind: NoticeEvent, GenerationID: genID, ConversationID: convID, Event: event(1)},
+					{Kind: NoticeFinish, GenerationID: genID, ConversationID: convID, LastEventID: 3},
+				}
+			},
+			want: []string{"delta", "lost"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewManager(Config{ReplayWindow: time.Minute})
+			m.SetLostEvent("lost", "resume")
+			genID, convID := uuid.New(), uuid.New()
+			for _, n := range tc.notices(genID, convID) {
+				m.Receive(n)
+			}
+
+			g, ok := m.Get(genID)
+			if !ok {
+				t.Fatal("no mirror")
+			}
+			events, _, finished := g.Events(0)
+			if !finished {
+				t.Fatal("mirror still running")
+			}
+			var names []string
+			for _, ev := range events {
+				names = append(names, ev.Name)
+			}
+			if len(names) != len(tc.want) {
+				t.Fatalf("mirror ended with %v, want %v", names, tc.want)
+			}
+			for i := range names {
+				if names[i] != tc.want[i] {
+					t.Fatalf("mirror ended with %v, want %v", names, tc.want)
+				}
+			}
+			// Resuming from the lost event starts right after the last one received
+			if last := events[len(events)-1]; last.Name == "lost" && last.ID != 1 {
+				t.Errorf("lost event has ID %d, want 1", last.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package generation
+
+import (
+	"github.com/google/uuid"
+)
+
+// Notice kinds exchanged between instances
+const (
+	NoticeEvent       = "event"       // a generation published an event
+	NoticeFinish      = "finish"      // a generation finished
+	NoticeCancel      = "cancel"      // a client asked to cancel a generation running elsewhere
+	NoticeSubscribe   = "subscribe"   // a client started watching a generation running elsewhere
+	NoticeUnsubscribe = "unsubscribe" // a client stopped watching a generation running elsewhere
+	NoticeChange      = "change"      // a conversation changed outside of a generation
+)
+
+// Notice is one piece of generation or conversation activity shared between instances
+type Notice struct {
+	Kind           string    `json:"kind"`
+	GenerationID   uuid.UUID `json:"generation_id,omitempty"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Event          *Event    `json:"event,omitempty"`
+	Disconnected   bool      `json:"disconnected,omitempty"`
+	LastEventID    uint64    `json:"last_event_id,omitempty"` // of a finished generation
+}
+
+// Relay carries notices to the managers of the other instances. Send must keep the
+// order of the notices, must not block and must not call back into the manager. Under
+// load it may drop event notices, which ends the mirrors that miss them, but no others.
+type Relay interface {
+	Send(n Notice)
+}
@@ -0,0 +1,131 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/google/uuid"
+	pb "github.com/typescript-any/llm-playground/internal/grpcapi/playgroundv1"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
+	service "github.com/typescript-any/llm-playground/internal/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type identityKey struct{}
+
+// methodScopes lists the scopes an API key needs for each method, matching the HTTP routes
+var methodScopes = map[string][]string{
+	pb.PlaygroundService_CreateConversation_FullMethodName: {models.ScopeConversationsWrite},
+	pb.PlaygroundService_ListConversations_FullMethodName:  {models.ScopeConversationsRead},
+	pb.PlaygroundService_UpdateConversation_FullMethodName: {models.ScopeConversationsWrite},
+	pb.PlaygroundService_RegenerateTitle_FullMethodName:    {models.ScopeConversationsWrite},
+	pb.PlaygroundService_ListMessages_FullMethodName:       {models.ScopeMessagesRead},
+	pb.PlaygroundService_SendMessage_FullMethodName:        {models.ScopeMessagesWrite},
+	pb.PlaygroundService_StreamMessage_FullMethodName:      {models.ScopeMessagesWrite},
+	pb.PlaygroundService_RegenerateMessage_FullMethodName:  {models.ScopeMessagesWrite},
+	pb.PlaygroundService_StreamGeneration_FullMethodName:   {models.ScopeMessagesRead},
+	pb.PlaygroundService_CancelGeneration_FullMethodName:   {models.ScopeMessagesWrite},
+}
+
+// currentIdentity returns the identity the auth interceptors authenticated
+func currentIdentity(ctx context.Context) service.Identity {
+	identity, _ := ctx.Value(identityKey{}).(service.Identity)
+	return identity
+}
+
+// currentUser returns the user the auth interceptors authenticated
+func currentUser(ctx context.Context) models.User {
+	return currentIdentity(ctx).User
+}
+
+// rateLimitSubject returns who the call is charged to: the authenticated user and API
+// key, and the address of the peer
+func rateLimitSubject(ctx context.Context) ratelimit.Subject {
+	var subject ratelimit.Subject
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		subject.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(subject.IP); err == nil {
+			subject.IP = host
+		}
+	}
+	if identity := currentIdentity(ctx); identity.User.ID != uuid.Nil {
+		subject.UserID = identity.User.ID.String()
+		if identity.APIKeyID != uuid.Nil {
+			subject.APIKeyID = identity.APIKeyID.String()
+		}
+	}
+	return subject
+}
+
+// authenticate resolves the bearer token of a call into its identity and checks the
+// scopes of the method, like AuthMiddleware and RequireScope do for HTTP. The call is
+// charged to the request limits of its peer before and of its identity after.
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if _, err := s.limiter.AllowRequest(ctx, rateLimitSubject(ctx)); err != nil {
+		return nil, statusError(err)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid authorization metadata")
+	}
+	token, ok := middleware.ParseBearer(values[0])
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid authorization metadata")
+	}
+
+	identity, err := s.authService.Authenticate(ctx, token)
+	if errors.Is(err, service.ErrUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "could not authenticate")
+	}
+	for _, scope := range methodScopes[method] {
+		if !identity.HasScope(scope) {
+			return nil, status.Errorf(codes.PermissionDenied, "API key lacks the %s scope", scope)
+		}
+	}
+	ctx = context.WithValue(ctx, identityKey{}, identity)
+
+	subject := rateLimitSubject(ctx)
+	subject.IP = "" // charged above
+	if _, err := s.limiter.AllowRequest(ctx, subject); err != nil {
+		return nil, statusError(err)
+	}
+	return ctx, nil
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authedStream carries the authenticated user in the context of a stream
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedStream) Context() context.Context {
+	return s.ctx
+}
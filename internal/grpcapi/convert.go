@@ -0,0 +1,139 @@
+package grpcapi
+
+import (
+	"encoding/json"
+
+	"github.com/typescript-any/llm-playground/internal/generation"
+	pb "github.com/typescript-any/llm-playground/internal/grpcapi/playgroundv1"
+	"github.com/typescript-any/llm-playground/internal/models"
+	service "github.com/typescript-any/llm-playground/internal/services"
+	"github.com/typescript-any/llm-playground/internal/streaming"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toConversation(conv models.Conversation) *pb.Conversation {
+	out := &pb.Conversation{
+		Id:        conv.ID.String(),
+		UserId:    conv.UserID.String(),
+		Title:     conv.Title,
+		Settings:  &structpb.Struct{},
+		CreatedAt: timestamppb.New(conv.CreatedAt),
+	}
+	if raw, err := json.Marshal(conv.Settings); err == nil {
+		out.Settings.UnmarshalJSON(raw)
+	}
+	if conv.SourceConversationID != nil {
+		out.SourceConversationId = conv.SourceConversationID.String()
+	}
+	if conv.WorkspaceID != nil {
+		out.WorkspaceId = conv.WorkspaceID.String()
+	}
+	return out
+}
+
+func toMessage(m models.Message) *pb.Message {
+	out := &pb.Message{
+		Id:             m.ID.String(),
+		ConversationId: m.ConversationID.String(),
+		Role:           m.Role,
+		Content:        m.Content,
+		Status:         m.Status,
+		CreatedAt:      timestamppb.New(m.CreatedAt),
+	}
+	if m.FinishReason != nil {
+		out.FinishReason = *m.FinishReason
+	}
+	if m.Error != nil {
+		out.Error = *m.Error
+	}
+	return out
+}
+
+func generationOptions(o *pb.GenerationOptions) service.GenerationOptions {
+	if o == nil {
+		return service.GenerationOptions{}
+	}
+	return service.GenerationOptions{
+		Model:        o.GetModel(),
+		SystemPrompt: o.SystemPrompt,
+		Temperature:  o.Temperature,
+		TopP:         o.TopP,
+		MaxTokens:    o.MaxTokens,
+	}
+}
+
+// toStreamEvent converts an event of g into its protobuf form. Events this API does
+// not type, such as replay progress, are sent with only their name.
+func toStreamEvent(g *generation.Generation, ev generation.Event) (*pb.StreamEvent, error) {
+	out := &pb.StreamEvent{
+		Id:           ev.ID,
+		GenerationId: g.ID.String(),
+		Event:        ev.Name,
+		Version:      streaming.SchemaVersion,
+	}
+
+	var err error
+	switch streaming.EventType(ev.Name) {
+	case streaming.EventMessageStart:
+		var p streaming.MessageStart
+		err = json.Unmarshal(ev.Data, &p)
+		out.Payload = &pb.StreamEvent_MessageStart{MessageStart: &pb.MessageStart{
+			GenerationId:       p.GenerationID,
+			ConversationId:     p.ConversationID,
+			UserMessageId:      p.UserMessageID,
+			AssistantMessageId: p.AssistantMessageID,
+			Model:              p.Model,
+			CreatedAt:          p.CreatedAt,
+		}}
+	case streaming.EventContentDelta:
+		var p streaming.ContentDelta
+		err = json.Unmarshal(ev.Data, &p)
+		out.Payload = &pb.StreamEvent_ContentDelta{ContentDelta: &pb.ContentDelta{
+			Index: int32(p.Index),
+			Type:  p.Delta.Type,
+			Value: p.Delta.Value,
+		}}
+	case streaming.EventToolCall:
+		var p streaming.ToolCall
+		err = json.Unmarshal(ev.Data, &p)
+		out.Payload = &pb.StreamEvent_ToolCall{ToolCall: &pb.ToolCall{
+			Index:      int32(p.Index),
+			ToolCallId: p.ToolCallID,
+			Name:       p.Name,
+			Arguments:  p.Arguments,
+		}}
+	case streaming.EventUsage:
+		var p streaming.Usage
+		err = json.Unmarshal(ev.Data, &p)
+		out.Payload = &pb.StreamEvent_Usage{Usage: &pb.Usage{
+			Index:            int32(p.Index),
+			PromptTokens:     p.PromptTokens,
+			CompletionTokens: p.CompletionTokens,
+			TotalTokens:      p.TotalTokens,
+		}}
+	case streaming.EventMessageComplete:
+		var p streaming.MessageComplete
+		err = json.Unmarshal(ev.Data, &p)
+		out.Payload = &pb.StreamEvent_MessageComplete{MessageComplete: &pb.MessageComplete{
+			MessageId:    p.MessageID,
+			FinishReason: p.FinishReason,
+			Status:       p.Status,
+		}}
+	case streaming.EventError:
+		var p streaming.Error
+		err = json.Unmarshal(ev.Data, &p)
+		out.Payload = &pb.StreamEvent_Error{Error: &pb.Error{
+			Code:    p.Code,
+			Message: p.Message,
+		}}
+	case streaming.EventConversationTitle:
+		var p streaming.ConversationTitle
+		err = json.Unmarshal(ev.Data, &p)
+		out.Payload = &pb.StreamEvent_ConversationTitle{ConversationTitle: &pb.ConversationTitle{
+			ConversationId: p.ConversationID,
+			Title:          p.Title,
+		}}
+	}
+	return out, err
+}
@@ -0,0 +1,58 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError maps service errors onto gRPC status codes, as messageError does onto HTTP statuses
+func statusError(err error) error {
+	var exceeded *ratelimit.Exceeded
+	switch {
+	case errors.As(err, &exceeded):
+		return status.Errorf(codes.ResourceExhausted, "%v, retry in %s", err, exceeded.Decision.RetryAfter)
+	case errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, "conversation not found")
+	case errors.Is(err, service.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrInvalidInput), errors.Is(err, service.ErrInvalidSettings), errors.Is(err, service.ErrContextTooLong):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrNothingToRegenerate), errors.Is(err, service.ErrProviderKeyUnavailable),
+		errors.Is(err, generation.ErrConversationBusy):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, generation.ErrTooManyGenerations):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, generation.ErrTooManySubscribers), errors.Is(err, service.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// parseID parses a uuid field of a request
+func parseID(raw, field string) (uuid.UUID, error) {
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "invalid %s", field)
+	}
+	return id, nil
+}
+
+// parseOptionalID parses a uuid field that may be left empty
+func parseOptionalID(raw, field string) (*uuid.UUID, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := parseID(raw, field)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
@@ -0,0 +1,1940 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.29.3
+// source: playground/v1/playground.proto
+
+package playgroundv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Conversation struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Title  string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	// Same object as the settings of the HTTP API
+	Settings             *structpb.Struct       `protobuf:"bytes,4,opt,name=settings,proto3" json:"settings,omitempty"`
+	SourceConversationId string                 `protobuf:"bytes,5,opt,name=source_conversation_id,json=sourceConversationId,proto3" json:"source_conversation_id,omitempty"`
+	CreatedAt            *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// Set on conversations shared in a workspace
+	WorkspaceId   string `protobuf:"bytes,7,opt,name=workspace_id,json=workspaceId,proto3" json:"workspace_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Conversation) Reset() {
+	*x = Conversation{}
+	mi := &file_playground_v1_playground_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Conversation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Conversation) ProtoMessage() {}
+
+func (x *Conversation) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Conversation.ProtoReflect.Descriptor instead.
+func (*Conversation) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Conversation) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Conversation) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Conversation) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Conversation) GetSettings() *structpb.Struct {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+func (x *Conversation) GetSourceConversationId() string {
+	if x != nil {
+		return x.SourceConversationId
+	}
+	return ""
+}
+
+func (x *Conversation) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Conversation) GetWorkspaceId() string {
+	if x != nil {
+		return x.WorkspaceId
+	}
+	return ""
+}
+
+type Message struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ConversationId string                 `protobuf:"bytes,2,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`
+	Role           string                 `protobuf:"bytes,3,opt,name=role,proto3" json:"role,omitempty"`
+	Content        string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	FinishReason   string                 `protobuf:"bytes,5,opt,name=finish_reason,json=finishReason,proto3" json:"finish_reason,omitempty"`
+	Status         string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	Error          string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_playground_v1_playground_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Message) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Message) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *Message) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Message) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Message) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+func (x *Message) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Message) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Message) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+// GenerationOptions overrides the conversation settings for one request
+type GenerationOptions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	SystemPrompt  *string                `protobuf:"bytes,2,opt,name=system_prompt,json=systemPrompt,proto3,oneof" json:"system_prompt,omitempty"`
+	Temperature   *float64               `protobuf:"fixed64,3,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`
+	TopP          *float64               `protobuf:"fixed64,4,opt,name=top_p,json=topP,proto3,oneof" json:"top_p,omitempty"`
+	MaxTokens     *int64                 `protobuf:"varint,5,opt,name=max_tokens,json=maxTokens,proto3,oneof" json:"max_tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerationOptions) Reset() {
+	*x = GenerationOptions{}
+	mi := &file_playground_v1_playground_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerationOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerationOptions) ProtoMessage() {}
+
+func (x *GenerationOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerationOptions.ProtoReflect.Descriptor instead.
+func (*GenerationOptions) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GenerationOptions) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *GenerationOptions) GetSystemPrompt() string {
+	if x != nil && x.SystemPrompt != nil {
+		return *x.SystemPrompt
+	}
+	return ""
+}
+
+func (x *GenerationOptions) GetTemperature() float64 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *GenerationOptions) GetTopP() float64 {
+	if x != nil && x.TopP != nil {
+		return *x.TopP
+	}
+	return 0
+}
+
+func (x *GenerationOptions) GetMaxTokens() int64 {
+	if x != nil && x.MaxTokens != nil {
+		return *x.MaxTokens
+	}
+	return 0
+}
+
+type CreateConversationRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Title string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	// Shares the conversation in a workspace instead of keeping it personal
+	WorkspaceId   string `protobuf:"bytes,3,opt,name=workspace_id,json=workspaceId,proto3" json:"workspace_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateConversationRequest) Reset() {
+	*x = CreateConversationRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateConversationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateConversationRequest) ProtoMessage() {}
+
+func (x *CreateConversationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateConversationRequest.ProtoReflect.Descriptor instead.
+func (*CreateConversationRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CreateConversationRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *CreateConversationRequest) GetWorkspaceId() string {
+	if x != nil {
+		return x.WorkspaceId
+	}
+	return ""
+}
+
+type ListConversationsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Skip  int32                  `protobuf:"varint,2,opt,name=skip,proto3" json:"skip,omitempty"`
+	// Defaults to 20
+	Limit int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Lists the conversations of a workspace instead of the personal ones
+	WorkspaceId string `protobuf:"bytes,4,opt,name=workspace_id,json=workspaceId,proto3" json:"workspace_id,omitempty"`
+	// Searches the titles
+	Query         string `protobuf:"bytes,5,opt,name=query,proto3" json:"query,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListConversationsRequest) Reset() {
+	*x = ListConversationsRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConversationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConversationsRequest) ProtoMessage() {}
+
+func (x *ListConversationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConversationsRequest.ProtoReflect.Descriptor instead.
+func (*ListConversationsRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListConversationsRequest) GetSkip() int32 {
+	if x != nil {
+		return x.Skip
+	}
+	return 0
+}
+
+func (x *ListConversationsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListConversationsRequest) GetWorkspaceId() string {
+	if x != nil {
+		return x.WorkspaceId
+	}
+	return ""
+}
+
+func (x *ListConversationsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+type ListConversationsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Conversations []*Conversation        `protobuf:"bytes,1,rep,name=conversations,proto3" json:"conversations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListConversationsResponse) Reset() {
+	*x = ListConversationsResponse{}
+	mi := &file_playground_v1_playground_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConversationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConversationsResponse) ProtoMessage() {}
+
+func (x *ListConversationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConversationsResponse.ProtoReflect.Descriptor instead.
+func (*ListConversationsResponse) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListConversationsResponse) GetConversations() []*Conversation {
+	if x != nil {
+		return x.Conversations
+	}
+	return nil
+}
+
+type UpdateConversationRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title *string                `protobuf:"bytes,2,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	// Merged into the stored settings; null values clear a key
+	Settings      *structpb.Struct `protobuf:"bytes,3,opt,name=settings,proto3" json:"settings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateConversationRequest) Reset() {
+	*x = UpdateConversationRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateConversationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateConversationRequest) ProtoMessage() {}
+
+func (x *UpdateConversationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateConversationRequest.ProtoReflect.Descriptor instead.
+func (*UpdateConversationRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateConversationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateConversationRequest) GetTitle() string {
+	if x != nil && x.Title != nil {
+		return *x.Title
+	}
+	return ""
+}
+
+func (x *UpdateConversationRequest) GetSettings() *structpb.Struct {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type RegenerateTitleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegenerateTitleRequest) Reset() {
+	*x = RegenerateTitleRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegenerateTitleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegenerateTitleRequest) ProtoMessage() {}
+
+func (x *RegenerateTitleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegenerateTitleRequest.ProtoReflect.Descriptor instead.
+func (*RegenerateTitleRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RegenerateTitleRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListMessagesRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationId string                 `protobuf:"bytes,1,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`
+	// Defaults to 200, at most 1000
+	Limit         int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMessagesRequest) Reset() {
+	*x = ListMessagesRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMessagesRequest) ProtoMessage() {}
+
+func (x *ListMessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMessagesRequest.ProtoReflect.Descriptor instead.
+func (*ListMessagesRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListMessagesRequest) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *ListMessagesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListMessagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*Message             `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMessagesResponse) Reset() {
+	*x = ListMessagesResponse{}
+	mi := &file_playground_v1_playground_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMessagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMessagesResponse) ProtoMessage() {}
+
+func (x *ListMessagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMessagesResponse.ProtoReflect.Descriptor instead.
+func (*ListMessagesResponse) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListMessagesResponse) GetMessages() []*Message {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type SendMessageRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationId string                 `protobuf:"bytes,1,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`
+	Content        string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Options        *GenerationOptions     `protobuf:"bytes,3,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SendMessageRequest) Reset() {
+	*x = SendMessageRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMessageRequest) ProtoMessage() {}
+
+func (x *SendMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMessageRequest.ProtoReflect.Descriptor instead.
+func (*SendMessageRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SendMessageRequest) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetOptions() *GenerationOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type SendMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Role          string                 `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendMessageResponse) Reset() {
+	*x = SendMessageResponse{}
+	mi := &file_playground_v1_playground_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMessageResponse) ProtoMessage() {}
+
+func (x *SendMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMessageResponse.ProtoReflect.Descriptor instead.
+func (*SendMessageResponse) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SendMessageResponse) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *SendMessageResponse) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type StreamMessageRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationId string                 `protobuf:"bytes,1,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`
+	Content        string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Options        *GenerationOptions     `protobuf:"bytes,3,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StreamMessageRequest) Reset() {
+	*x = StreamMessageRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMessageRequest) ProtoMessage() {}
+
+func (x *StreamMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMessageRequest.ProtoReflect.Descriptor instead.
+func (*StreamMessageRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StreamMessageRequest) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *StreamMessageRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *StreamMessageRequest) GetOptions() *GenerationOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type RegenerateMessageRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationId string                 `protobuf:"bytes,1,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`
+	Options        *GenerationOptions     `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RegenerateMessageRequest) Reset() {
+	*x = RegenerateMessageRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegenerateMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegenerateMessageRequest) ProtoMessage() {}
+
+func (x *RegenerateMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegenerateMessageRequest.ProtoReflect.Descriptor instead.
+func (*RegenerateMessageRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RegenerateMessageRequest) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *RegenerateMessageRequest) GetOptions() *GenerationOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type StreamGenerationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GenerationId  string                 `protobuf:"bytes,1,opt,name=generation_id,json=generationId,proto3" json:"generation_id,omitempty"`
+	LastEventId   uint64                 `protobuf:"varint,2,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamGenerationRequest) Reset() {
+	*x = StreamGenerationRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamGenerationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamGenerationRequest) ProtoMessage() {}
+
+func (x *StreamGenerationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamGenerationRequest.ProtoReflect.Descriptor instead.
+func (*StreamGenerationRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *StreamGenerationRequest) GetGenerationId() string {
+	if x != nil {
+		return x.GenerationId
+	}
+	return ""
+}
+
+func (x *StreamGenerationRequest) GetLastEventId() uint64 {
+	if x != nil {
+		return x.LastEventId
+	}
+	return 0
+}
+
+type CancelGenerationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GenerationId  string                 `protobuf:"bytes,1,opt,name=generation_id,json=generationId,proto3" json:"generation_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelGenerationRequest) Reset() {
+	*x = CancelGenerationRequest{}
+	mi := &file_playground_v1_playground_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelGenerationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelGenerationRequest) ProtoMessage() {}
+
+func (x *CancelGenerationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelGenerationRequest.ProtoReflect.Descriptor instead.
+func (*CancelGenerationRequest) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CancelGenerationRequest) GetGenerationId() string {
+	if x != nil {
+		return x.GenerationId
+	}
+	return ""
+}
+
+type CancelGenerationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GenerationId  string                 `protobuf:"bytes,1,opt,name=generation_id,json=generationId,proto3" json:"generation_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelGenerationResponse) Reset() {
+	*x = CancelGenerationResponse{}
+	mi := &file_playground_v1_playground_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelGenerationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelGenerationResponse) ProtoMessage() {}
+
+func (x *CancelGenerationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelGenerationResponse.ProtoReflect.Descriptor instead.
+func (*CancelGenerationResponse) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CancelGenerationResponse) GetGenerationId() string {
+	if x != nil {
+		return x.GenerationId
+	}
+	return ""
+}
+
+func (x *CancelGenerationResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+// StreamEvent is one event of the streaming schema, the same events SSE and
+// WebSocket clients receive. The payload field matches the event name.
+type StreamEvent struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	GenerationId string                 `protobuf:"bytes,2,opt,name=generation_id,json=generationId,proto3" json:"generation_id,omitempty"`
+	Event        string                 `protobuf:"bytes,3,opt,name=event,proto3" json:"event,omitempty"`
+	// Schema version of the payload
+	Version int32 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*StreamEvent_MessageStart
+	//	*StreamEvent_ContentDelta
+	//	*StreamEvent_ToolCall
+	//	*StreamEvent_Usage
+	//	*StreamEvent_MessageComplete
+	//	*StreamEvent_Error
+	//	*StreamEvent_ConversationTitle
+	Payload       isStreamEvent_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEvent) Reset() {
+	*x = StreamEvent{}
+	mi := &file_playground_v1_playground_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEvent) ProtoMessage() {}
+
+func (x *StreamEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEvent.ProtoReflect.Descriptor instead.
+func (*StreamEvent) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *StreamEvent) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *StreamEvent) GetGenerationId() string {
+	if x != nil {
+		return x.GenerationId
+	}
+	return ""
+}
+
+func (x *StreamEvent) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *StreamEvent) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *StreamEvent) GetPayload() isStreamEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *StreamEvent) GetMessageStart() *MessageStart {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamEvent_MessageStart); ok {
+			return x.MessageStart
+		}
+	}
+	return nil
+}
+
+func (x *StreamEvent) GetContentDelta() *ContentDelta {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamEvent_ContentDelta); ok {
+			return x.ContentDelta
+		}
+	}
+	return nil
+}
+
+func (x *StreamEvent) GetToolCall() *ToolCall {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamEvent_ToolCall); ok {
+			return x.ToolCall
+		}
+	}
+	return nil
+}
+
+func (x *StreamEvent) GetUsage() *Usage {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamEvent_Usage); ok {
+			return x.Usage
+		}
+	}
+	return nil
+}
+
+func (x *StreamEvent) GetMessageComplete() *MessageComplete {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamEvent_MessageComplete); ok {
+			return x.MessageComplete
+		}
+	}
+	return nil
+}
+
+func (x *StreamEvent) GetError() *Error {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamEvent_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+func (x *StreamEvent) GetConversationTitle() *ConversationTitle {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamEvent_ConversationTitle); ok {
+			return x.ConversationTitle
+		}
+	}
+	return nil
+}
+
+type isStreamEvent_Payload interface {
+	isStreamEvent_Payload()
+}
+
+type StreamEvent_MessageStart struct {
+	MessageStart *MessageStart `protobuf:"bytes,10,opt,name=message_start,json=messageStart,proto3,oneof"`
+}
+
+type StreamEvent_ContentDelta struct {
+	ContentDelta *ContentDelta `protobuf:"bytes,11,opt,name=content_delta,json=contentDelta,proto3,oneof"`
+}
+
+type StreamEvent_ToolCall struct {
+	ToolCall *ToolCall `protobuf:"bytes,12,opt,name=tool_call,json=toolCall,proto3,oneof"`
+}
+
+type StreamEvent_Usage struct {
+	Usage *Usage `protobuf:"bytes,13,opt,name=usage,proto3,oneof"`
+}
+
+type StreamEvent_MessageComplete struct {
+	MessageComplete *MessageComplete `protobuf:"bytes,14,opt,name=message_complete,json=messageComplete,proto3,oneof"`
+}
+
+type StreamEvent_Error struct {
+	Error *Error `protobuf:"bytes,15,opt,name=error,proto3,oneof"`
+}
+
+type StreamEvent_ConversationTitle struct {
+	ConversationTitle *ConversationTitle `protobuf:"bytes,16,opt,name=conversation_title,json=conversationTitle,proto3,oneof"`
+}
+
+func (*StreamEvent_MessageStart) isStreamEvent_Payload() {}
+
+func (*StreamEvent_ContentDelta) isStreamEvent_Payload() {}
+
+func (*StreamEvent_ToolCall) isStreamEvent_Payload() {}
+
+func (*StreamEvent_Usage) isStreamEvent_Payload() {}
+
+func (*StreamEvent_MessageComplete) isStreamEvent_Payload() {}
+
+func (*StreamEvent_Error) isStreamEvent_Payload() {}
+
+func (*StreamEvent_ConversationTitle) isStreamEvent_Payload() {}
+
+type MessageStart struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	GenerationId       string                 `protobuf:"bytes,1,opt,name=generation_id,json=generationId,proto3" json:"generation_id,omitempty"`
+	ConversationId     string                 `protobuf:"bytes,2,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`
+	UserMessageId      string                 `protobuf:"bytes,3,opt,name=user_message_id,json=userMessageId,proto3" json:"user_message_id,omitempty"`
+	AssistantMessageId string                 `protobuf:"bytes,4,opt,name=assistant_message_id,json=assistantMessageId,proto3" json:"assistant_message_id,omitempty"`
+	Model              string                 `protobuf:"bytes,5,opt,name=model,proto3" json:"model,omitempty"`
+	// Unix seconds
+	CreatedAt     int64 `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageStart) Reset() {
+	*x = MessageStart{}
+	mi := &file_playground_v1_playground_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageStart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageStart) ProtoMessage() {}
+
+func (x *MessageStart) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageStart.ProtoReflect.Descriptor instead.
+func (*MessageStart) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *MessageStart) GetGenerationId() string {
+	if x != nil {
+		return x.GenerationId
+	}
+	return ""
+}
+
+func (x *MessageStart) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *MessageStart) GetUserMessageId() string {
+	if x != nil {
+		return x.UserMessageId
+	}
+	return ""
+}
+
+func (x *MessageStart) GetAssistantMessageId() string {
+	if x != nil {
+		return x.AssistantMessageId
+	}
+	return ""
+}
+
+func (x *MessageStart) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *MessageStart) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+type ContentDelta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContentDelta) Reset() {
+	*x = ContentDelta{}
+	mi := &file_playground_v1_playground_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContentDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentDelta) ProtoMessage() {}
+
+func (x *ContentDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentDelta.ProtoReflect.Descriptor instead.
+func (*ContentDelta) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ContentDelta) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ContentDelta) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ContentDelta) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type ToolCall struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Index      int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	ToolCallId string                 `protobuf:"bytes,2,opt,name=tool_call_id,json=toolCallId,proto3" json:"tool_call_id,omitempty"`
+	Name       string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// JSON arguments as produced by the model
+	Arguments     string `protobuf:"bytes,4,opt,name=arguments,proto3" json:"arguments,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolCall) Reset() {
+	*x = ToolCall{}
+	mi := &file_playground_v1_playground_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolCall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolCall) ProtoMessage() {}
+
+func (x *ToolCall) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolCall.ProtoReflect.Descriptor instead.
+func (*ToolCall) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ToolCall) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ToolCall) GetToolCallId() string {
+	if x != nil {
+		return x.ToolCallId
+	}
+	return ""
+}
+
+func (x *ToolCall) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolCall) GetArguments() string {
+	if x != nil {
+		return x.Arguments
+	}
+	return ""
+}
+
+type Usage struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Index            int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	PromptTokens     int64                  `protobuf:"varint,2,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int64                  `protobuf:"varint,3,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens      int64                  `protobuf:"varint,4,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Usage) Reset() {
+	*x = Usage{}
+	mi := &file_playground_v1_playground_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Usage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Usage) ProtoMessage() {}
+
+func (x *Usage) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Usage.ProtoReflect.Descriptor instead.
+func (*Usage) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Usage) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Usage) GetPromptTokens() int64 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetCompletionTokens() int64 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetTotalTokens() int64 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+type MessageComplete struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageId     string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	FinishReason  string                 `protobuf:"bytes,2,opt,name=finish_reason,json=finishReason,proto3" json:"finish_reason,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageComplete) Reset() {
+	*x = MessageComplete{}
+	mi := &file_playground_v1_playground_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageComplete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageComplete) ProtoMessage() {}
+
+func (x *MessageComplete) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageComplete.ProtoReflect.Descriptor instead.
+func (*MessageComplete) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *MessageComplete) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *MessageComplete) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+func (x *MessageComplete) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type Error struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_playground_v1_playground_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Error) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ConversationTitle struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConversationId string                 `protobuf:"bytes,1,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`
+	Title          string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ConversationTitle) Reset() {
+	*x = ConversationTitle{}
+	mi := &file_playground_v1_playground_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConversationTitle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConversationTitle) ProtoMessage() {}
+
+func (x *ConversationTitle) ProtoReflect() protoreflect.Message {
+	mi := &file_playground_v1_playground_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConversationTitle.ProtoReflect.Descriptor instead.
+func (*ConversationTitle) Descriptor() ([]byte, []int) {
+	return file_playground_v1_playground_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ConversationTitle) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *ConversationTitle) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+var File_playground_v1_playground_proto protoreflect.FileDescriptor
+
+const file_playground_v1_playground_proto_rawDesc = "" +
+	"\n" +
+	"\x1eplayground/v1/playground.proto\x12\rplayground.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x96\x02\n" +
+	"\fConversation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x123\n" +
+	"\bsettings\x18\x04 \x01(\v2\x17.google.protobuf.StructR\bsettings\x124\n" +
+	"\x16source_conversation_id\x18\x05 \x01(\tR\x14sourceConversationId\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12!\n" +
+	"\fworkspace_id\x18\a \x01(\tR\vworkspaceId\"\xfe\x01\n" +
+	"\aMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12'\n" +
+	"\x0fconversation_id\x18\x02 \x01(\tR\x0econversationId\x12\x12\n" +
+	"\x04role\x18\x03 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\tR\acontent\x12#\n" +
+	"\rfinish_reason\x18\x05 \x01(\tR\ffinishReason\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xf3\x01\n" +
+	"\x11GenerationOptions\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12(\n" +
+	"\rsystem_prompt\x18\x02 \x01(\tH\x00R\fsystemPrompt\x88\x01\x01\x12%\n" +
+	"\vtemperature\x18\x03 \x01(\x01H\x01R\vtemperature\x88\x01\x01\x12\x18\n" +
+	"\x05top_p\x18\x04 \x01(\x01H\x02R\x04topP\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_tokens\x18\x05 \x01(\x03H\x03R\tmaxTokens\x88\x01\x01B\x10\n" +
+	"\x0e_system_promptB\x0e\n" +
+	"\f_temperatureB\b\n" +
+	"\x06_top_pB\r\n" +
+	"\v_max_tokens\"c\n" +
+	"\x19CreateConversationRequest\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12!\n" +
+	"\fworkspace_id\x18\x03 \x01(\tR\vworkspaceIdJ\x04\b\x01\x10\x02R\auser_id\"\x8c\x01\n" +
+	"\x18ListConversationsRequest\x12\x12\n" +
+	"\x04skip\x18\x02 \x01(\x05R\x04skip\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12!\n" +
+	"\fworkspace_id\x18\x04 \x01(\tR\vworkspaceId\x12\x14\n" +
+	"\x05query\x18\x05 \x01(\tR\x05queryJ\x04\b\x01\x10\x02R\auser_id\"^\n" +
+	"\x19ListConversationsResponse\x12A\n" +
+	"\rconversations\x18\x01 \x03(\v2\x1b.playground.v1.ConversationR\rconversations\"\x85\x01\n" +
+	"\x19UpdateConversationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x19\n" +
+	"\x05title\x18\x02 \x01(\tH\x00R\x05title\x88\x01\x01\x123\n" +
+	"\bsettings\x18\x03 \x01(\v2\x17.google.protobuf.StructR\bsettingsB\b\n" +
+	"\x06_title\"(\n" +
+	"\x16RegenerateTitleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"T\n" +
+	"\x13ListMessagesRequest\x12'\n" +
+	"\x0fconversation_id\x18\x01 \x01(\tR\x0econversationId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"J\n" +
+	"\x14ListMessagesResponse\x122\n" +
+	"\bmessages\x18\x01 \x03(\v2\x16.playground.v1.MessageR\bmessages\"\x93\x01\n" +
+	"\x12SendMessageRequest\x12'\n" +
+	"\x0fconversation_id\x18\x01 \x01(\tR\x0econversationId\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12:\n" +
+	"\aoptions\x18\x03 \x01(\v2 .playground.v1.GenerationOptionsR\aoptions\"C\n" +
+	"\x13SendMessageResponse\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"\x95\x01\n" +
+	"\x14StreamMessageRequest\x12'\n" +
+	"\x0fconversation_id\x18\x01 \x01(\tR\x0econversationId\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12:\n" +
+	"\aoptions\x18\x03 \x01(\v2 .playground.v1.GenerationOptionsR\aoptions\"\x7f\n" +
+	"\x18RegenerateMessageRequest\x12'\n" +
+	"\x0fconversation_id\x18\x01 \x01(\tR\x0econversationId\x12:\n" +
+	"\aoptions\x18\x02 \x01(\v2 .playground.v1.GenerationOptionsR\aoptions\"b\n" +
+	"\x17StreamGenerationRequest\x12#\n" +
+	"\rgeneration_id\x18\x01 \x01(\tR\fgenerationId\x12\"\n" +
+	"\rlast_event_id\x18\x02 \x01(\x04R\vlastEventId\">\n" +
+	"\x17CancelGenerationRequest\x12#\n" +
+	"\rgeneration_id\x18\x01 \x01(\tR\fgenerationId\"W\n" +
+	"\x18CancelGenerationResponse\x12#\n" +
+	"\rgeneration_id\x18\x01 \x01(\tR\fgenerationId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\"\xb9\x04\n" +
+	"\vStreamEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12#\n" +
+	"\rgeneration_id\x18\x02 \x01(\tR\fgenerationId\x12\x14\n" +
+	"\x05event\x18\x03 \x01(\tR\x05event\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x05R\aversion\x12B\n" +
+	"\rmessage_start\x18\n" +
+	" \x01(\v2\x1b.playground.v1.MessageStartH\x00R\fmessageStart\x12B\n" +
+	"\rcontent_delta\x18\v \x01(\v2\x1b.playground.v1.ContentDeltaH\x00R\fcontentDelta\x126\n" +
+	"\ttool_call\x18\f \x01(\v2\x17.playground.v1.ToolCallH\x00R\btoolCall\x12,\n" +
+	"\x05usage\x18\r \x01(\v2\x14.playground.v1.UsageH\x00R\x05usage\x12K\n" +
+	"\x10message_complete\x18\x0e \x01(\v2\x1e.playground.v1.MessageCompleteH\x00R\x0fmessageComplete\x12,\n" +
+	"\x05error\x18\x0f \x01(\v2\x14.playground.v1.ErrorH\x00R\x05error\x12Q\n" +
+	"\x12conversation_title\x18\x10 \x01(\v2 .playground.v1.ConversationTitleH\x00R\x11conversationTitleB\t\n" +
+	"\apayload\"\xeb\x01\n" +
+	"\fMessageStart\x12#\n" +
+	"\rgeneration_id\x18\x01 \x01(\tR\fgenerationId\x12'\n" +
+	"\x0fconversation_id\x18\x02 \x01(\tR\x0econversationId\x12&\n" +
+	"\x0fuser_message_id\x18\x03 \x01(\tR\ruserMessageId\x120\n" +
+	"\x14assistant_message_id\x18\x04 \x01(\tR\x12assistantMessageId\x12\x14\n" +
+	"\x05model\x18\x05 \x01(\tR\x05model\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\x03R\tcreatedAt\"N\n" +
+	"\fContentDelta\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\"t\n" +
+	"\bToolCall\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12 \n" +
+	"\ftool_call_id\x18\x02 \x01(\tR\n" +
+	"toolCallId\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1c\n" +
+	"\targuments\x18\x04 \x01(\tR\targuments\"\x92\x01\n" +
+	"\x05Usage\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12#\n" +
+	"\rprompt_tokens\x18\x02 \x01(\x03R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x03 \x01(\x03R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\x04 \x01(\x03R\vtotalTokens\"m\n" +
+	"\x0fMessageComplete\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\tR\tmessageId\x12#\n" +
+	"\rfinish_reason\x18\x02 \x01(\tR\ffinishReason\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\"5\n" +
+	"\x05Error\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"R\n" +
+	"\x11ConversationTitle\x12'\n" +
+	"\x0fconversation_id\x18\x01 \x01(\tR\x0econversationId\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title2\xaa\a\n" +
+	"\x11PlaygroundService\x12[\n" +
+	"\x12CreateConversation\x12(.playground.v1.CreateConversationRequest\x1a\x1b.playground.v1.Conversation\x12f\n" +
+	"\x11ListConversations\x12'.playground.v1.ListConversationsRequest\x1a(.playground.v1.ListConversationsResponse\x12[\n" +
+	"\x12UpdateConversation\x12(.playground.v1.UpdateConversationRequest\x1a\x1b.playground.v1.Conversation\x12U\n" +
+	"\x0fRegenerateTitle\x12%.playground.v1.RegenerateTitleRequest\x1a\x1b.playground.v1.Conversation\x12W\n" +
+	"\fListMessages\x12\".playground.v1.ListMessagesRequest\x1a#.playground.v1.ListMessagesResponse\x12T\n" +
+	"\vSendMessage\x12!.playground.v1.SendMessageRequest\x1a\".playground.v1.SendMessageResponse\x12R\n" +
+	"\rStreamMessage\x12#.playground.v1.StreamMessageRequest\x1a\x1a.playground.v1.StreamEvent0\x01\x12Z\n" +
+	"\x11RegenerateMessage\x12'.playground.v1.RegenerateMessageRequest\x1a\x1a.playground.v1.StreamEvent0\x01\x12X\n" +
+	"\x10StreamGeneration\x12&.playground.v1.StreamGenerationRequest\x1a\x1a.playground.v1.StreamEvent0\x01\x12c\n" +
+	"\x10CancelGeneration\x12&.playground.v1.CancelGenerationRequest\x1a'.playground.v1.CancelGenerationResponseBUZSgithub.com/typescript-any/llm-playground/internal/grpcapi/playgroundv1;playgroundv1b\x06proto3"
+
+var (
+	file_playground_v1_playground_proto_rawDescOnce sync.Once
+	file_playground_v1_playground_proto_rawDescData []byte
+)
+
+func file_playground_v1_playground_proto_rawDescGZIP() []byte {
+	file_playground_v1_playground_proto_rawDescOnce.Do(func() {
+		file_playground_v1_playground_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_playground_v1_playground_proto_rawDesc), len(file_playground_v1_playground_proto_rawDesc)))
+	})
+	return file_playground_v1_playground_proto_rawDescData
+}
+
+var file_playground_v1_playground_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_playground_v1_playground_proto_goTypes = []any{
+	(*Conversation)(nil),              // 0: playground.v1.Conversation
+	(*Message)(nil),                   // 1: playground.v1.Message
+	(*GenerationOptions)(nil),         // 2: playground.v1.GenerationOptions
+	(*CreateConversationRequest)(nil), // 3: playground.v1.CreateConversationRequest
+	(*ListConversationsRequest)(nil),  // 4: playground.v1.ListConversationsRequest
+	(*ListConversationsResponse)(nil), // 5: playground.v1.ListConversationsResponse
+	(*UpdateConversationRequest)(nil), // 6: playground.v1.UpdateConversationRequest
+	(*RegenerateTitleRequest)(nil),    // 7: playground.v1.RegenerateTitleRequest
+	(*ListMessagesRequest)(nil),       // 8: playground.v1.ListMessagesRequest
+	(*ListMessagesResponse)(nil),      // 9: playground.v1.ListMessagesResponse
+	(*SendMessageRequest)(nil),        // 10: playground.v1.SendMessageRequest
+	(*SendMessageResponse)(nil),       // 11: playground.v1.SendMessageResponse
+	(*StreamMessageRequest)(nil),      // 12: playground.v1.StreamMessageRequest
+	(*RegenerateMessageRequest)(nil),  // 13: playground.v1.RegenerateMessageRequest
+	(*StreamGenerationRequest)(nil),   // 14: playground.v1.StreamGenerationRequest
+	(*CancelGenerationRequest)(nil),   // 15: playground.v1.CancelGenerationRequest
+	(*CancelGenerationResponse)(nil),  // 16: playground.v1.CancelGenerationResponse
+	(*StreamEvent)(nil),               // 17: playground.v1.StreamEvent
+	(*MessageStart)(nil),              // 18: playground.v1.MessageStart
+	(*ContentDelta)(nil),              // 19: playground.v1.ContentDelta
+	(*ToolCall)(nil),                  // 20: playground.v1.ToolCall
+	(*Usage)(nil),                     // 21: playground.v1.Usage
+	(*MessageComplete)(nil),           // 22: playground.v1.MessageComplete
+	(*Error)(nil),                     // 23: playground.v1.Error
+	(*ConversationTitle)(nil),         // 24: playground.v1.ConversationTitle
+	(*structpb.Struct)(nil),           // 25: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil),     // 26: google.protobuf.Timestamp
+}
+var file_playground_v1_playground_proto_depIdxs = []int32{
+	25, // 0: playground.v1.Conversation.settings:type_name -> google.protobuf.Struct
+	26, // 1: playground.v1.Conversation.created_at:type_name -> google.protobuf.Timestamp
+	26, // 2: playground.v1.Message.created_at:type_name -> google.protobuf.Timestamp
+	0,  // 3: playground.v1.ListConversationsResponse.conversations:type_name -> playground.v1.Conversation
+	25, // 4: playground.v1.UpdateConversationRequest.settings:type_name -> google.protobuf.Struct
+	1,  // 5: playground.v1.ListMessagesResponse.messages:type_name -> playground.v1.Message
+	2,  // 6: playground.v1.SendMessageRequest.options:type_name -> playground.v1.GenerationOptions
+	2,  // 7: playground.v1.StreamMessageRequest.options:type_name -> playground.v1.GenerationOptions
+	2,  // 8: playground.v1.RegenerateMessageRequest.options:type_name -> playground.v1.GenerationOptions
+	18, // 9: playground.v1.StreamEvent.message_start:type_name -> playground.v1.MessageStart
+	19, // 10: playground.v1.StreamEvent.content_delta:type_name -> playground.v1.ContentDelta
+	20, // 11: playground.v1.StreamEvent.tool_call:type_name -> playground.v1.ToolCall
+	21, // 12: playground.v1.StreamEvent.usage:type_name -> playground.v1.Usage
+	22, // 13: playground.v1.StreamEvent.message_complete:type_name -> playground.v1.MessageComplete
+	23, // 14: playground.v1.StreamEvent.error:type_name -> playground.v1.Error
+	24, // 15: playground.v1.StreamEvent.conversation_title:type_name -> playground.v1.ConversationTitle
+	3,  // 16: playground.v1.PlaygroundService.CreateConversation:input_type -> playground.v1.CreateConversationRequest
+	4,  // 17: playground.v1.PlaygroundService.ListConversations:input_type -> playground.v1.ListConversationsRequest
+	6,  // 18: playground.v1.PlaygroundService.UpdateConversation:input_type -> playground.v1.UpdateConversationRequest
+	7,  // 19: playground.v1.PlaygroundService.RegenerateTitle:input_type -> playground.v1.RegenerateTitleRequest
+	8,  // 20: playground.v1.PlaygroundService.ListMessages:input_type -> playground.v1.ListMessagesRequest
+	10, // 21: playground.v1.PlaygroundService.SendMessage:input_type -> playground.v1.SendMessageRequest
+	12, // 22: playground.v1.PlaygroundService.StreamMessage:input_type -> playground.v1.StreamMessageRequest
+	13, // 23: playground.v1.PlaygroundService.RegenerateMessage:input_type -> playground.v1.RegenerateMessageRequest
+	14, // 24: playground.v1.PlaygroundService.StreamGeneration:input_type -> playground.v1.StreamGenerationRequest
+	15, // 25: playground.v1.PlaygroundService.CancelGeneration:input_type -> playground.v1.CancelGenerationRequest
+	0,  // 26: playground.v1.PlaygroundService.CreateConversation:output_type -> playground.v1.Conversation
+	5,  // 27: playground.v1.PlaygroundService.ListConversations:output_type -> playground.v1.ListConversationsResponse
+	0,  // 28: playground.v1.PlaygroundService.UpdateConversation:output_type -> playground.v1.Conversation
+	0,  // 29: playground.v1.PlaygroundService.RegenerateTitle:output_type -> playground.v1.Conversation
+	9,  // 30: playground.v1.PlaygroundService.ListMessages:output_type -> playground.v1.ListMessagesResponse
+	11, // 31: playground.v1.PlaygroundService.SendMessage:output_type -> playground.v1.SendMessageResponse
+	17, // 32: playground.v1.PlaygroundService.StreamMessage:output_type -> playground.v1.StreamEvent
+	17, // 33: playground.v1.PlaygroundService.RegenerateMessage:output_type -> playground.v1.StreamEvent
+	17, // 34: playground.v1.PlaygroundService.StreamGeneration:output_type -> playground.v1.StreamEvent
+	16, // 35: playground.v1.PlaygroundService.CancelGeneration:output_type -> playground.v1.CancelGenerationResponse
+	26, // [26:36] is the sub-list for method output_type
+	16, // [16:26] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_playground_v1_playground_proto_init() }
+func file_playground_v1_playground_proto_init() {
+	if File_playground_v1_playground_proto != nil {
+		return
+	}
+	file_playground_v1_playground_proto_msgTypes[2].OneofWrappers = []any{}
+	file_playground_v1_playground_proto_msgTypes[6].OneofWrappers = []any{}
+	file_playground_v1_playground_proto_msgTypes[17].OneofWrappers = []any{
+		(*StreamEvent_MessageStart)(nil),
+		(*StreamEvent_ContentDelta)(nil),
+		(*StreamEvent_ToolCall)(nil),
+		(*StreamEvent_Usage)(nil),
+		(*StreamEvent_MessageComplete)(nil),
+		(*StreamEvent_Error)(nil),
+		(*StreamEvent_ConversationTitle)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_playground_v1_playground_proto_rawDesc), len(file_playground_v1_playground_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   25,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_playground_v1_playground_proto_goTypes,
+		DependencyIndexes: file_playground_v1_playground_proto_depIdxs,
+		MessageInfos:      file_playground_v1_playground_proto_msgTypes,
+	}.Build()
+	File_playground_v1_playground_proto = out.File
+	file_playground_v1_playground_proto_goTypes = nil
+	file_playground_v1_playground_proto_depIdxs = nil
+}
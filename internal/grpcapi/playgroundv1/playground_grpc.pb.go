@@ -0,0 +1,487 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: playground/v1/playground.proto
+
+package playgroundv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PlaygroundService_CreateConversation_FullMethodName = "/playground.v1.PlaygroundService/CreateConversation"
+	PlaygroundService_ListConversations_FullMethodName  = "/playground.v1.PlaygroundService/ListConversations"
+	PlaygroundService_UpdateConversation_FullMethodName = "/playground.v1.PlaygroundService/UpdateConversation"
+	PlaygroundService_RegenerateTitle_FullMethodName    = "/playground.v1.PlaygroundService/RegenerateTitle"
+	PlaygroundService_ListMessages_FullMethodName       = "/playground.v1.PlaygroundService/ListMessages"
+	PlaygroundService_SendMessage_FullMethodName        = "/playground.v1.PlaygroundService/SendMessage"
+	PlaygroundService_StreamMessage_FullMethodName      = "/playground.v1.PlaygroundService/StreamMessage"
+	PlaygroundService_RegenerateMessage_FullMethodName  = "/playground.v1.PlaygroundService/RegenerateMessage"
+	PlaygroundService_StreamGeneration_FullMethodName   = "/playground.v1.PlaygroundService/StreamGeneration"
+	PlaygroundService_CancelGeneration_FullMethodName   = "/playground.v1.PlaygroundService/CancelGeneration"
+)
+
+// PlaygroundServiceClient is the client API for PlaygroundService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PlaygroundService mirrors the conversation and message endpoints of the HTTP API.
+// Every call needs the same bearer token as HTTP, in the "authorization" metadata,
+// and acts as the user it authenticates.
+type PlaygroundServiceClient interface {
+	CreateConversation(ctx context.Context, in *CreateConversationRequest, opts ...grpc.CallOption) (*Conversation, error)
+	ListConversations(ctx context.Context, in *ListConversationsRequest, opts ...grpc.CallOption) (*ListConversationsResponse, error)
+	UpdateConversation(ctx context.Context, in *UpdateConversationRequest, opts ...grpc.CallOption) (*Conversation, error)
+	RegenerateTitle(ctx context.Context, in *RegenerateTitleRequest, opts ...grpc.CallOption) (*Conversation, error)
+	ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error)
+	SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*SendMessageResponse, error)
+	// StreamMessage sends a user message and streams the reply as it is generated
+	StreamMessage(ctx context.Context, in *StreamMessageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamEvent], error)
+	// RegenerateMessage replaces the last assistant reply with a freshly streamed one
+	RegenerateMessage(ctx context.Context, in *RegenerateMessageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamEvent], error)
+	// StreamGeneration resumes a generation after the last event a client saw
+	StreamGeneration(ctx context.Context, in *StreamGenerationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamEvent], error)
+	CancelGeneration(ctx context.Context, in *CancelGenerationRequest, opts ...grpc.CallOption) (*CancelGenerationResponse, error)
+}
+
+type playgroundServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPlaygroundServiceClient(cc grpc.ClientConnInterface) PlaygroundServiceClient {
+	return &playgroundServiceClient{cc}
+}
+
+func (c *playgroundServiceClient) CreateConversation(ctx context.Context, in *CreateConversationRequest, opts ...grpc.CallOption) (*Conversation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Conversation)
+	err := c.cc.Invoke(ctx, PlaygroundService_CreateConversation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playgroundServiceClient) ListConversations(ctx context.Context, in *ListConversationsRequest, opts ...grpc.CallOption) (*ListConversationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListConversationsResponse)
+	err := c.cc.Invoke(ctx, PlaygroundService_ListConversations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playgroundServiceClient) UpdateConversation(ctx context.Context, in *UpdateConversationRequest, opts ...grpc.CallOption) (*Conversation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Conversation)
+	err := c.cc.Invoke(ctx, PlaygroundService_UpdateConversation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playgroundServiceClient) RegenerateTitle(ctx context.Context, in *RegenerateTitleRequest, opts ...grpc.CallOption) (*Conversation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Conversation)
+	err := c.cc.Invoke(ctx, PlaygroundService_RegenerateTitle_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playgroundServiceClient) ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMessagesResponse)
+	err := c.cc.Invoke(ctx, PlaygroundService_ListMessages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playgroundServiceClient) SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*SendMessageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendMessageResponse)
+	err := c.cc.Invoke(ctx, PlaygroundService_SendMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playgroundServiceClient) StreamMessage(ctx context.Context, in *StreamMessageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PlaygroundService_ServiceDesc.Streams[0], PlaygroundService_StreamMessage_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamMessageRequest, StreamEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PlaygroundService_StreamMessageClient = grpc.ServerStreamingClient[StreamEvent]
+
+func (c *playgroundServiceClient) RegenerateMessage(ctx context.Context, in *RegenerateMessageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PlaygroundService_ServiceDesc.Streams[1], PlaygroundService_RegenerateMessage_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RegenerateMessageRequest, StreamEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PlaygroundService_RegenerateMessageClient = grpc.ServerStreamingClient[StreamEvent]
+
+func (c *playgroundServiceClient) StreamGeneration(ctx context.Context, in *StreamGenerationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PlaygroundService_ServiceDesc.Streams[2], PlaygroundService_StreamGeneration_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamGenerationRequest, StreamEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PlaygroundService_StreamGenerationClient = grpc.ServerStreamingClient[StreamEvent]
+
+func (c *playgroundServiceClient) CancelGeneration(ctx context.Context, in *CancelGenerationRequest, opts ...grpc.CallOption) (*CancelGenerationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelGenerationResponse)
+	err := c.cc.Invoke(ctx, PlaygroundService_CancelGeneration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PlaygroundServiceServer is the server API for PlaygroundService service.
+// All implementations must embed UnimplementedPlaygroundServiceServer
+// for forward compatibility.
+//
+// PlaygroundService mirrors the conversation and message endpoints of the HTTP API.
+// Every call needs the same bearer token as HTTP, in the "authorization" metadata,
+// and acts as the user it authenticates.
+type PlaygroundServiceServer interface {
+	CreateConversation(context.Context, *CreateConversationRequest) (*Conversation, error)
+	ListConversations(context.Context, *ListConversationsRequest) (*ListConversationsResponse, error)
+	UpdateConversation(context.Context, *UpdateConversationRequest) (*Conversation, error)
+	RegenerateTitle(context.Context, *RegenerateTitleRequest) (*Conversation, error)
+	ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error)
+	SendMessage(context.Context, *SendMessageRequest) (*SendMessageResponse, error)
+	// StreamMessage sends a user message and streams the reply as it is generated
+	StreamMessage(*StreamMessageRequest, grpc.ServerStreamingServer[StreamEvent]) error
+	// RegenerateMessage replaces the last assistant reply with a freshly streamed one
+	RegenerateMessage(*RegenerateMessageRequest, grpc.ServerStreamingServer[StreamEvent]) error
+	// StreamGeneration resumes a generation after the last event a client saw
+	StreamGeneration(*StreamGenerationRequest, grpc.ServerStreamingServer[StreamEvent]) error
+	CancelGeneration(context.Context, *CancelGenerationRequest) (*CancelGenerationResponse, error)
+	mustEmbedUnimplementedPlaygroundServiceServer()
+}
+
+// UnimplementedPlaygroundServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPlaygroundServiceServer struct{}
+
+func (UnimplementedPlaygroundServiceServer) CreateConversation(context.Context, *CreateConversationRequest) (*Conversation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateConversation not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) ListConversations(context.Context, *ListConversationsRequest) (*ListConversationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListConversations not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) UpdateConversation(context.Context, *UpdateConversationRequest) (*Conversation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateConversation not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) RegenerateTitle(context.Context, *RegenerateTitleRequest) (*Conversation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegenerateTitle not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMessages not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) SendMessage(context.Context, *SendMessageRequest) (*SendMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendMessage not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) StreamMessage(*StreamMessageRequest, grpc.ServerStreamingServer[StreamEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMessage not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) RegenerateMessage(*RegenerateMessageRequest, grpc.ServerStreamingServer[StreamEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method RegenerateMessage not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) StreamGeneration(*StreamGenerationRequest, grpc.ServerStreamingServer[StreamEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamGeneration not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) CancelGeneration(context.Context, *CancelGenerationRequest) (*CancelGenerationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelGeneration not implemented")
+}
+func (UnimplementedPlaygroundServiceServer) mustEmbedUnimplementedPlaygroundServiceServer() {}
+func (UnimplementedPlaygroundServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafePlaygroundServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PlaygroundServiceServer will
+// result in compilation errors.
+type UnsafePlaygroundServiceServer interface {
+	mustEmbedUnimplementedPlaygroundServiceServer()
+}
+
+func RegisterPlaygroundServiceServer(s grpc.ServiceRegistrar, srv PlaygroundServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPlaygroundServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PlaygroundService_ServiceDesc, srv)
+}
+
+func _PlaygroundService_CreateConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlaygroundServiceServer).CreateConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlaygroundService_CreateConversation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlaygroundServiceServer).CreateConversation(ctx, req.(*CreateConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlaygroundService_ListConversations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListConversationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlaygroundServiceServer).ListConversations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlaygroundService_ListConversations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlaygroundServiceServer).ListConversations(ctx, req.(*ListConversationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlaygroundService_UpdateConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlaygroundServiceServer).UpdateConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlaygroundService_UpdateConversation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlaygroundServiceServer).UpdateConversation(ctx, req.(*UpdateConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlaygroundService_RegenerateTitle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegenerateTitleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlaygroundServiceServer).RegenerateTitle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlaygroundService_RegenerateTitle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlaygroundServiceServer).RegenerateTitle(ctx, req.(*RegenerateTitleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlaygroundService_ListMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlaygroundServiceServer).ListMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlaygroundService_ListMessages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlaygroundServiceServer).ListMessages(ctx, req.(*ListMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlaygroundService_SendMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlaygroundServiceServer).SendMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlaygroundService_SendMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlaygroundServiceServer).SendMessage(ctx, req.(*SendMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlaygroundService_StreamMessage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMessageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PlaygroundServiceServer).StreamMessage(m, &grpc.GenericServerStream[StreamMessageRequest, StreamEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PlaygroundService_StreamMessageServer = grpc.ServerStreamingServer[StreamEvent]
+
+func _PlaygroundService_RegenerateMessage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RegenerateMessageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PlaygroundServiceServer).RegenerateMessage(m, &grpc.GenericServerStream[RegenerateMessageRequest, StreamEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PlaygroundService_RegenerateMessageServer = grpc.ServerStreamingServer[StreamEvent]
+
+func _PlaygroundService_StreamGeneration_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamGenerationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PlaygroundServiceServer).StreamGeneration(m, &grpc.GenericServerStream[StreamGenerationRequest, StreamEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PlaygroundService_StreamGenerationServer = grpc.ServerStreamingServer[StreamEvent]
+
+func _PlaygroundService_CancelGeneration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelGenerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlaygroundServiceServer).CancelGeneration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlaygroundService_CancelGeneration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlaygroundServiceServer).CancelGeneration(ctx, req.(*CancelGenerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PlaygroundService_ServiceDesc is the grpc.ServiceDesc for PlaygroundService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PlaygroundService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "playground.v1.PlaygroundService",
+	HandlerType: (*PlaygroundServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateConversation",
+			Handler:    _PlaygroundService_CreateConversation_Handler,
+		},
+		{
+			MethodName: "ListConversations",
+			Handler:    _PlaygroundService_ListConversations_Handler,
+		},
+		{
+			MethodName: "UpdateConversation",
+			Handler:    _PlaygroundService_UpdateConversation_Handler,
+		},
+		{
+			MethodName: "RegenerateTitle",
+			Handler:    _PlaygroundService_RegenerateTitle_Handler,
+		},
+		{
+			MethodName: "ListMessages",
+			Handler:    _PlaygroundService_ListMessages_Handler,
+		},
+		{
+			MethodName: "SendMessage",
+			Handler:    _PlaygroundService_SendMessage_Handler,
+		},
+		{
+			MethodName: "CancelGeneration",
+			Handler:    _PlaygroundService_CancelGeneration_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMessage",
+			Handler:       _PlaygroundService_StreamMessage_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RegenerateMessage",
+			Handler:       _PlaygroundService_RegenerateMessage_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamGeneration",
+			Handler:       _PlaygroundService_StreamGeneration_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "playground/v1/playground.proto",
+}
@@ -0,0 +1,357 @@
+// Package grpcapi serves the conversation and message operations over gRPC, for
+// backends that would rather not parse HTTP and SSE. It shares the services and the
+// streaming engine with the HTTP API.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	pb "github.com/typescript-any/llm-playground/internal/grpcapi/playgroundv1"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+	"github.com/typescript-any/llm-playground/internal/streaming"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	requestTimeout = 5 * time.Second
+	titleTimeout   = 15 * time.Second
+)
+
+// Server implements PlaygroundService
+type Server struct {
+	pb.UnimplementedPlaygroundServiceServer
+
+	authService         *service.AuthService
+	conversationService *service.ConversationService
+	messageService      *service.MessageService
+	engine              *streaming.Engine
+	limiter             *ratelimit.Limiter
+}
+
+func NewServer(authService *service.AuthService, conversationService *service.ConversationService, messageService *service.MessageService, engine *streaming.Engine, limiter *ratelimit.Limiter) *Server {
+	return &Server{
+		authService:         authService,
+		conversationService: conversationService,
+		messageService:      messageService,
+		engine:              engine,
+		limiter:             limiter,
+	}
+}
+
+// NewGRPCServer returns a gRPC server that authenticates every call and serves s
+func NewGRPCServer(s *Server) *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(s.unaryAuth),
+		grpc.StreamInterceptor(s.streamAuth),
+	)
+	pb.RegisterPlaygroundServiceServer(gs, s)
+	return gs
+}
+
+func (s *Server) CreateConversation(ctx context.Context, req *pb.CreateConversationRequest) (*pb.Conversation, error) {
+	workspaceID, err := parseOptionalID(req.GetWorkspaceId(), "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	conv, err := s.conversationService.CreateConversation(ctx, service.ConversationCreateParams{
+		UserID:      currentUser(ctx).ID,
+		WorkspaceID: workspaceID,
+		Title:       req.GetTitle(),
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, status.Error(codes.NotFound, "workspace not found")
+	case errors.Is(err, service.ErrForbidden):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "could not create conversation")
+	}
+	return toConversation(conv), nil
+}
+
+func (s *Server) ListConversations(ctx context.Context, req *pb.ListConversationsRequest) (*pb.ListConversationsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = 20
+	}
+	workspaceID, err := parseOptionalID(req.GetWorkspaceId(), "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	conversations, err := s.conversationService.ListConversations(ctx, service.ConversationListParams{
+		UserID:      currentUser(ctx).ID,
+		WorkspaceID: workspaceID,
+		Query:       req.GetQuery(),
+		Offset:      int(req.GetSkip()),
+		Limit:       limit,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "no conversations found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "could not get conversation")
+	}
+
+	resp := &pb.ListConversationsResponse{}
+	for _, conv := range conversations {
+		resp.Conversations = append(resp.Conversations, toConversation(conv))
+	}
+	return resp, nil
+}
+
+func (s *Server) UpdateConversation(ctx context.Context, req *pb.UpdateConversationRequest) (*pb.Conversation, error) {
+	convID, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+	params := service.ConversationUpdateParams{
+		UserID: currentUser(ctx).ID,
+		ID:     convID,
+		Title:  req.Title,
+	}
+	if req.GetSettings() != nil {
+		if params.SettingsPatch, err = req.GetSettings().MarshalJSON(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid settings")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	conv, err := s.conversationService.UpdateConversation(ctx, params)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	s.engine.PublishChange(convID, streaming.ChangeUpdated)
+
+	return toConversation(conv), nil
+}
+
+func (s *Server) RegenerateTitle(ctx context.Context, req *pb.RegenerateTitleRequest) (*pb.Conversation, error) {
+	convID, err := parseID(req.GetId(), "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, titleTimeout)
+	defer cancel()
+
+	conv, err := s.conversationService.RegenerateTitle(ctx, currentUser(ctx).ID, convID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "conversation or first message not found")
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, "could not generate title")
+	}
+	s.engine.PublishChange(convID, streaming.ChangeUpdated)
+
+	return toConversation(conv), nil
+}
+
+func (s *Server) ListMessages(ctx context.Context, req *pb.ListMessagesRequest) (*pb.ListMessagesResponse, error) {
+	convID, err := parseID(req.GetConversationId(), "conversation_id")
+	if err != nil {
+		return nil, err
+	}
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = 200
+	}
+	if limit < 0 || limit > 1000 {
+		return nil, status.Error(codes.InvalidArgument, "limit must be between 1 and 1000")
+	}
+
+	messages, err := s.messageService.ListMessages(ctx, service.MessageListParams{
+		UserID:         currentUser(ctx).ID,
+		ConversationID: convID,
+		Limit:          limit,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &pb.ListMessagesResponse{}
+	for _, m := range messages {
+		resp.Messages = append(resp.Messages, toMessage(m))
+	}
+	return resp, nil
+}
+
+func (s *Server) SendMessage(ctx context.Context, req *pb.SendMessageRequest) (*pb.SendMessageResponse, error) {
+	convID, err := parseID(req.GetConversationId(), "conversation_id")
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := s.messageService.SendMessage(ctx, service.MessageSendParams{
+		UserID:            currentUser(ctx).ID,
+		ConversationID:    convID,
+		Content:           req.GetContent(),
+		GenerationOptions: generationOptions(req.GetOptions()),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	s.engine.PublishChange(convID, streaming.ChangeMessages)
+
+	return &pb.SendMessageResponse{Role: reply.Role, Content: reply.Content}, nil
+}
+
+func (s *Server) StreamMessage(req *pb.StreamMessageRequest, stream grpc.ServerStreamingServer[pb.StreamEvent]) error {
+	convID, err := parseID(req.GetConversationId(), "conversation_id")
+	if err != nil {
+		return err
+	}
+	userID := currentUser(stream.Context()).ID
+	if err := s.checkAccess(stream.Context(), userID, convID, service.PermissionWrite); err != nil {
+		return err
+	}
+
+	// The generation outlives this call so clients can resume it with StreamGeneration
+	g, err := s.engine.StartReply(rateLimitSubject(stream.Context()), convID, func(ctx context.Context) (*service.MessageStream, error) {
+		return s.messageService.StreamMessage(ctx, service.MessageStreamParams{
+			UserID:            userID,
+			ConversationID:    convID,
+			Content:           req.GetContent(),
+			GenerationOptions: generationOptions(req.GetOptions()),
+		})
+	}, streaming.Hooks{})
+	if err != nil {
+		return statusError(err)
+	}
+
+	return s.follow(stream, g, 0)
+}
+
+func (s *Server) RegenerateMessage(req *pb.RegenerateMessageRequest, stream grpc.ServerStreamingServer[pb.StreamEvent]) error {
+	convID, err := parseID(req.GetConversationId(), "conversation_id")
+	if err != nil {
+		return err
+	}
+	userID := currentUser(stream.Context()).ID
+	if err := s.checkAccess(stream.Context(), userID, convID, service.PermissionWrite); err != nil {
+		return err
+	}
+
+	g, err := s.engine.StartReply(rateLimitSubject(stream.Context()), convID, func(ctx context.Context) (*service.MessageStream, error) {
+		return s.messageService.RegenerateMessage(ctx, service.MessageRegenerateParams{
+			UserID:            userID,
+			ConversationID:    convID,
+			GenerationOptions: generationOptions(req.GetOptions()),
+		})
+	}, streaming.Hooks{})
+	if err != nil {
+		return statusError(err)
+	}
+
+	return s.follow(stream, g, 0)
+}
+
+func (s *Server) StreamGeneration(req *pb.StreamGenerationRequest, stream grpc.ServerStreamingServer[pb.StreamEvent]) error {
+	id, err := parseID(req.GetGenerationId(), "generation_id")
+	if err != nil {
+		return err
+	}
+
+	g, err := s.generation(stream.Context(), id, service.PermissionRead)
+	if err != nil {
+		return err
+	}
+
+	return s.follow(stream, g, req.GetLastEventId())
+}
+
+func (s *Server) CancelGeneration(ctx context.Context, req *pb.CancelGenerationRequest) (*pb.CancelGenerationResponse, error) {
+	id, err := parseID(req.GetGenerationId(), "generation_id")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.generation(ctx, id, service.PermissionWrite); err != nil {
+		return nil, err
+	}
+	if !s.engine.Cancel(id) {
+		return nil, status.Error(codes.NotFound, "generation not found or already finished")
+	}
+
+	return &pb.CancelGenerationResponse{GenerationId: id.String(), Status: "cancelling"}, nil
+}
+
+// checkAccess fails with NotFound unless userID may use the conversation with perm
+func (s *Server) checkAccess(ctx context.Context, userID, convID uuid.UUID, perm service.Permission) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	if err := s.conversationService.CheckAccess(ctx, userID, convID, perm); err != nil {
+		return statusError(err)
+	}
+	return nil
+}
+
+// generation returns a generation of a conversation the caller may use.
+// Generations of other users' conversations are reported as not found.
+func (s *Server) generation(ctx context.Context, id uuid.UUID, perm service.Permission) (*generation.Generation, error) {
+	g, ok := s.engine.Generation(id)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "generation not found or expired")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	err := s.conversationService.CheckAccess(ctx, currentUser(ctx).ID, g.ConversationID, perm)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "generation not found or expired")
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "could not get generation")
+	}
+	return g, nil
+}
+
+// follow sends the events of g after lastID on stream until the generation finishes
+// or the client cancels the call
+func (s *Server) follow(stream grpc.ServerStreamingServer[pb.StreamEvent], g *generation.Generation, lastID uint64) error {
+	ctx := stream.Context()
+	err := s.engine.Follow(g, lastID, ctx.Done(), func(events []generation.Event) error {
+		for _, ev := range events {
+			msg, err := toStreamEvent(g, ev)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return statusError(err)
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+type APIKeyHandler struct {
+	service *service.APIKeyService
+}
+
+func NewAPIKeyHandler(s *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: s,
+	}
+}
+
+// POST /keys
+// The response is the only time the full key is shown.
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	var body struct {
+		Name      string     `json:"name"`
+		Kind      string     `json:"kind"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, err := h.service.CreateAPIKey(ctx, service.APIKeyCreateParams{
+		UserID:    middleware.CurrentUser(c).ID,
+		Name:      body.Name,
+		Kind:      body.Kind,
+		Scopes:    body.Scopes,
+		ExpiresAt: body.ExpiresAt,
+	})
+	if errors.Is(err, service.ErrInvalidInput) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not create API key")
+	}
+
+	return c.Status(http.StatusCreated).JSON(key)
+}
+
+// GET /keys
+func (h *APIKeyHandler) ListAPIKeys(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keys, err := h.service.ListAPIKeys(ctx, middleware.CurrentUser(c).ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not list API keys")
+	}
+	return c.JSON(keys)
+}
+
+// DELETE /keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid key id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = h.service.RevokeAPIKey(ctx, id, middleware.CurrentUser(c).ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not revoke API key")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
@@ -1,37 +1,48 @@
 package handler
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/models"
 	"github.com/typescript-any/llm-playground/internal/repository"
 	service "github.com/typescript-any/llm-playground/internal/services"
-	"github.com/valyala/fasthttp"
+	"github.com/typescript-any/llm-playground/internal/streaming"
+)
+
+const (
+	titleTimeout     = 15 * time.Second
+	titleGracePeriod = 3 * time.Second
 )
 
 type ConversationHandler struct {
 	conversationService *service.ConversationService
 	messageService      *service.MessageService
+	engine              *streaming.Engine
 }
 
-func NewConversationHandler(conversationService *service.ConversationService, messageService *service.MessageService) *ConversationHandler {
+func NewConversationHandler(conversationService *service.ConversationService, messageService *service.MessageService, engine *streaming.Engine) *ConversationHandler {
 	return &ConversationHandler{
 		conversationService: conversationService,
 		messageService:      messageService,
+		engine:              engine,
 	}
 }
 
 // POST /conversations
+// With workspace_id the conversation is shared with the members of the workspace.
 func (h *ConversationHandler) CreateConversation(c *fiber.Ctx) error {
 	type reqBody struct {
-		UserID string `json:"user_id"`
-		Title  string `json:"title"`
+		Title       string     `json:"title"`
+		WorkspaceID *uuid.UUID `json:"workspace_id"`
 	}
 
 	var body reqBody
@@ -39,47 +50,51 @@ func (h *ConversationHandler) CreateConversation(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
 
-	userID, err := uuid.Parse(body.UserID)
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid user_id"})
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	conv, err := h.conversationService.CreateConversation(ctx, service.ConversationCreateParams{
-		UserID: userID,
-		Title:  body.Title,
+		UserID:      middleware.CurrentUser(c).ID,
+		WorkspaceID: body.WorkspaceID,
+		Title:       body.Title,
 	})
-	if err == repository.ErrInternal {
-		return fiber.NewError(fiber.StatusInternalServerError, "Could not create conversation")
+	if err != nil {
+		return workspaceError(err, "Could not create conversation")
 	}
 	return c.Status(http.StatusCreated).JSON(conv)
 }
 
-// GET /conversations/:user_id
+// GET /conversations
+// Lists the personal conversations of the authenticated user, or with workspace_id the
+// conversations of a workspace they are a member of. q searches the titles.
 func (h *ConversationHandler) ListConversations(c *fiber.Ctx) error {
-	userID, err := uuid.Parse(c.Params("user_id"))
 	skip := c.QueryInt("skip", 0)
 	limit := c.QueryInt("limit", 20)
 
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid user_id"})
+	var workspaceID *uuid.UUID
+	if raw := c.Query("workspace_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid workspace id"})
+		}
+		workspaceID = &id
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	conversations, err := h.conversationService.ListConversations(ctx, service.ConversationListParams{
-		UserID: userID,
-		Offset: skip,
-		Limit:  limit,
+		UserID:      middleware.CurrentUser(c).ID,
+		WorkspaceID: workspaceID,
+		Query:       c.Query("q"),
+		Offset:      skip,
+		Limit:       limit,
 	})
-	if err == repository.ErrNotFound {
+	if errors.Is(err, repository.ErrNotFound) {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "no conversations found"})
 	}
-	if err == repository.ErrInternal {
-		return fiber.NewError(fiber.ErrInternalServerError.Code, "could not get conversation")
+	if err != nil {
+		return workspaceError(err, "could not get conversations")
 	}
 
 	return c.JSON(conversations)
@@ -88,123 +103,309 @@ func (h *ConversationHandler) ListConversations(c *fiber.Ctx) error {
 // POST /conversations/new
 func (h *ConversationHandler) CreateNewConversation(c *fiber.Ctx) error {
 	type reqBody struct {
-		UserID  string `json:"user_id"`
-		Content string `json:"content"`
-		Model   string `json:"model"`
+		Content     string     `json:"content"`
+		WorkspaceID *uuid.UUID `json:"workspace_id"`
+		generationRequest
 	}
 	var req reqBody
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
 
-	userID, err := uuid.Parse(req.UserID)
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid user_id"})
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	userID := middleware.CurrentUser(c).ID
 	conv, err := h.conversationService.CreateNewConversation(ctx, service.ConversationNewParams{
-		UserID:  userID,
-		Content: req.Content,
-		Model:   req.Model,
+		UserID:      userID,
+		WorkspaceID: req.WorkspaceID,
+		Content:     req.Content,
 	})
-	if err == repository.ErrInternal {
-		return fiber.NewError(fiber.StatusInternalServerError, "Could not create conversation")
+	if err != nil {
+		return workspaceError(err, "Could not create conversation")
 	}
 
 	convID := conv.ID
 
-	stream, acc, err := h.messageService.StreamMessage(c.Context(), service.MessageStreamParams{
-		ConversationID: convID,
-		Content:        req.Content,
-		Model:          req.Model,
-	})
-	if err != nil {
-		return fiber.NewError(fiber.ErrInternalServerError.Code, err.Error())
-	}
-
-	// Set headers for SSE
-	c.Set("Content-Type", "text/event-stream")
-	c.Set("Cache-Control", "no-cache")
-	c.Set("Connection", "keep-alive")
-	c.Set("Access-Control-Allow-Origin", "*") // Add CORS if needed
-
-	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
-		// 1. Send start event
-		messageStart := MessageStart{
-			Type:      "message_start",
-			Model:     req.Model,
-			ConvID:    convID.String(),
-			CreatedAt: time.Now().Unix(),
-		}
-		h.sendEvent(w, "message_start", messageStart)
-
-		// 2. Stream content deltas
-		// Use the accumulator returned from service instead of creating new one
-		for stream.Next() {
-			chunk := stream.Current()
-			acc.AddChunk(chunk)
-
-			if len(chunk.Choices) > 0 {
-				delta := chunk.Choices[0].Delta.Content
-				if delta != "" {
-					// Send as JSON structured event
-					contentDelta := ContentDelta{
-						Type:  "content_block_delta",
-						Index: 0, // Assuming single message for now
-					}
-					contentDelta.Delta.Type = "text_delta"
-					contentDelta.Delta.Value = delta
-					h.sendEvent(w, "content_block_delta", contentDelta)
+	// Filled by title generation once the reply is under way; the title is pushed on the open stream
+	titles := make(chan models.Conversation, 1)
+
+	// The generation outlives this request so clients can resume it
+	g, err := h.engine.StartReply(middleware.RateLimitSubject(c), convID, func(ctx context.Context) (*service.MessageStream, error) {
+		return h.messageService.StreamMessage(ctx, service.MessageStreamParams{
+			UserID:            userID,
+			ConversationID:    convID,
+			Content:           req.Content,
+			GenerationOptions: req.options(),
+		})
+	}, streaming.Hooks{
+		// Push the title as soon as it is ready
+		AfterChunk: func(g *generation.Generation) {
+			select {
+			case conv, ok := <-titles:
+				if ok {
+					publishTitle(g, conv)
 				}
+				titles = nil
+			default:
 			}
-		}
+		},
+		// Give a slow title a short grace period before closing the stream
+		BeforeComplete: func(g *generation.Generation) {
+			if titles == nil {
+				return
+			}
+			select {
+			case conv, ok := <-titles:
+				if ok {
+					publishTitle(g, conv)
+				}
+			case <-time.After(titleGracePeriod):
+			}
+		},
+	})
+	if err != nil {
+		h.discardConversation(userID, convID)
+		return messageError(err)
+	}
+	h.generateTitle(titles, userID, convID, req.Content)
+
+	return h.engine.Serve(c, g, 0)
+}
+
+// discardConversation deletes a new conversation whose reply was refused, such as by a
+// rate limit, so that retrying does not pile up empty conversations
+func (h *ConversationHandler) discardConversation(userID, convID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.conversationService.DiscardNewConversation(ctx, userID, convID); err != nil {
+		log.Printf("Error discarding conversation %s: %v", convID, err)
+	}
+}
 
-		if stream.Err() != nil {
-			h.sendErrorEvent(w, stream.Err().Error())
+// generateTitle runs title generation in the background.
+// titles yields the renamed conversation, or is closed without a value on failure.
+func (h *ConversationHandler) generateTitle(titles chan<- models.Conversation, userID, convID uuid.UUID, content string) {
+	go func() {
+		defer close(titles)
+
+		ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+		defer cancel()
+
+		conv, err := h.conversationService.GenerateTitle(ctx, userID, convID, content)
+		if err != nil {
+			log.Printf("Error generating title for %s: %v", convID, err)
 			return
 		}
+		titles <- conv
+	}()
+}
+
+// POST /conversations/:id/title/regenerate
+func (h *ConversationHandler) RegenerateTitle(c *fiber.Ctx) error {
+	convID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid conversation id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+	defer cancel()
+
+	conv, err := h.conversationService.RegenerateTitle(ctx, middleware.CurrentUser(c).ID, convID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "conversation or first message not found"})
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, "could not generate title")
+	}
+	h.engine.PublishChange(convID, streaming.ChangeUpdated)
+
+	return c.JSON(conv)
+}
+
+// POST /conversations/:id/replay
+// Re-sends every user turn of the source conversation into a new conversation
+// using the requested model and parameters, streaming progress over SSE.
+func (h *ConversationHandler) ReplayConversation(c *fiber.Ctx) error {
+	sourceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid conversation id"})
+	}
+
+	var req generationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if req.Model == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "model is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-		// Save AI full message after completion
-		if len(acc.Choices) > 0 {
-			aiContent := acc.Choices[0].Message.Content
-			if _, err := h.messageService.SaveAssistantMessage(context.Background(), service.MessageSaveParams{
+	userID := middleware.CurrentUser(c).ID
+	plan, err := h.conversationService.CreateReplay(ctx, service.ReplayCreateParams{
+		UserID:            userID,
+		SourceID:          sourceID,
+		GenerationOptions: req.options(),
+	})
+	switch {
+	case errors.Is(err, service.ErrInvalidSettings):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrForbidden):
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "conversation not found or has no user messages"})
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "could not create replay")
+	}
+
+	g, err := h.engine.Start(middleware.RateLimitSubject(c), plan.Conversation.ID, func(g *generation.Generation) {
+		convID := plan.Conversation.ID
+		streaming.Publish(g, &streaming.ReplayStart{
+			GenerationID:         g.ID.String(),
+			ConversationID:       convID.String(),
+			SourceConversationID: plan.Source.ID.String(),
+			Model:                plan.Conversation.Settings.Model,
+			Turns:                len(plan.Turns),
+		})
+
+		finishReason := models.FinishReasonStop
+		for i, turn := range plan.Turns {
+			ms, err := h.messageService.StreamMessage(g.Context(), service.MessageStreamParams{
+				UserID:         userID,
 				ConversationID: convID,
-				Content:        aiContent,
-			}); err != nil {
-				fmt.Fprintf(w, "event: error\ndata: %v\n\n", err)
-				w.Flush()
+				Content:        turn.Content,
+			})
+			if err != nil {
+				streaming.Publish(g, streaming.NewError(err))
+				return
+			}
+			streaming.Publish(g, &streaming.TurnStart{
+				Index:              i,
+				Content:            turn.Content,
+				UserMessageID:      ms.UserMessageID.String(),
+				AssistantMessageID: ms.AssistantMessageID.String(),
+			})
+
+			outcome := h.engine.StreamTurn(g, ms, i, nil)
+			if outcome.Err != nil {
+				streaming.Publish(g, streaming.NewError(outcome.Err))
 				return
 			}
-		}
 
-		// 3. Send completion event
-		messageComplete := MessageComplete{
-			Type:       "message_complete",
-			StopReason: "end_turn",
+			turnComplete := &streaming.TurnComplete{
+				Index:        i,
+				FinishReason: outcome.FinishReason,
+				Status:       outcome.Status,
+			}
+			if outcome.Message != nil {
+				turnComplete.MessageID = outcome.Message.ID.String()
+			}
+			streaming.Publish(g, turnComplete)
+
+			if g.Context().Err() != nil {
+				finishReason = outcome.FinishReason
+				break
+			}
 		}
-		h.sendEvent(w, "message_complete", messageComplete)
-	}))
 
-	return nil
+		streaming.Publish(g, &streaming.ReplayComplete{
+			ConversationID:       convID.String(),
+			SourceConversationID: plan.Source.ID.String(),
+			FinishReason:         finishReason,
+		})
+	})
+	if err != nil {
+		return messageError(err)
+	}
+
+	return h.engine.Serve(c, g, 0)
 }
 
-// Helper function to send structured events
-func (h *ConversationHandler) sendEvent(w *bufio.Writer, eventType string, data interface{}) {
-	jsonData, _ := json.Marshal(data)
-	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, string(jsonData))
-	w.Flush()
+// GET /conversations/:id/events
+// Streams every generation of the conversation, so several tabs or devices can watch
+// the same answer. Event ids are cursors that Last-Event-ID resumes from.
+// It is only served as Server-Sent Events: the AI SDK data stream carries one message
+// without ids, so it can neither hold several generations nor be resumed. Clients that
+// ask for it get 406 and should follow GET /generations/:id/events instead.
+func (h *ConversationHandler) StreamEvents(c *fiber.Ctx) error {
+	convID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid conversation id"})
+	}
+	if streaming.WantsDataStream(c) {
+		return c.Status(http.StatusNotAcceptable).JSON(fiber.Map{"error": "conversation events are only served as text/event-stream; use /generations/:id/events for the data stream"})
+	}
+	from, err := streaming.ParseCursor(streaming.LastEventID(c))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid Last-Event-ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = h.conversationService.CheckAccess(ctx, middleware.CurrentUser(c).ID, convID, service.PermissionRead)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "conversation not found"})
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not get conversation")
+	}
+
+	return h.engine.ServeConversationSSE(c, convID, from)
 }
 
-// Helper function to send error events
-func (h *ConversationHandler) sendErrorEvent(w *bufio.Writer, errorMsg string) {
-	errorData := map[string]interface{}{
-		"type":  "error",
-		"error": errorMsg,
+// PATCH /conversations/:id
+func (h *ConversationHandler) UpdateConversation(c *fiber.Ctx) error {
+	convID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid conversation id"})
+	}
+
+	var body struct {
+		Title    *string         `json:"title"`
+		Settings json.RawMessage `json:"settings"`
 	}
-	jsonData, _ := json.Marshal(errorData)
-	fmt.Fprintf(w, "event: error\ndata: %s\n\n", string(jsonData))
-	w.Flush()
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if string(body.Settings) == "null" {
+		body.Settings = nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conv, err := h.conversationService.UpdateConversation(ctx, service.ConversationUpdateParams{
+		UserID:        middleware.CurrentUser(c).ID,
+		ID:            convID,
+		Title:         body.Title,
+		SettingsPatch: body.Settings,
+	})
+	switch {
+	case errors.Is(err, service.ErrInvalidInput), errors.Is(err, service.ErrInvalidSettings):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrForbidden):
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "conversation not found"})
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "could not update conversation")
+	}
+	h.engine.PublishChange(convID, streaming.ChangeUpdated)
+
+	return c.JSON(conv)
+}
+
+// Helper function to publish the generated title
+func publishTitle(g *generation.Generation, conv models.Conversation) {
+	streaming.Publish(g, &streaming.ConversationTitle{
+		ConversationID: conv.ID.String(),
+		Title:          conv.Title,
+	})
 }
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+	"github.com/typescript-any/llm-playground/internal/streaming"
+)
+
+type GenerationHandler struct {
+	conversationService *service.ConversationService
+	engine              *streaming.Engine
+}
+
+func NewGenerationHandler(conversationService *service.ConversationService, engine *streaming.Engine) *GenerationHandler {
+	return &GenerationHandler{
+		conversationService: conversationService,
+		engine:              engine,
+	}
+}
+
+// generation returns a generation of a conversation the caller may use.
+// Generations of other users' conversations are reported as not found.
+func (h *GenerationHandler) generation(c *fiber.Ctx, id uuid.UUID, perm service.Permission) (*generation.Generation, error) {
+	g, ok := h.engine.Generation(id)
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.conversationService.CheckAccess(ctx, middleware.CurrentUser(c).ID, g.ConversationID, perm); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// POST /generations/:id/cancel
+func (h *GenerationHandler) CancelGeneration(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid generation id"})
+	}
+
+	_, err = h.generation(c, id, service.PermissionWrite)
+	switch {
+	case errors.Is(err, repository.ErrNotFound), err == nil && !h.engine.Cancel(id):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "generation not found or already finished"})
+	case errors.Is(err, service.ErrForbidden):
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "could not get generation")
+	}
+
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{
+		"id":     id,
+		"status": "cancelling",
+	})
+}
+
+// GET /generations/:id/events
+// Replays the events after Last-Event-ID and then follows the generation live.
+func (h *GenerationHandler) StreamEvents(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid generation id"})
+	}
+	var lastID uint64
+	if raw := streaming.LastEventID(c); raw != "" {
+		if lastID, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid Last-Event-ID"})
+		}
+	}
+
+	g, err := h.generation(c, id, service.PermissionRead)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "generation not found or expired"})
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not get generation")
+	}
+
+	return h.engine.Serve(c, g, lastID)
+}
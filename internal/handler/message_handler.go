@@ -1,46 +1,74 @@
 package handler
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
+	"github.com/typescript-any/llm-playground/internal/repository"
 	service "github.com/typescript-any/llm-playground/internal/services"
-	"github.com/valyala/fasthttp"
+	"github.com/typescript-any/llm-playground/internal/streaming"
 )
 
 type MessageHandler struct {
-	service *service.MessageService
+	service             *service.MessageService
+	conversationService *service.ConversationService
+	engine              *streaming.Engine
 }
 
-type MessageStart struct {
-	Type      string `json:"type"`
-	Model     string `json:"model"`
-	ConvID    string `json:"conversation_id"`
-	CreatedAt int64  `json:"created_at"`
+// generationRequest holds the optional per-request overrides of the conversation settings
+type generationRequest struct {
+	Model        string   `json:"model"`
+	SystemPrompt *string  `json:"system_prompt"`
+	Temperature  *float64 `json:"temperature"`
+	TopP         *float64 `json:"top_p"`
+	MaxTokens    *int64   `json:"max_tokens"`
 }
 
-type ContentDelta struct {
-	Type  string `json:"type"`
-	Index int    `json:"index"`
-	Delta struct {
-		Type  string `json:"type"`
-		Value string `json:"value"`
-	} `json:"delta"`
+func (r generationRequest) options() service.GenerationOptions {
+	return service.GenerationOptions{
+		Model:        r.Model,
+		SystemPrompt: r.SystemPrompt,
+		Temperature:  r.Temperature,
+		TopP:         r.TopP,
+		MaxTokens:    r.MaxTokens,
+	}
 }
 
-type MessageComplete struct {
-	Type       string `json:"type"`
-	StopReason string `json:"stop_reason"`
+// messageError maps service errors onto HTTP errors
+func messageError(err error) error {
+	var exceeded *ratelimit.Exceeded
+	switch {
+	case errors.As(err, &exceeded):
+		return err // answered with its RateLimit headers by the error handler
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "conversation not found")
+	case errors.Is(err, service.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidSettings), errors.Is(err, service.ErrContextTooLong):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrNothingToRegenerate), errors.Is(err, service.ErrProviderKeyUnavailable),
+		errors.Is(err, generation.ErrConversationBusy):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, generation.ErrTooManyGenerations):
+		return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, generation.ErrTooManySubscribers), errors.Is(err, service.ErrQuotaExceeded):
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
 }
 
-func NewMessageHandler(s *service.MessageService) *MessageHandler {
+func NewMessageHandler(s *service.MessageService, conversationService *service.ConversationService, engine *streaming.Engine) *MessageHandler {
 	return &MessageHandler{
-		service: s,
+		service:             s,
+		conversationService: conversationService,
+		engine:              engine,
 	}
 }
 
@@ -52,24 +80,79 @@ func (h *MessageHandler) SendMessage(c *fiber.Ctx) error {
 	}
 	var req struct {
 		Content string `json:"content"`
-		Model   string `json:"model"`
+		generationRequest
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.ErrBadRequest.Code, "user_id content model are required")
 	}
 
 	reply, err := h.service.SendMessage(c.Context(), service.MessageSendParams{
-		ConversationID: convID,
-		Content:        req.Content,
-		Model:          req.Model,
+		UserID:            middleware.CurrentUser(c).ID,
+		ConversationID:    convID,
+		Content:           req.Content,
+		GenerationOptions: req.options(),
 	})
 	if err != nil {
-		return err
+		return messageError(err)
 	}
+	h.engine.PublishChange(convID, streaming.ChangeMessages)
 
 	return c.JSON(reply)
 }
 
+// GET /conversations/:id/messages
+func (h *MessageHandler) ListMessages(c *fiber.Ctx) error {
+	convID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.ErrBadRequest.Code, "Invalid conversation_id")
+	}
+	limit := c.QueryInt("limit", 200)
+	if limit <= 0 || limit > 1000 {
+		return fiber.NewError(fiber.ErrBadRequest.Code, "limit must be between 1 and 1000")
+	}
+
+	messages, err := h.service.ListMessages(c.Context(), service.MessageListParams{
+		UserID:         middleware.CurrentUser(c).ID,
+		ConversationID: convID,
+		Limit:          limit,
+	})
+	if err != nil {
+		return messageError(err)
+	}
+
+	return c.JSON(messages)
+}
+
+// PUT /conversations/:id/messages/:message_id/pin, DELETE to unpin
+// Pinned messages are sent with every prompt of the conversation.
+func (h *MessageHandler) PinMessage(c *fiber.Ctx) error {
+	convID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.ErrBadRequest.Code, "Invalid conversation_id")
+	}
+	messageID, err := uuid.Parse(c.Params("message_id"))
+	if err != nil {
+		return fiber.NewError(fiber.ErrBadRequest.Code, "Invalid message_id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	message, err := h.service.PinMessage(ctx, service.MessagePinParams{
+		UserID:         middleware.CurrentUser(c).ID,
+		ConversationID: convID,
+		MessageID:      messageID,
+		Pinned:         c.Method() != fiber.MethodDelete,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "message not found")
+	}
+	if err != nil {
+		return messageError(err)
+	}
+	return c.JSON(message)
+}
+
 // StreamMessage handles streaming AI responses via Server-Sent Events (SSE)
 func (h *MessageHandler) StreamMessage(c *fiber.Ctx) error {
 	convID, err := uuid.Parse(c.Params("id"))
@@ -79,101 +162,63 @@ func (h *MessageHandler) StreamMessage(c *fiber.Ctx) error {
 
 	var req struct {
 		Content string `json:"content"`
-		Model   string `json:"model"`
+		generationRequest
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.ErrBadRequest.Code, "content and model are required")
 	}
 
-	stream, acc, err := h.service.StreamMessage(c.Context(), service.MessageStreamParams{
-		ConversationID: convID,
-		Content:        req.Content,
-		Model:          req.Model,
-	})
+	userID := middleware.CurrentUser(c).ID
+	if err := h.conversationService.CheckAccess(c.Context(), userID, convID, service.PermissionWrite); err != nil {
+		return messageError(err)
+	}
+
+	// The generation outlives this request so clients can resume it
+	g, err := h.engine.StartReply(middleware.RateLimitSubject(c), convID, func(ctx context.Context) (*service.MessageStream, error) {
+		return h.service.StreamMessage(ctx, service.MessageStreamParams{
+			UserID:            userID,
+			ConversationID:    convID,
+			Content:           req.Content,
+			GenerationOptions: req.options(),
+		})
+	}, streaming.Hooks{})
 	if err != nil {
-		return fiber.NewError(fiber.ErrInternalServerError.Code, err.Error())
-	}
-
-	// Set headers for SSE
-	c.Set("Content-Type", "text/event-stream")
-	c.Set("Cache-Control", "no-cache")
-	c.Set("Connection", "keep-alive")
-	c.Set("Access-Control-Allow-Origin", "*") // Add CORS if needed
-
-	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
-		// 1. Send start event
-		messageStart := MessageStart{
-			Type:      "message_start",
-			Model:     req.Model,
-			ConvID:    convID.String(),
-			CreatedAt: time.Now().Unix(),
-		}
-		h.sendEvent(w, "message_start", messageStart)
-
-		// 2. Stream content deltas
-		// Use the accumulator returned from service instead of creating new one
-		for stream.Next() {
-			chunk := stream.Current()
-			acc.AddChunk(chunk)
-
-			if len(chunk.Choices) > 0 {
-				delta := chunk.Choices[0].Delta.Content
-				if delta != "" {
-					// Send as JSON structured event
-					contentDelta := ContentDelta{
-						Type:  "content_block_delta",
-						Index: 0, // Assuming single message for now
-					}
-					contentDelta.Delta.Type = "text_delta"
-					contentDelta.Delta.Value = delta
-					h.sendEvent(w, "content_block_delta", contentDelta)
-				}
-			}
-		}
+		return messageError(err)
+	}
 
-		if stream.Err() != nil {
-			h.sendErrorEvent(w, stream.Err().Error())
-			return
-		}
+	return h.engine.Serve(c, g, 0)
+}
 
-		// Save AI full message after completion
-		if len(acc.Choices) > 0 {
-			aiContent := acc.Choices[0].Message.Content
-			if _, err := h.service.SaveAssistantMessage(context.Background(), service.MessageSaveParams{
-				ConversationID: convID,
-				Content:        aiContent,
-			}); err != nil {
-				fmt.Fprintf(w, "event: error\ndata: %v\n\n", err)
-				w.Flush()
-				return
-			}
-		}
+// POST /conversations/:id/messages/regenerate
+// Replaces the last assistant reply with a freshly streamed one.
+func (h *MessageHandler) RegenerateMessage(c *fiber.Ctx) error {
+	convID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.ErrBadRequest.Code, "Invalid conversation_id")
+	}
 
-		// 3. Send completion event
-		messageComplete := MessageComplete{
-			Type:       "message_complete",
-			StopReason: "end_turn",
+	var req generationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.ErrBadRequest.Code, "invalid request")
 		}
-		h.sendEvent(w, "message_complete", messageComplete)
-	}))
-
-	return nil
-}
+	}
 
-// Helper function to send structured events
-func (h *MessageHandler) sendEvent(w *bufio.Writer, eventType string, data interface{}) {
-	jsonData, _ := json.Marshal(data)
-	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, string(jsonData))
-	w.Flush()
-}
+	userID := middleware.CurrentUser(c).ID
+	if err := h.conversationService.CheckAccess(c.Context(), userID, convID, service.PermissionWrite); err != nil {
+		return messageError(err)
+	}
 
-// Helper function to send error events
-func (h *MessageHandler) sendErrorEvent(w *bufio.Writer, errorMsg string) {
-	errorData := map[string]interface{}{
-		"type":  "error",
-		"error": errorMsg,
+	g, err := h.engine.StartReply(middleware.RateLimitSubject(c), convID, func(ctx context.Context) (*service.MessageStream, error) {
+		return h.service.RegenerateMessage(ctx, service.MessageRegenerateParams{
+			UserID:            userID,
+			ConversationID:    convID,
+			GenerationOptions: req.options(),
+		})
+	}, streaming.Hooks{})
+	if err != nil {
+		return messageError(err)
 	}
-	jsonData, _ := json.Marshal(errorData)
-	fmt.Fprintf(w, "event: error\ndata: %s\n\n", string(jsonData))
-	w.Flush()
+
+	return h.engine.Serve(c, g, 0)
 }
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+type ModelHandler struct {
+	service *service.ModelService
+}
+
+func NewModelHandler(s *service.ModelService) *ModelHandler {
+	return &ModelHandler{
+		service: s,
+	}
+}
+
+// GET /models
+// Lists the models of the registry with their prices.
+func (h *ModelHandler) ListModels(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list, err := h.service.ListModels(ctx)
+	if err != nil {
+		return quotaError(err, "could not list models")
+	}
+	return c.JSON(list)
+}
+
+// PUT /admin/models/*
+// Adds a model or replaces its prices. Model ids such as openai/gpt-4o contain slashes,
+// so the id is the rest of the path.
+func (h *ModelHandler) SaveModel(c *fiber.Ctx) error {
+	var body struct {
+		InputPrice    float64 `json:"input_price"`
+		OutputPrice   float64 `json:"output_price"`
+		ContextWindow int     `json:"context_window"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	model, err := h.service.SaveModel(ctx, service.ModelSaveParams{
+		ID:            c.Params("*"),
+		InputPrice:    body.InputPrice,
+		OutputPrice:   body.OutputPrice,
+		ContextWindow: body.ContextWindow,
+	})
+	if err != nil {
+		return quotaError(err, "could not save model")
+	}
+	return c.JSON(model)
+}
+
+// DELETE /admin/models/*
+func (h *ModelHandler) DeleteModel(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.service.DeleteModel(ctx, c.Params("*")); err != nil {
+		return quotaError(err, "could not delete model")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
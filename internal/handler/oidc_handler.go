@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/typescript-any/llm-playground/internal/auth"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+// oidcStateCookie binds a sign-in to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	oidc         *auth.OIDC
+	auth         *service.AuthService
+	postLoginURL string // where the browser lands with its token; empty answers with JSON
+	secureCookie bool
+}
+
+func NewOIDCHandler(oidc *auth.OIDC, authService *service.AuthService, postLoginURL string, secureCookie bool) *OIDCHandler {
+	return &OIDCHandler{
+		oidc:         oidc,
+		auth:         authService,
+		postLoginURL: postLoginURL,
+		secureCookie: secureCookie,
+	}
+}
+
+// GET /auth/oidc/login
+// Redirects the browser to the identity provider.
+func (h *OIDCHandler) Login(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	authURL, state, err := h.oidc.Begin(ctx)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not start sign-in")
+	}
+	h.setStateCookie(c, state, time.Now().Add(h.oidc.LoginTTL()))
+	return c.Redirect(authURL, http.StatusFound)
+}
+
+// GET /auth/oidc/callback
+// Completes the sign-in and issues a login token, in the fragment of the post-login URL
+// when one is configured.
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	if reason := c.Query("error"); reason != "" {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "sign-in failed: " + reason})
+	}
+	state := c.Query("state")
+	cookie := c.Cookies(oidcStateCookie)
+	h.setStateCookie(c, "", time.Unix(0, 0))
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "sign-in was not started from this browser"})
+	}
+
+	// Redeeming the code calls the identity provider
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	claims, err := h.oidc.Finish(ctx, state, c.Query("code"))
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "sign-in expired or already completed"})
+	case errors.Is(err, auth.ErrInvalidLogin):
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "could not complete sign-in")
+	}
+
+	session, err := h.auth.SignInWithOIDC(ctx, claims)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not complete sign-in")
+	}
+
+	if h.postLoginURL == "" {
+		return c.JSON(session)
+	}
+	fragment := url.Values{
+		"token":      {session.Token},
+		"expires_at": {session.ExpiresAt.UTC().Format(time.RFC3339)},
+	}
+	return c.Redirect(h.postLoginURL+"#"+fragment.Encode(), http.StatusFound)
+}
+
+// setStateCookie sets or, with an expiry in the past, clears the state cookie
+func (h *OIDCHandler) setStateCookie(c *fiber.Ctx, state string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		Expires:  expires,
+		Secure:   h.secureCookie,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
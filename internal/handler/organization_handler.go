@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+type OrganizationHandler struct {
+	service *service.OrganizationService
+}
+
+func NewOrganizationHandler(s *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		service: s,
+	}
+}
+
+// workspaceError maps the errors of organization and workspace operations onto HTTP errors.
+// Organizations and workspaces the caller is not a member of are not found.
+func workspaceError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput), errors.Is(err, service.ErrInvalidSettings):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "organization or workspace not found")
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, fallback)
+	}
+}
+
+// POST /organizations
+// The creator becomes the first owner.
+func (h *OrganizationHandler) CreateOrganization(c *fiber.Ctx) error {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	org, err := h.service.CreateOrganization(ctx, middleware.CurrentUser(c).ID, body.Name)
+	if err != nil {
+		return workspaceError(err, "could not create organization")
+	}
+	return c.Status(http.StatusCreated).JSON(org)
+}
+
+// GET /organizations
+func (h *OrganizationHandler) ListOrganizations(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	orgs, err := h.service.ListOrganizations(ctx, middleware.CurrentUser(c).ID)
+	if err != nil {
+		return workspaceError(err, "could not list organizations")
+	}
+	return c.JSON(orgs)
+}
+
+// GET /organizations/:id
+func (h *OrganizationHandler) GetOrganization(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	org, err := h.service.GetOrganization(ctx, middleware.CurrentUser(c).ID, orgID)
+	if err != nil {
+		return workspaceError(err, "could not get organization")
+	}
+	return c.JSON(org)
+}
+
+// GET /organizations/:id/members
+func (h *OrganizationHandler) ListMembers(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	members, err := h.service.ListMembers(ctx, middleware.CurrentUser(c).ID, orgID)
+	if err != nil {
+		return workspaceError(err, "could not list members")
+	}
+	return c.JSON(members)
+}
+
+// POST /organizations/:id/members
+// Adds an existing account by email, as a member unless another role is given.
+func (h *OrganizationHandler) InviteMember(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+	var body struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	member, err := h.service.InviteMember(ctx, service.MemberInviteParams{
+		ActorID: middleware.CurrentUser(c).ID,
+		OrgID:   orgID,
+		Email:   body.Email,
+		Role:    body.Role,
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "already a member of this organization"})
+	}
+	if err != nil {
+		return workspaceError(err, "could not add member")
+	}
+	return c.Status(http.StatusCreated).JSON(member)
+}
+
+// PATCH /organizations/:id/members/:user_id
+func (h *OrganizationHandler) UpdateMember(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	member, err := h.service.UpdateMemberRole(ctx, service.MemberUpdateParams{
+		ActorID: middleware.CurrentUser(c).ID,
+		OrgID:   orgID,
+		UserID:  userID,
+		Role:    body.Role,
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "an organization needs at least one owner"})
+	}
+	if err != nil {
+		return workspaceError(err, "could not update member")
+	}
+	return c.JSON(member)
+}
+
+// DELETE /organizations/:id/members/:user_id
+// Members may remove themselves to leave the organization.
+func (h *OrganizationHandler) RemoveMember(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = h.service.RemoveMember(ctx, middleware.CurrentUser(c).ID, orgID, userID)
+	if errors.Is(err, repository.ErrConflict) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "an organization needs at least one owner"})
+	}
+	if err != nil {
+		return workspaceError(err, "could not remove member")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// POST /organizations/:id/workspaces
+func (h *OrganizationHandler) CreateWorkspace(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+	var body struct {
+		Name            string                      `json:"name"`
+		AllowedModels   []string                    `json:"allowed_models"`
+		DefaultSettings models.ConversationSettings `json:"default_settings"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ws, err := h.service.CreateWorkspace(ctx, service.WorkspaceCreateParams{
+		UserID:          middleware.CurrentUser(c).ID,
+		OrgID:           orgID,
+		Name:            body.Name,
+		AllowedModels:   body.AllowedModels,
+		DefaultSettings: body.DefaultSettings,
+	})
+	if err != nil {
+		return workspaceError(err, "could not create workspace")
+	}
+	return c.Status(http.StatusCreated).JSON(ws)
+}
+
+// GET /organizations/:id/workspaces
+func (h *OrganizationHandler) ListWorkspaces(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	workspaces, err := h.service.ListWorkspaces(ctx, middleware.CurrentUser(c).ID, orgID)
+	if err != nil {
+		return workspaceError(err, "could not list workspaces")
+	}
+	return c.JSON(workspaces)
+}
+
+// GET /workspaces/:id
+func (h *OrganizationHandler) GetWorkspace(c *fiber.Ctx) error {
+	workspaceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid workspace id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ws, err := h.service.GetWorkspace(ctx, middleware.CurrentUser(c).ID, workspaceID)
+	if err != nil {
+		return workspaceError(err, "could not get workspace")
+	}
+	return c.JSON(ws)
+}
+
+// PATCH /workspaces/:id
+// allowed_models and default_settings replace the stored ones; [] allows every model.
+func (h *OrganizationHandler) UpdateWorkspace(c *fiber.Ctx) error {
+	workspaceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid workspace id"})
+	}
+	var body struct {
+		Name            *string                      `json:"name"`
+		AllowedModels   []string                     `json:"allowed_models"`
+		DefaultSettings *models.ConversationSettings `json:"default_settings"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ws, err := h.service.UpdateWorkspace(ctx, service.WorkspaceUpdateParams{
+		UserID:          middleware.CurrentUser(c).ID,
+		ID:              workspaceID,
+		Name:            body.Name,
+		AllowedModels:   body.AllowedModels,
+		DefaultSettings: body.DefaultSettings,
+	})
+	if err != nil {
+		return workspaceError(err, "could not update workspace")
+	}
+	return c.JSON(ws)
+}
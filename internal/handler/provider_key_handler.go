@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+type ProviderKeyHandler struct {
+	service *service.ProviderKeyService
+}
+
+func NewProviderKeyHandler(s *service.ProviderKeyService) *ProviderKeyHandler {
+	return &ProviderKeyHandler{
+		service: s,
+	}
+}
+
+// providerKeyError maps the errors of provider key operations onto HTTP errors
+func providerKeyError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "provider key not found")
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, fallback)
+	}
+}
+
+// PUT /provider-keys/me
+// Replaces the provider key of the caller. The key is never returned.
+func (h *ProviderKeyHandler) SetUserKey(c *fiber.Ctx) error {
+	var body struct {
+		Key string `json:"key"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, err := h.service.SetUserKey(ctx, middleware.CurrentUser(c).ID, body.Key)
+	if err != nil {
+		return providerKeyError(err, "could not save provider key")
+	}
+	return c.JSON(key)
+}
+
+// GET /provider-keys/me
+func (h *ProviderKeyHandler) GetUserKey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, err := h.service.GetUserKey(ctx, middleware.CurrentUser(c).ID)
+	if err != nil {
+		return providerKeyError(err, "could not get provider key")
+	}
+	return c.JSON(key)
+}
+
+// DELETE /provider-keys/me
+func (h *ProviderKeyHandler) DeleteUserKey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.service.DeleteUserKey(ctx, middleware.CurrentUser(c).ID); err != nil {
+		return providerKeyError(err, "could not delete provider key")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// PUT /provider-keys/organizations/:id
+// Replaces the provider key workspace conversations of the organization use.
+func (h *ProviderKeyHandler) SetOrgKey(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+	var body struct {
+		Key string `json:"key"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, err := h.service.SetOrgKey(ctx, middleware.CurrentUser(c).ID, orgID, body.Key)
+	if err != nil {
+		return providerKeyError(err, "could not save provider key")
+	}
+	return c.JSON(key)
+}
+
+// GET /provider-keys/organizations/:id
+func (h *ProviderKeyHandler) GetOrgKey(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, err := h.service.GetOrgKey(ctx, middleware.CurrentUser(c).ID, orgID)
+	if err != nil {
+		return providerKeyError(err, "could not get provider key")
+	}
+	return c.JSON(key)
+}
+
+// DELETE /provider-keys/organizations/:id
+func (h *ProviderKeyHandler) DeleteOrgKey(c *fiber.Ctx) error {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.service.DeleteOrgKey(ctx, middleware.CurrentUser(c).ID, orgID); err != nil {
+		return providerKeyError(err, "could not delete provider key")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
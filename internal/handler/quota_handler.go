@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+type QuotaHandler struct {
+	service *service.QuotaService
+}
+
+func NewQuotaHandler(s *service.QuotaService) *QuotaHandler {
+	return &QuotaHandler{
+		service: s,
+	}
+}
+
+// quotaError maps the errors of quota and model registry operations onto HTTP errors
+func quotaError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "not found")
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, fallback)
+	}
+}
+
+// quotaOwner reads whose quota a route is about: a user, an organization or, on the
+// default route, neither
+func quotaOwner(c *fiber.Ctx) (userID, orgID *uuid.UUID, err error) {
+	if raw := c.Params("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "invalid user id")
+		}
+		return &id, nil, nil
+	}
+	if raw := c.Params("org_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "invalid organization id")
+		}
+		return nil, &id, nil
+	}
+	return nil, nil, nil
+}
+
+// GET /quotas/me
+// Returns the quota that applies to the caller and what they spent against it.
+func (h *QuotaHandler) GetMyQuota(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	status, err := h.service.Status(ctx, middleware.CurrentUser(c).ID)
+	if err != nil {
+		return quotaError(err, "could not get quota")
+	}
+	return c.JSON(status)
+}
+
+// GET /admin/quotas
+func (h *QuotaHandler) ListQuotas(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	quotas, err := h.service.ListQuotas(ctx)
+	if err != nil {
+		return quotaError(err, "could not list quotas")
+	}
+	return c.JSON(quotas)
+}
+
+// GET /admin/quotas/default, /admin/quotas/users/:user_id, /admin/quotas/organizations/:org_id
+func (h *QuotaHandler) GetQuota(c *fiber.Ctx) error {
+	userID, orgID, err := quotaOwner(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	status, err := h.service.GetQuota(ctx, userID, orgID)
+	if err != nil {
+		return quotaError(err, "could not get quota")
+	}
+	return c.JSON(status)
+}
+
+// PUT /admin/quotas/default, /admin/quotas/users/:user_id, /admin/quotas/organizations/:org_id
+// Replaces every limit of the quota; limits left out or null do not apply.
+func (h *QuotaHandler) SetQuota(c *fiber.Ctx) error {
+	userID, orgID, err := quotaOwner(c)
+	if err != nil {
+		return err
+	}
+	var body struct {
+		DailyTokens    *int64   `json:"daily_tokens"`
+		MonthlyTokens  *int64   `json:"monthly_tokens"`
+		DailyCost      *float64 `json:"daily_cost"`
+		MonthlyCost    *float64 `json:"monthly_cost"`
+		DowngradeModel *string  `json:"downgrade_model"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	quota, err := h.service.SetQuota(ctx, service.QuotaSetParams{
+		UserID:         userID,
+		OrgID:          orgID,
+		DailyTokens:    body.DailyTokens,
+		MonthlyTokens:  body.MonthlyTokens,
+		DailyCost:      body.DailyCost,
+		MonthlyCost:    body.MonthlyCost,
+		DowngradeModel: body.DowngradeModel,
+	})
+	if err != nil {
+		return quotaError(err, "could not save quota")
+	}
+	return c.JSON(quota)
+}
+
+// DELETE /admin/quotas/default, /admin/quotas/users/:user_id, /admin/quotas/organizations/:org_id
+func (h *QuotaHandler) DeleteQuota(c *fiber.Ctx) error {
+	userID, orgID, err := quotaOwner(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.service.DeleteQuota(ctx, userID, orgID); err != nil {
+		return quotaError(err, "could not delete quota")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
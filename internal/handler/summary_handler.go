@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+	"github.com/typescript-any/llm-playground/internal/streaming"
+)
+
+type SummaryHandler struct {
+	service *service.SummaryService
+	engine  *streaming.Engine
+}
+
+func NewSummaryHandler(s *service.SummaryService, engine *streaming.Engine) *SummaryHandler {
+	return &SummaryHandler{
+		service: s,
+		engine:  engine,
+	}
+}
+
+// GET /conversations/:id/summary
+func (h *SummaryHandler) GetSummary(c *fiber.Ctx) error {
+	convID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid conversation id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	summary, err := h.service.GetSummary(ctx, middleware.CurrentUser(c).ID, convID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "no summary for this conversation"})
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not get summary")
+	}
+
+	return c.JSON(summary)
+}
+
+// PUT /conversations/:id/summary
+func (h *SummaryHandler) UpdateSummary(c *fiber.Ctx) error {
+	convID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid conversation id"})
+	}
+
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	summary, err := h.service.UpdateSummary(ctx, service.SummaryUpdateParams{
+		UserID:         middleware.CurrentUser(c).ID,
+		ConversationID: convID,
+		Content:        body.Content,
+	})
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrForbidden):
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "no summary for this conversation"})
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "could not update summary")
+	}
+	h.engine.PublishChange(convID, streaming.ChangeSummary)
+
+	return c.JSON(summary)
+}
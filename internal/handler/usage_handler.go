@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/models"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+// usageDateLayout is how the days of a usage range are written
+const usageDateLayout = "2006-01-02"
+
+type UsageHandler struct {
+	service *service.UsageService
+}
+
+func NewUsageHandler(s *service.UsageService) *UsageHandler {
+	return &UsageHandler{
+		service: s,
+	}
+}
+
+// usageError maps the errors of usage reports onto HTTP errors
+func usageError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, "only admins can see the usage of others")
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, fallback)
+	}
+}
+
+// GET /usage
+// Sums the LLM calls from the day from to the day to, both included and in UTC, by the
+// comma-separated group_by of day, model and user. Defaults to this month by day.
+// user_id and org_id narrow the report; admins may report on anyone, others only on
+// themselves. Answers with CSV for format=csv or Accept: text/csv.
+func (h *UsageHandler) GetUsage(c *fiber.Ctx) error {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var err error
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(usageDateLayout, raw); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "from must be a date such as 2025-01-31"})
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(usageDateLayout, raw); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "to must be a date such as 2025-01-31"})
+		}
+	}
+
+	params := service.UsageReportParams{
+		CallerID: middleware.CurrentUser(c).ID,
+		Admin:    middleware.CurrentIdentity(c).HasRole(models.AdminRole),
+		From:     from,
+		To:       to.AddDate(0, 0, 1),
+		GroupBy:  []string{models.UsageGroupDay},
+	}
+	if raw := c.Query("group_by"); raw != "" {
+		params.GroupBy = nil
+		for _, g := range strings.Split(raw, ",") {
+			params.GroupBy = append(params.GroupBy, strings.TrimSpace(g))
+		}
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+		}
+		params.UserID = &id
+	}
+	if raw := c.Query("org_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid organization id"})
+		}
+		params.OrgID = &id
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	report, err := h.service.Report(ctx, params)
+	if err != nil {
+		return usageError(err, "could not get usage")
+	}
+
+	if c.Query("format") == "csv" || (c.Query("format") == "" && c.Accepts(fiber.MIMEApplicationJSON, "text/csv") == "text/csv") {
+		return writeUsageCSV(c, params, report)
+	}
+	return c.JSON(report.Rows)
+}
+
+// writeUsageCSV sends a report as a CSV attachment, with a column per dimension grouped by
+func writeUsageCSV(c *fiber.Ctx, params service.UsageReportParams, report service.UsageReport) error {
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Attachment("usage-" + params.From.Format(usageDateLayout) + "-" + params.To.AddDate(0, 0, -1).Format(usageDateLayout) + ".csv")
+
+	w := csv.NewWriter(c)
+	header := append([]string{}, report.GroupBy...)
+	header = append(header, "calls", "prompt_tokens", "completion_tokens", "cost_usd")
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		record := []string{}
+		for _, g := range report.GroupBy {
+			switch g {
+			case models.UsageGroupDay:
+				record = append(record, row.Day.Format(usageDateLayout))
+			case models.UsageGroupModel:
+				record = append(record, *row.Model)
+			case models.UsageGroupUser:
+				if row.UserID != nil {
+					record = append(record, row.UserID.String())
+				} else {
+					record = append(record, "")
+				}
+			}
+		}
+		record = append(record,
+			strconv.FormatInt(row.Calls, 10),
+			strconv.FormatInt(row.PromptTokens, 10),
+			strconv.FormatInt(row.CompletionTokens, 10),
+			strconv.FormatFloat(row.Cost, 'f', 6, 64),
+		)
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+type UserHandler struct {
+	service *service.UserService
+}
+
+func NewUserHandler(s *service.UserService) *UserHandler {
+	return &UserHandler{
+		service: s,
+	}
+}
+
+// POST /auth/register
+func (h *UserHandler) Register(c *fiber.Ctx) error {
+	var body struct {
+		Email    string `json:"email"`
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := h.service.Register(ctx, service.UserRegisterParams{
+		Email:    body.Email,
+		Name:     body.Name,
+		Password: body.Password,
+	})
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repository.ErrConflict):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "email already registered"})
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "could not register")
+	}
+
+	return c.Status(http.StatusCreated).JSON(session)
+}
+
+// POST /auth/login
+func (h *UserHandler) Login(c *fiber.Ctx) error {
+	var body struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := h.service.Login(ctx, service.UserLoginParams{
+		Email:    body.Email,
+		Password: body.Password,
+	})
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not log in")
+	}
+
+	return c.JSON(session)
+}
+
+// POST /auth/logout
+func (h *UserHandler) Logout(c *fiber.Ctx) error {
+	token, _ := middleware.BearerToken(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.service.Logout(ctx, token); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not log out")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// GET /users/me
+func (h *UserHandler) GetProfile(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.service.GetProfile(ctx, middleware.CurrentUser(c).ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "could not get profile")
+	}
+	return c.JSON(user)
+}
+
+// PATCH /users/me
+func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
+	var body struct {
+		Name            *string `json:"name"`
+		CurrentPassword string  `json:"current_password"`
+		NewPassword     *string `json:"new_password"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.service.UpdateProfile(ctx, service.UserUpdateParams{
+		ID:              middleware.CurrentUser(c).ID,
+		Name:            body.Name,
+		CurrentPassword: body.CurrentPassword,
+		NewPassword:     body.NewPassword,
+	})
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCredentials):
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "current password is incorrect"})
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "could not update profile")
+	}
+
+	return c.JSON(user)
+}
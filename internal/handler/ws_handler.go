@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
+	service "github.com/typescript-any/llm-playground/internal/services"
+	"github.com/typescript-any/llm-playground/internal/streaming"
+)
+
+type WebSocketHandler struct {
+	messageService      *service.MessageService
+	conversationService *service.ConversationService
+	engine              *streaming.Engine
+	limiter             *ratelimit.Limiter
+}
+
+// wsClientFrame is a frame sent by the client: send, cancel, regenerate or ping
+type wsClientFrame struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	generationRequest
+}
+
+func NewWebSocketHandler(messageService *service.MessageService, conversationService *service.ConversationService, engine *streaming.Engine, limiter *ratelimit.Limiter) *WebSocketHandler {
+	return &WebSocketHandler{
+		messageService:      messageService,
+		conversationService: conversationService,
+		engine:              engine,
+		limiter:             limiter,
+	}
+}
+
+// Upgrade validates the request before it is upgraded to a WebSocket
+func (h *WebSocketHandler) Upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	convID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.ErrBadRequest.Code, "Invalid conversation_id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userID := middleware.CurrentUser(c).ID
+	if err := h.conversationService.CheckAccess(ctx, userID, convID, service.PermissionRead); err != nil {
+		return messageError(err)
+	}
+	c.Locals("conversation_id", convID)
+	c.Locals("user_id", userID)
+	c.Locals("rate_limit_subject", middleware.RateLimitSubject(c))
+	return c.Next()
+}
+
+// GET /ws/conversations/:id
+func (h *WebSocketHandler) Chat() fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		s := &wsSession{
+			h:      h,
+			conn:   conn,
+			userID: conn.Locals("user_id").(uuid.UUID),
+			convID: conn.Locals("conversation_id").(uuid.UUID),
+			// Frames are charged like the HTTP requests they stand in for
+			subject: conn.Locals("rate_limit_subject").(ratelimit.Subject),
+			closed:  make(chan struct{}),
+		}
+		defer close(s.closed)
+
+		// Every generation of the conversation is forwarded, including the ones started
+		// from other tabs or devices
+		go func() {
+			err := h.engine.FollowConversation(s.convID, streaming.Cursor{}, s.closed, func(g *generation.Generation, events []generation.Event) error {
+				for _, ev := range events {
+					if err := s.write(streaming.NewFrame(g, ev)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				s.sendError(streaming.ErrorCode(err), err.Error())
+				conn.Close()
+			}
+		}()
+
+		for {
+			_, raw, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var frame wsClientFrame
+			if err := json.Unmarshal(raw, &frame); err != nil {
+				s.sendError(streaming.CodeInvalidRequest, "invalid frame")
+				continue
+			}
+			s.handle(frame)
+		}
+	})
+}
+
+// wsSession is one WebSocket connection to a conversation
+type wsSession struct {
+	h       *WebSocketHandler
+	conn    *websocket.Conn
+	userID  uuid.UUID
+	convID  uuid.UUID
+	subject ratelimit.Subject
+	closed  chan struct{}
+
+	writeMu sync.Mutex
+}
+
+func (s *wsSession) handle(frame wsClientFrame) {
+	switch frame.Type {
+	case "ping":
+		s.write(streaming.Frame{Event: "pong"})
+	case "send":
+		s.start(func(ctx context.Context) (*service.MessageStream, error) {
+			return s.h.messageService.StreamMessage(ctx, service.MessageStreamParams{
+				UserID:            s.userID,
+				ConversationID:    s.convID,
+				Content:           frame.Content,
+				GenerationOptions: frame.options(),
+			})
+		})
+	case "regenerate":
+		s.start(func(ctx context.Context) (*service.MessageStream, error) {
+			return s.h.messageService.RegenerateMessage(ctx, service.MessageRegenerateParams{
+				UserID:            s.userID,
+				ConversationID:    s.convID,
+				GenerationOptions: frame.options(),
+			})
+		})
+	case "cancel":
+		if err := s.checkWrite(); err != nil {
+			s.sendError(streaming.ErrorCode(err), err.Error())
+			return
+		}
+		if !s.h.engine.CancelConversation(s.convID) {
+			s.sendError(streaming.CodeConflict, "no generation in progress")
+		}
+	default:
+		s.sendError(streaming.CodeInvalidRequest, "unknown frame type "+frame.Type)
+	}
+}
+
+// start runs a reply through the streaming engine. Its events reach the socket
+// through the conversation watch like those of any other generation.
+func (s *wsSession) start(open streaming.Opener) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	_, err := s.h.limiter.AllowRequest(ctx, s.subject)
+	cancel()
+	if err != nil {
+		s.sendError(streaming.ErrorCode(err), err.Error())
+		return
+	}
+	if _, err := s.h.engine.StartReply(s.subject, s.convID, open, streaming.Hooks{}); err != nil {
+		s.sendError(streaming.ErrorCode(err), err.Error())
+	}
+}
+
+// checkWrite checks that the user may still write to the conversation, as viewers may not
+func (s *wsSession) checkWrite() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.h.conversationService.CheckAccess(ctx, s.userID, s.convID, service.PermissionWrite)
+}
+
+func (s *wsSession) write(frame streaming.Frame) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteJSON(frame)
+}
+
+func (s *wsSession) sendError(code, message string) {
+	s.write(streaming.ErrorFrame(code, message))
+}
@@ -1,26 +1,123 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
+	service "github.com/typescript-any/llm-playground/internal/services"
 )
 
-func AuthMiddleware(c *fiber.Ctx) error {
+// localsIdentity is the c.Locals key of the authenticated identity
+const localsIdentity = "identity"
+
+// AuthMiddleware resolves the bearer token of a request, a login token, an API key or
+// a JWT, into its identity, which handlers read with CurrentUser and CurrentIdentity.
+// The request is then charged to the user and API key of the identity; a nil limiter
+// charges nothing.
+func AuthMiddleware(authService *service.AuthService, limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := BearerToken(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Missing or invalid Authorization header",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		identity, err := authService.Authenticate(ctx, token)
+		if errors.Is(err, service.ErrUnauthenticated) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "could not authenticate")
+		}
+
+		c.Locals(localsIdentity, identity)
+
+		subject := RateLimitSubject(c)
+		subject.IP = "" // charged by RateLimitIP
+		if err := allowRequest(c, limiter, subject); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// BearerToken reads the token of the Authorization header
+func BearerToken(c *fiber.Ctx) (string, bool) {
 	authHeader := c.Get("Authorization")
 
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer") {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Missing or invalid Authorization header",
-		})
+	// Browsers cannot set headers on a WebSocket handshake, so accept the token as a query parameter there
+	if authHeader == "" && strings.EqualFold(c.Get("Upgrade"), "websocket") && c.Query("access_token") != "" {
+		return c.Query("access_token"), true
 	}
 
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-	if token != "test-token" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
+	return ParseBearer(authHeader)
+}
+
+// ParseBearer extracts the token of a "Bearer <token>" credential
+func ParseBearer(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
 	}
+	return token, true
+}
 
+// CurrentIdentity returns the identity AuthMiddleware authenticated
+func CurrentIdentity(c *fiber.Ctx) service.Identity {
+	identity, _ := c.Locals(localsIdentity).(service.Identity)
+	return identity
+}
+
+// CurrentUser returns the user AuthMiddleware authenticated
+func CurrentUser(c *fiber.Ctx) models.User {
+	return CurrentIdentity(c).User
+}
+
+// RequireScope rejects requests made with an API key that lacks one of scopes. It runs after AuthMiddleware.
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		identity := CurrentIdentity(c)
+		for _, scope := range scopes {
+			if !identity.HasScope(scope) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "API key lacks the " + scope + " scope",
+				})
+			}
+		}
+		return c.Next()
+	}
+}
+
+// RejectAPIKeys keeps API keys away from routes that manage credentials, so a leaked
+// key cannot mint more keys. It runs after AuthMiddleware.
+func RejectAPIKeys(c *fiber.Ctx) error {
+	if CurrentIdentity(c).Method == service.AuthMethodAPIKey {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "API keys cannot be used here",
+		})
+	}
 	return c.Next()
 }
+
+// RequireRole rejects requests whose identity was not granted role. It runs after AuthMiddleware.
+func RequireRole(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !CurrentIdentity(c).HasRole(role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Forbidden",
+			})
+		}
+		return c.Next()
+	}
+}
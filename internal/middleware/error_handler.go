@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"errors"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
 )
 
 func ErrorHandler(c *fiber.Ctx, err error) error {
@@ -11,6 +13,11 @@ func ErrorHandler(c *fiber.Ctx, err error) error {
 	if e, ok := err.(*fiber.Error); ok {
 		code = e.Code
 	}
+	var exceeded *ratelimit.Exceeded
+	if errors.As(err, &exceeded) {
+		code = fiber.StatusTooManyRequests
+		SetRateLimitHeaders(c, exceeded.Decision)
+	}
 
 	// Log error with request info
 	log.Printf("[ERROR] %s %s - %d - %s\n", c.Method(), c.Path(), code, err.Error())
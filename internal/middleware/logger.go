@@ -2,14 +2,20 @@ package middleware
 
 import (
 	"log"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// secretBodyPrefix marks the routes whose request bodies carry provider keys, which are never logged
+const secretBodyPrefix = "/api/provider-keys"
+
 func RequestResponseLogger(c *fiber.Ctx) error {
 	// --- Log request ---
 	reqBody := ""
-	if c.Request().Body() != nil {
+	if strings.HasPrefix(c.Path(), secretBodyPrefix) {
+		reqBody = "[redacted]"
+	} else if c.Request().Body() != nil {
 		reqBody = string(c.Request().Body())
 	}
 	log.Printf("[REQUEST] %s %s\nHeaders: %v\nBody: %s\n", c.Method(), c.Path(), c.GetReqHeaders(), reqBody)
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
+)
+
+// localsRateLimit is the c.Locals key of the tightest limit charged so far
+const localsRateLimit = "ratelimit"
+
+// RateLimitIP charges requests to the client IP. It runs before AuthMiddleware, so
+// requests with bad credentials count too.
+func RateLimitIP(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := allowRequest(c, limiter, ratelimit.Subject{IP: c.IP()}); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// allowRequest charges a request to s and reports the tightest limit in the RateLimit
+// headers. The *ratelimit.Exceeded it returns is answered by ErrorHandler.
+func allowRequest(c *fiber.Ctx, limiter *ratelimit.Limiter, s ratelimit.Subject) error {
+	d, err := limiter.AllowRequest(c.UserContext(), s)
+	if err != nil {
+		return err
+	}
+	if previous, ok := c.Locals(localsRateLimit).(ratelimit.Decision); ok {
+		d = previous.Tighter(d)
+	}
+	c.Locals(localsRateLimit, d)
+	SetRateLimitHeaders(c, d)
+	return nil
+}
+
+// SetRateLimitHeaders reports a decision in the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers, and in Retry-After when it turned the request away
+func SetRateLimitHeaders(c *fiber.Ctx, d ratelimit.Decision) {
+	if d.Limit == 0 {
+		return
+	}
+	c.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(d.Reset.Seconds()))))
+	if !d.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(d.RetryAfter.Seconds()))))
+	}
+}
+
+// RateLimitSubject returns who the streams of a request are charged to. It runs after
+// AuthMiddleware.
+func RateLimitSubject(c *fiber.Ctx) ratelimit.Subject {
+	identity := CurrentIdentity(c)
+	s := ratelimit.Subject{UserID: identity.User.ID.String(), IP: c.IP()}
+	if identity.APIKeyID != uuid.Nil {
+		s.APIKeyID = identity.APIKeyID.String()
+	}
+	return s
+}
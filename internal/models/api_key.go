@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey is a long-lived credential of a user, limited to its scopes
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Kind       string     `json:"kind"`
+	Prefix     string     `json:"prefix"` // visible start of the key, to recognise it
+	Salt       []byte     `json:"-"`
+	KeyHash    []byte     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// API key kinds. Personal keys act for their owner from their own scripts; service
+// keys are meant for unattended jobs such as CI.
+const (
+	APIKeyKindPersonal = "personal"
+	APIKeyKindService  = "service"
+)
+
+// Scopes an API key can be granted
+const (
+	ScopeConversationsRead  = "conversations:read"
+	ScopeConversationsWrite = "conversations:write"
+	ScopeMessagesRead       = "messages:read"
+	ScopeMessagesWrite      = "messages:write"
+	ScopeUsageRead          = "usage:read"
+)
+
+// Scopes lists every scope
+var Scopes = []string{ScopeConversationsRead, ScopeConversationsWrite, ScopeMessagesRead, ScopeMessagesWrite, ScopeUsageRead}
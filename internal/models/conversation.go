@@ -7,8 +7,29 @@ import (
 )
 
 type Conversation struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Title     string    `json:"title"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                   uuid.UUID            `json:"id"`
+	UserID               uuid.UUID            `json:"user_id"`
+	WorkspaceID          *uuid.UUID           `json:"workspace_id,omitempty"` // set on shared conversations
+	Title                string               `json:"title"`
+	Settings             ConversationSettings `json:"settings"`
+	SourceConversationID *uuid.UUID           `json:"source_conversation_id,omitempty"` // set on replays
+	CreatedAt            time.Time            `json:"created_at"`
+}
+
+// ConversationSettings holds the per-conversation defaults applied to every
+// completion request that does not override them.
+type ConversationSettings struct {
+	SystemPrompt string           `json:"system_prompt,omitempty"`
+	Model        string           `json:"model,omitempty"`
+	Temperature  *float64         `json:"temperature,omitempty"`
+	TopP         *float64         `json:"top_p,omitempty"`
+	MaxTokens    *int64           `json:"max_tokens,omitempty"`
+	Tools        []ToolDefinition `json:"tools,omitempty"`
+}
+
+// ToolDefinition describes a function tool exposed to the model
+type ToolDefinition struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
 }
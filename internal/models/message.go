@@ -11,6 +11,10 @@ type Message struct {
 	ConversationID uuid.UUID `json:"conversation_id" db:"conversation_id"`
 	Role           string    `json:"role" db:"role"`       // "user" or "assistant"
 	Content        string    `json:"content" db:"content"` // message text
+	FinishReason   *string   `json:"finish_reason,omitempty" db:"finish_reason"`
+	Status         string    `json:"status" db:"status"`
+	Error          *string   `json:"error,omitempty" db:"error"` // why an assistant reply is incomplete
+	Pinned         bool      `json:"pinned" db:"pinned"`         // always sent to the model
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 }
 
@@ -24,3 +28,20 @@ const (
 	RoleAssistant = "assistant"
 	RoleSystem    = "system"
 )
+
+// Message statuses. Assistant replies that did not run to completion keep
+// their partial content with an interrupted or error status.
+const (
+	MessageStatusComplete    = "complete"
+	MessageStatusInterrupted = "interrupted"
+	MessageStatusError       = "error"
+)
+
+const (
+	// FinishReasonStop is the provider's finish reason for a reply that ended naturally
+	FinishReasonStop = "stop"
+	// FinishReasonCancelled marks an assistant reply that was stopped by the user or a disconnect
+	FinishReasonCancelled = "cancelled"
+	// FinishReasonError marks an assistant reply cut short by an upstream error
+	FinishReasonError = "error"
+)
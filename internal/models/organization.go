@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization is a team whose members share the conversations of its workspaces
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"` // role of the caller, when listed for them
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Membership is the role of a user in an organization
+type Membership struct {
+	OrgID     uuid.UUID `json:"org_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Membership roles, from most to least privileged. Owners and admins manage members
+// and workspaces, members read and write conversations, viewers only read them.
+const (
+	MemberRoleOwner  = "owner"
+	MemberRoleAdmin  = "admin"
+	MemberRoleMember = "member"
+	MemberRoleViewer = "viewer"
+)
+
+// Workspace groups the shared conversations of an organization.
+// AllowedModels limits the models they may use, empty allows any, and
+// DefaultSettings apply wherever a conversation does not set its own.
+type Workspace struct {
+	ID              uuid.UUID            `json:"id"`
+	OrgID           uuid.UUID            `json:"org_id"`
+	Name            string               `json:"name"`
+	AllowedModels   []string             `json:"allowed_models"`
+	DefaultSettings ConversationSettings `json:"default_settings"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
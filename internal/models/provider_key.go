@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProviderKey is an upstream provider key of a user or an organization. The key itself
+// is only kept encrypted and never leaves the server.
+type ProviderKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	OrgID      *uuid.UUID `json:"org_id,omitempty"`
+	KeyID      string     `json:"-"` // master key the secret is sealed under
+	Nonce      []byte     `json:"-"`
+	Ciphertext []byte     `json:"-"`
+	Hint       string     `json:"hint"` // end of the key, to recognise it
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
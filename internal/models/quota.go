@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ModelInfo is a model of the registry with its prices in USD per million tokens
+type ModelInfo struct {
+	ID            string    `json:"id"`
+	InputPrice    float64   `json:"input_price"`
+	OutputPrice   float64   `json:"output_price"`
+	ContextWindow int       `json:"context_window"` // tokens, 0 when unknown
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Cost returns what a call with these token counts costs, in USD
+func (m ModelInfo) Cost(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*m.InputPrice + float64(completionTokens)*m.OutputPrice) / 1e6
+}
+
+// Quota holds the token and cost budgets of a user or an organization, or the default of
+// every user when neither is set. Nil limits do not apply.
+type Quota struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         *uuid.UUID `json:"user_id,omitempty"`
+	OrgID          *uuid.UUID `json:"org_id,omitempty"`
+	DailyTokens    *int64     `json:"daily_tokens"`
+	MonthlyTokens  *int64     `json:"monthly_tokens"`
+	DailyCost      *float64   `json:"daily_cost"` // USD
+	MonthlyCost    *float64   `json:"monthly_cost"`
+	DowngradeModel *string    `json:"downgrade_model"` // used past a budget instead of rejecting
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// QuotaUsage is what was spent against a quota in one period
+type QuotaUsage struct {
+	Tokens int64   `json:"tokens"`
+	Cost   float64 `json:"cost"` // USD
+}
+
+// QuotaStatus is a quota along with what was spent against it today and this month
+type QuotaStatus struct {
+	Quota   *Quota     `json:"quota"` // nil when no quota applies
+	Daily   QuotaUsage `json:"daily"`
+	Monthly QuotaUsage `json:"monthly"`
+}
+
+// Quota periods, in UTC
+const (
+	QuotaPeriodDay   = "day"
+	QuotaPeriodMonth = "month"
+)
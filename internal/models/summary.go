@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Summary condenses the turns of a conversation that fall outside the history window
+type Summary struct {
+	ConversationID  uuid.UUID `json:"conversation_id"`
+	Content         string    `json:"content"`
+	SummarizedUntil time.Time `json:"summarized_until"` // created_at of the last folded message
+	MessageCount    int       `json:"message_count"`    // number of messages folded so far
+	UpdatedAt       time.Time `json:"updated_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageEvent is one LLM call with what it used and cost
+type UsageEvent struct {
+	ID               uuid.UUID  `json:"id"`
+	UserID           *uuid.UUID `json:"user_id"`
+	OrgID            *uuid.UUID `json:"org_id"`
+	ConversationID   *uuid.UUID `json:"conversation_id"`
+	Model            string     `json:"model"`
+	Kind             string     `json:"kind"`
+	PromptTokens     int64      `json:"prompt_tokens"`
+	CompletionTokens int64      `json:"completion_tokens"`
+	Cost             float64    `json:"cost"` // USD, at the registry prices of the time
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// What an LLM call was made for
+const (
+	UsageKindChat    = "chat"
+	UsageKindTitle   = "title"
+	UsageKindSummary = "summary"
+)
+
+// Dimensions usage can be aggregated by
+const (
+	UsageGroupDay   = "day"
+	UsageGroupModel = "model"
+	UsageGroupUser  = "user"
+)
+
+// UsageGroups lists every dimension usage can be aggregated by
+var UsageGroups = []string{UsageGroupDay, UsageGroupModel, UsageGroupUser}
+
+// UsageRow is the usage of one group of a report. Only the dimensions grouped by are set.
+type UsageRow struct {
+	Day              *time.Time `json:"day,omitempty"`
+	Model            *string    `json:"model,omitempty"`
+	UserID           *uuid.UUID `json:"user_id,omitempty"`
+	Calls            int64      `json:"calls"`
+	PromptTokens     int64      `json:"prompt_tokens"`
+	CompletionTokens int64      `json:"completion_tokens"`
+	Cost             float64    `json:"cost"` // USD
+}
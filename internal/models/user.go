@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User is an account that owns conversations
+type User struct {
+	ID           uuid.UUID `json:"id"`
+	Email        string    `json:"email"`
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// OIDCLogin is a sign-in waiting for the identity provider to call back
+type OIDCLogin struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// AdminRole is the identity role that manages quotas and the model registry. It is
+// granted by the identity provider or to the users listed in ADMIN_USER_IDS.
+const AdminRole = "admin"
@@ -0,0 +1,282 @@
+// Package pubsub shares generations between server instances so that any instance
+// can serve the live stream of any conversation.
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+const (
+	// maxNotifySize keeps messages under the 8000 byte NOTIFY payload limit
+	maxNotifySize = 7900
+	// outboxSize is how many notices may wait to be sent; more event notices are dropped
+	outboxSize      = 4096
+	reconnectDelay  = 2 * time.Second
+	cleanupInterval = time.Minute
+)
+
+// Config configures the Postgres relay
+type Config struct {
+	// Channel is the LISTEN/NOTIFY channel shared by every instance
+	Channel string
+	// InstanceID tells the notices of this instance apart from the others
+	InstanceID string
+	// PayloadRetention is how long large payloads are kept in stream_payloads
+	PayloadRetention time.Duration
+}
+
+// message is what goes over the channel. Notices too large for NOTIFY are stored
+// in stream_payloads and only their id is sent.
+type message struct {
+	Instance  string             `json:"instance"`
+	Notice    *generation.Notice `json:"notice,omitempty"`
+	PayloadID int64              `json:"payload_id,omitempty"`
+}
+
+// Postgres relays generation notices through Postgres LISTEN/NOTIFY
+type Postgres struct {
+	pool     *pgxpool.Pool
+	payloads *repository.StreamPayloadRepo
+	manager  *generation.Manager
+	cfg      Config
+
+	outbox  chan generation.Notice
+	dropped atomic.Uint64 // event notices dropped because the outbox was full
+
+	mu       sync.Mutex
+	overflow []generation.Notice // other notices waiting for room, in order
+	pending  chan struct{}       // signals the sender that overflow is not empty
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewPostgres(pool *pgxpool.Pool, payloads *repository.StreamPayloadRepo, manager *generation.Manager, cfg Config) *Postgres {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Postgres{
+		pool:     pool,
+		payloads: payloads,
+		manager:  manager,
+		cfg:      cfg,
+		outbox:   make(chan generation.Notice, outboxSize),
+		pending:  make(chan struct{}, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start registers the relay with the manager and starts listening and sending
+func (p *Postgres) Start() {
+	p.manager.SetRelay(p)
+
+	listening := make(chan struct{})
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		p.listen(listening)
+	}()
+	go func() {
+		defer p.wg.Done()
+		p.send()
+	}()
+	<-listening
+}
+
+// Close stops the relay and releases its connection. Notices not sent yet are dropped.
+func (p *Postgres) Close() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Send queues a notice for the other instances without blocking the publisher.
+// While Postgres falls behind and the outbox is full, event notices are dropped: the
+// mirrors that miss them end with an error, and their clients have to resume against
+// the instance running the generation, or its stored reply once it finished. Every other
+// notice is kept in an overflow queue until there is room, since losing a finish, a
+// cancel or a subscription would leave generations running or mirrors hanging.
+func (p *Postgres) Send(n generation.Notice) {
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.overflow) == 0 {
+		select {
+		case p.outbox <- n:
+			return
+		default:
+		}
+	}
+	if n.Kind == generation.NoticeEvent {
+		dropped := p.dropped.Add(1)
+		log.Printf("Dropping event notice for generation %s: relay outbox full (%d dropped so far)", n.GenerationID, dropped)
+		return
+	}
+	p.overflow = append(p.overflow, n)
+	select {
+	case p.pending <- struct{}{}:
+	default:
+	}
+}
+
+// Dropped returns how many event notices were dropped because the outbox was full
+func (p *Postgres) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// nextOverflow takes the oldest notice waiting in the overflow queue
+func (p *Postgres) nextOverflow() (generation.Notice, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.overflow) == 0 {
+		return generation.Notice{}, false
+	}
+	n := p.overflow[0]
+	p.overflow = p.overflow[1:]
+	return n, true
+}
+
+// send publishes queued notices one at a time so they are delivered in order. The outbox
+// goes first: overflowing notices were queued after everything in it.
+func (p *Postgres) send() {
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+
+	relay := func(n generation.Notice) {
+		if err := p.notify(n); err != nil {
+			log.Printf("Error relaying %s notice for generation %s: %v", n.Kind, n.GenerationID, err)
+		}
+	}
+	for {
+		select {
+		case n := <-p.outbox:
+			relay(n)
+			continue
+		case <-p.ctx.Done():
+			return
+		default:
+		}
+		if n, ok := p.nextOverflow(); ok {
+			relay(n)
+			continue
+		}
+
+		select {
+		case n := <-p.outbox:
+			relay(n)
+		case <-p.pending:
+		case <-cleanup.C:
+			p.payloads.DeletePayloadsOlderThan(p.ctx, p.cfg.PayloadRetention)
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Postgres) notify(n generation.Notice) error {
+	msg := message{Instance: p.cfg.InstanceID, Notice: &n}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxNotifySize {
+		notice, _ := json.Marshal(n)
+		id, err := p.payloads.SavePayload(p.ctx, notice)
+		if err != nil {
+			return err
+		}
+		payload, _ = json.Marshal(message{Instance: p.cfg.InstanceID, PayloadID: id})
+	}
+
+	_, err = p.pool.Exec(p.ctx, `SELECT pg_notify($1, $2)`, p.cfg.Channel, string(payload))
+	return err
+}
+
+// listen holds a connection that LISTENs on the channel, reconnecting when it is lost.
+// listening is closed once the first LISTEN succeeded or the relay was closed.
+func (p *Postgres) listen(listening chan struct{}) {
+	var once bool
+	ready := func() {
+		if !once {
+			once = true
+			close(listening)
+		}
+	}
+	defer ready()
+
+	for p.ctx.Err() == nil {
+		err := p.listenOnce(ready)
+		if p.ctx.Err() != nil {
+			return
+		}
+		log.Printf("Stream relay lost its connection, reconnecting: %v", err)
+
+		select {
+		case <-time.After(reconnectDelay):
+		case <-p.ctx.Done():
+		}
+	}
+}
+
+func (p *Postgres) listenOnce(ready func()) error {
+	conn, err := p.pool.Acquire(p.ctx)
+	if err != nil {
+		return err
+	}
+	// The connection is closed rather than put back in the pool while it still LISTENs
+	defer conn.Release()
+	defer conn.Conn().Close(context.Background())
+
+	if _, err := conn.Exec(p.ctx, "LISTEN "+pgx.Identifier{p.cfg.Channel}.Sanitize()); err != nil {
+		return err
+	}
+	ready()
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(p.ctx)
+		if err != nil {
+			return err
+		}
+		p.receive([]byte(notification.Payload))
+	}
+}
+
+// receive hands a notice from another instance to the manager
+func (p *Postgres) receive(payload []byte) {
+	var msg message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		log.Printf("Error decoding relayed notice: %v", err)
+		return
+	}
+	if msg.Instance == p.cfg.InstanceID {
+		return
+	}
+
+	if msg.PayloadID != 0 {
+		stored, err := p.payloads.GetPayload(p.ctx, msg.PayloadID)
+		if err != nil {
+			log.Printf("Error loading relayed payload %d: %v", msg.PayloadID, err)
+			return
+		}
+		msg.Notice = &generation.Notice{}
+		if err := json.Unmarshal(stored, msg.Notice); err != nil {
+			log.Printf("Error decoding relayed payload %d: %v", msg.PayloadID, err)
+			return
+		}
+	}
+	if msg.Notice != nil {
+		p.manager.Receive(*msg.Notice)
+	}
+}
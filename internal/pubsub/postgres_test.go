@@ -0,0 +1,70 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+)
+
+func TestPublishDoesNotBlockOnFullOutbox(t *testing.T) {
+	manager := generation.NewManager(generation.Config{ReplayWindow: time.Minute})
+	// Never started, so nothing drains the outbox
+	relay := NewPostgres(nil, nil, manager, Config{InstanceID: "test"})
+	manager.SetRelay(relay)
+
+	g, err := manager.Create(uuid.New())
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	const published = outboxSize + 10
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < published; i++ {
+			g.Publish("delta", i)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a relay that never drains")
+	}
+
+	events, _, _ := g.Events(0)
+	if len(events) != published {
+		t.Errorf("got %d local events, want %d", len(events), published)
+	}
+	if got := relay.Dropped(); got != published-outboxSize {
+		t.Errorf("got %d dropped notices, want %d", got, published-outboxSize)
+	}
+}
+
+func TestSendKeepsControlNoticesOnFullOutbox(t *testing.T) {
+	relay := NewPostgres(nil, nil, generation.NewManager(generation.Config{ReplayWindow: time.Minute}), Config{InstanceID: "test"})
+	genID := uuid.New()
+	for i := 0; i < outboxSize; i++ {
+		relay.Send(generation.Notice{Kind: generation.NoticeEvent, GenerationID: genID})
+	}
+
+	relay.Send(generation.Notice{Kind: generation.NoticeCancel, GenerationID: genID})
+	relay.Send(generation.Notice{Kind: generation.NoticeEvent, GenerationID: genID})
+	relay.Send(generation.Notice{Kind: generation.NoticeUnsubscribe, GenerationID: genID})
+
+	if got := relay.Dropped(); got != 1 {
+		t.Errorf("got %d dropped notices, want only the event", got)
+	}
+	var kinds []string
+	for {
+		n, ok := relay.nextOverflow()
+		if !ok {
+			break
+		}
+		kinds = append(kinds, n.Kind)
+	}
+	if len(kinds) != 2 || kinds[0] != generation.NoticeCancel || kinds[1] != generation.NoticeUnsubscribe {
+		t.Errorf("kept %v, want the cancel then the unsubscribe", kinds)
+	}
+}
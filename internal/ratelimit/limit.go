@@ -0,0 +1,101 @@
+// Package ratelimit limits how often callers may send requests and how many streams
+// they may run at once, per user, per API key and per IP.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket that holds Burst tokens and refills all of them over Period.
+// The zero Limit is disabled.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit reads a limit written as count/period, such as 60/1m. An empty string or a
+// count of 0 disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Limit{}, nil
+	}
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must be written as count/period, such as 60/1m", s)
+	}
+	burst, err := strconv.Atoi(count)
+	if err != nil || burst < 0 {
+		return Limit{}, fmt.Errorf("limit %q must start with a count", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must end with a period such as 1m", s)
+	}
+	if burst == 0 {
+		return Limit{}, nil
+	}
+	return Limit{Burst: burst, Period: d}, nil
+}
+
+// Enabled reports whether the limit applies
+func (l Limit) Enabled() bool {
+	return l.Burst > 0 && l.Period > 0
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Burst, l.Period)
+}
+
+// Decision is the outcome of taking a token or a stream slot, as reported to the caller
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request may pass, when not allowed
+}
+
+// Bucket is the stored state of a token bucket
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Take refills b for the time passed since it was last updated and takes a token if
+// one is left. A bucket that was never used starts full.
+func (l Limit) Take(b *Bucket, now time.Time) Decision {
+	rate := float64(l.Burst) / l.Period.Seconds() // tokens per second
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = float64(l.Burst)
+	} else if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(l.Burst), b.Tokens+elapsed*rate)
+	}
+	b.UpdatedAt = now
+
+	d := Decision{Limit: l.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - b.Tokens) / rate)
+	}
+	d.Remaining = int(b.Tokens)
+	d.Reset = seconds((float64(l.Burst) - b.Tokens) / rate)
+	return d
+}
+
+// Refund gives back a token taken from b, up to a full bucket
+func (l Limit) Refund(b *Bucket) {
+	b.Tokens = math.Min(float64(l.Burst), b.Tokens+1)
+}
+
+// seconds converts a number of seconds into a duration, rounded up to whole seconds as
+// the headers carry them
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s)) * time.Second
+}
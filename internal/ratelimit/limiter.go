@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Config sets the limits of a Limiter. Disabled limits and stream limits of 0 do not apply.
+type Config struct {
+	UserRequests Limit
+	KeyRequests  Limit
+	IPRequests   Limit
+
+	UserStreams int
+	KeyStreams  int
+	IPStreams   int
+}
+
+// Subject is who a request or stream is charged to. Empty fields are not charged.
+type Subject struct {
+	UserID   string
+	APIKeyID string
+	IP       string
+}
+
+// Exceeded is returned when a limit turns a request or stream away
+type Exceeded struct {
+	Decision Decision
+	Streams  bool // the concurrent stream limit, rather than the request rate
+}
+
+func (e *Exceeded) Error() string {
+	if e.Streams {
+		return "too many concurrent streams"
+	}
+	return "too many requests"
+}
+
+// Limiter charges requests and streams against the limits of the user, API key and IP
+// they come from. A nil Limiter lets everything through.
+type Limiter struct {
+	store Store
+	cfg   Config
+}
+
+func New(store Store, cfg Config) *Limiter {
+	return &Limiter{
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// AllowRequest takes a token from every request bucket of s and returns the decision of
+// the one closest to its limit, with an *Exceeded error when one is empty. A request
+// turned away gives back the tokens it took from the other buckets. A store that fails
+// lets the request through.
+func (l *Limiter) AllowRequest(ctx context.Context, s Subject) (Decision, error) {
+	if l == nil {
+		return Decision{}, nil
+	}
+	type token struct {
+		key   string
+		limit Limit
+	}
+	var taken []token
+	var tightest Decision
+	for _, b := range []struct {
+		key   string
+		id    string
+		limit Limit
+	}{
+		{"user", s.UserID, l.cfg.UserRequests},
+		{"key", s.APIKeyID, l.cfg.KeyRequests},
+		{"ip", s.IP, l.cfg.IPRequests},
+	} {
+		if b.id == "" || !b.limit.Enabled() {
+			continue
+		}
+		key := "requests:" + b.key + ":" + b.id
+		d, err := l.store.Take(ctx, key, b.limit)
+		if err != nil {
+			continue
+		}
+		if !d.Allowed {
+			for _, t := range taken {
+				l.store.Refund(ctx, t.key, t.limit)
+			}
+			return d, &Exceeded{Decision: d}
+		}
+		taken = append(taken, token{key, b.limit})
+		tightest = tightest.Tighter(d)
+	}
+	return tightest, nil
+}
+
+// AcquireStream takes a stream slot of the user, API key and IP of s, and returns the
+// function that frees them once the stream ends. A store that fails lets the stream run.
+func (l *Limiter) AcquireStream(ctx context.Context, s Subject) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	type slot struct{ key, lease string }
+	var held []slot
+	release = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, h := range held {
+			if err := l.store.Release(ctx, h.key, h.lease); err != nil {
+				log.Printf("Error releasing stream slot %s: %v", h.key, err)
+			}
+		}
+	}
+
+	for _, b := range []struct {
+		key string
+		id  string
+		max int
+	}{
+		{"user", s.UserID, l.cfg.UserStreams},
+		{"key", s.APIKeyID, l.cfg.KeyStreams},
+		{"ip", s.IP, l.cfg.IPStreams},
+	} {
+		if b.id == "" || b.max <= 0 {
+			continue
+		}
+		key := "streams:" + b.key + ":" + b.id
+		lease, d, err := l.store.Acquire(ctx, key, b.max)
+		if err != nil {
+			continue
+		}
+		if !d.Allowed {
+			release()
+			return nil, &Exceeded{Decision: d, Streams: true}
+		}
+		held = append(held, slot{key, lease})
+	}
+	return release, nil
+}
+
+// Tighter returns whichever of d and o leaves the caller less room. The zero Decision,
+// of no limit, leaves the most.
+func (d Decision) Tighter(o Decision) Decision {
+	switch {
+	case o.Limit == 0:
+		return d
+	case d.Limit == 0:
+		return o
+	case d.Allowed != o.Allowed:
+		if d.Allowed {
+			return o
+		}
+		return d
+	case o.Remaining < d.Remaining || (o.Remaining == d.Remaining && o.Reset > d.Reset):
+		return o
+	default:
+		return d
+	}
+}
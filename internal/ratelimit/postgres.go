@@ -0,0 +1,200 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const cleanupInterval = time.Minute
+
+// Postgres is a Store shared by every replica, in the rate_limit_buckets and
+// rate_limit_leases tables. Time is taken from the database clock so replicas with
+// drifting clocks agree.
+type Postgres struct {
+	pool *pgxpool.Pool
+	// leaseTTL is how long a stream slot outlives the last renewal of its instance
+	leaseTTL time.Duration
+
+	mu   sync.Mutex
+	held map[string]struct{} // leases of this instance, renewed until released
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewPostgres(pool *pgxpool.Pool, leaseTTL time.Duration) *Postgres {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Postgres{
+		pool:     pool,
+		leaseTTL: leaseTTL,
+		held:     make(map[string]struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start renews the leases of this instance and deletes expired state in the background
+func (p *Postgres) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.maintain()
+	}()
+}
+
+// Close stops the background work. The leases still held expire after the lease TTL.
+func (p *Postgres) Close() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	var d Decision
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		// A new bucket starts full. The no-op update locks an existing one and returns it.
+		var b Bucket
+		var now time.Time
+		err := tx.QueryRow(ctx, `
+			INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+			VALUES ($1, $2, NOW(), NOW())
+			ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+			RETURNING tokens, updated_at, NOW()`,
+			key, float64(limit.Burst),
+		).Scan(&b.Tokens, &b.UpdatedAt, &now)
+		if err != nil {
+			return err
+		}
+
+		d = limit.Take(&b, now)
+		_, err = tx.Exec(ctx, `
+			UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3, full_at = $4
+			WHERE key = $1`,
+			key, b.Tokens, b.UpdatedAt, now.Add(d.Reset),
+		)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error in Take: %v", err)
+		return Decision{}, err
+	}
+	return d, nil
+}
+
+func (p *Postgres) Refund(ctx context.Context, key string, limit Limit) error {
+	_, err := p.pool.Exec(ctx, `
+		UPDATE rate_limit_buckets SET tokens = LEAST(tokens + 1, $2)
+		WHERE key = $1`,
+		key, float64(limit.Burst),
+	)
+	if err != nil {
+		log.Printf("Error in Refund: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) Acquire(ctx context.Context, key string, max int) (string, Decision, error) {
+	var lease string
+	var held int
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		// Serialize the slots of a key, as counting them does not lock anything
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, key); err != nil {
+			return err
+		}
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM rate_limit_leases WHERE key = $1 AND expires_at > NOW()`,
+			key,
+		).Scan(&held)
+		if err != nil || held >= max {
+			return err
+		}
+		held++
+		return tx.QueryRow(ctx, `
+			INSERT INTO rate_limit_leases (key, expires_at)
+			VALUES ($1, NOW() + make_interval(secs => $2))
+			RETURNING lease::text`,
+			key, p.leaseTTL.Seconds(),
+		).Scan(&lease)
+	})
+	if err != nil {
+		log.Printf("Error in Acquire: %v", err)
+		return "", Decision{}, err
+	}
+	if lease == "" {
+		return "", Decision{Limit: max, RetryAfter: streamRetryAfter}, nil
+	}
+
+	p.mu.Lock()
+	p.held[lease] = struct{}{}
+	p.mu.Unlock()
+	return lease, Decision{Allowed: true, Limit: max, Remaining: max - held}, nil
+}
+
+func (p *Postgres) Release(ctx context.Context, key, lease string) error {
+	p.mu.Lock()
+	delete(p.held, lease)
+	p.mu.Unlock()
+
+	_, err := p.pool.Exec(ctx, `DELETE FROM rate_limit_leases WHERE key = $1 AND lease = $2`, key, lease)
+	if err != nil {
+		log.Printf("Error in Release: %v", err)
+		return err
+	}
+	return nil
+}
+
+// maintain renews held leases three times per lease TTL and deletes full buckets and
+// expired leases once a minute
+func (p *Postgres) maintain() {
+	renew := time.NewTicker(p.leaseTTL / 3)
+	defer renew.Stop()
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-renew.C:
+			p.renew()
+		case <-cleanup.C:
+			p.cleanup()
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Postgres) renew() {
+	p.mu.Lock()
+	leases := make([]string, 0, len(p.held))
+	for lease := range p.held {
+		leases = append(leases, lease)
+	}
+	p.mu.Unlock()
+	if len(leases) == 0 {
+		return
+	}
+
+	_, err := p.pool.Exec(p.ctx, `
+		UPDATE rate_limit_leases SET expires_at = NOW() + make_interval(secs => $2)
+		WHERE lease = ANY($1::uuid[])`,
+		leases, p.leaseTTL.Seconds(),
+	)
+	if err != nil {
+		log.Printf("Error renewing %d rate limit leases: %v", len(leases), err)
+	}
+}
+
+func (p *Postgres) cleanup() {
+	if _, err := p.pool.Exec(p.ctx, `DELETE FROM rate_limit_buckets WHERE full_at <= NOW()`); err != nil {
+		log.Printf("Error deleting full rate limit buckets: %v", err)
+	}
+	if _, err := p.pool.Exec(p.ctx, `DELETE FROM rate_limit_leases WHERE expires_at <= NOW()`); err != nil {
+		log.Printf("Error deleting expired rate limit leases: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "60/1m", want: Limit{Burst: 60, Period: time.Minute}},
+		{in: " 5/10s ", want: Limit{Burst: 5, Period: 10 * time.Second}},
+		{in: "", want: Limit{}},
+		{in: "0/1m", want: Limit{}},
+		{in: "60", wantErr: true},
+		{in: "x/1m", wantErr: true},
+		{in: "-1/1m", wantErr: true},
+		{in: "60/0s", wantErr: true},
+		{in: "60/soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLimitTake(t *testing.T) {
+	limit := Limit{Burst: 3, Period: 3 * time.Second} // a token per second
+	start := time.Unix(1000, 0)
+	var b Bucket
+
+	for i := 2; i >= 0; i-- {
+		d := limit.Take(&b, start)
+		if !d.Allowed || d.Remaining != i || d.Limit != 3 {
+			t.Fatalf("take %d = %+v, want allowed with %d remaining", 3-i, d, i)
+		}
+	}
+	d := limit.Take(&b, start)
+	if d.Allowed || d.RetryAfter != time.Second || d.Reset != 3*time.Second {
+		t.Fatalf("take on empty bucket = %+v, want retry after 1s and reset in 3s", d)
+	}
+
+	if d := limit.Take(&b, start.Add(1500*time.Millisecond)); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("take after 1.5s = %+v, want allowed with 0 remaining", d)
+	}
+	if d := limit.Take(&b, start.Add(time.Hour)); !d.Allowed || d.Remaining != 2 {
+		t.Fatalf("take after an hour = %+v, want a full bucket less one", d)
+	}
+}
+
+func TestMemoryStreams(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	a, d, _ := m.Acquire(ctx, "k", 2)
+	if a == "" || d.Remaining != 1 {
+		t.Fatalf("first acquire = %q %+v", a, d)
+	}
+	if b, _, _ := m.Acquire(ctx, "k", 2); b == "" || b == a {
+		t.Fatalf("second acquire = %q, want a new lease", b)
+	}
+	if c, d, _ := m.Acquire(ctx, "k", 2); c != "" || d.Allowed || d.RetryAfter == 0 {
+		t.Fatalf("third acquire = %q %+v, want it turned away", c, d)
+	}
+	if c, _, _ := m.Acquire(ctx, "other", 2); c == "" {
+		t.Fatal("another key shares the slots")
+	}
+
+	m.Release(ctx, "k", a)
+	if c, _, _ := m.Acquire(ctx, "k", 2); c == "" {
+		t.Fatal("released slot is not free")
+	}
+}
+
+func TestMemoryPrunesFullBuckets(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	limit := Limit{Burst: 1, Period: time.Second}
+
+	m.Take(context.Background(), "a", limit)
+	now = now.Add(2 * time.Minute)
+	m.Take(context.Background(), "b", limit)
+	if _, ok := m.buckets["a"]; ok {
+		t.Fatal("full bucket was kept")
+	}
+	if _, ok := m.buckets["b"]; !ok {
+		t.Fatal("bucket in use was pruned")
+	}
+}
+
+func TestLimiterRequests(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemory(), Config{
+		UserRequests: Limit{Burst: 5, Period: time.Minute},
+		KeyRequests:  Limit{Burst: 2, Period: time.Minute},
+	})
+	viaKey := Subject{UserID: "u", APIKeyID: "k", IP: "10.0.0.1"}
+
+	d, err := l.AllowRequest(ctx, viaKey)
+	if err != nil || d.Limit != 2 || d.Remaining != 1 {
+		t.Fatalf("first request = %+v, %v; want the key limit reported", d, err)
+	}
+	l.AllowRequest(ctx, viaKey)
+
+	_, err = l.AllowRequest(ctx, viaKey)
+	var exceeded *Exceeded
+	if !errors.As(err, &exceeded) || exceeded.Streams || exceeded.Decision.RetryAfter <= 0 {
+		t.Fatalf("third request with the key = %v, want *Exceeded with a retry", err)
+	}
+
+	// The user has tokens left for requests without the key, and was not charged for
+	// the request the key turned away
+	d, err = l.AllowRequest(ctx, Subject{UserID: "u"})
+	if err != nil || d.Limit != 5 || d.Remaining != 2 {
+		t.Fatalf("request without the key = %+v, %v", d, err)
+	}
+
+	// IP requests are not limited by this config
+	if d, err := l.AllowRequest(ctx, Subject{IP: "10.0.0.1"}); err != nil || d.Limit != 0 {
+		t.Fatalf("unlimited request = %+v, %v", d, err)
+	}
+}
+
+func TestLimiterStreams(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemory(), Config{UserStreams: 2, IPStreams: 1})
+
+	release, err := l.AcquireStream(ctx, Subject{UserID: "u", IP: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The IP slot is taken; the user slot taken before finding out is given back
+	_, err = l.AcquireStream(ctx, Subject{UserID: "u", IP: "a"})
+	var exceeded *Exceeded
+	if !errors.As(err, &exceeded) || !exceeded.Streams {
+		t.Fatalf("second stream from the IP = %v, want *Exceeded", err)
+	}
+	if _, err := l.AcquireStream(ctx, Subject{UserID: "u", IP: "b"}); err != nil {
+		t.Fatalf("second stream of the user = %v, want a free slot", err)
+	}
+	if _, err := l.AcquireStream(ctx, Subject{UserID: "u", IP: "c"}); err == nil {
+		t.Fatal("third stream of the user was let through")
+	}
+
+	release()
+	if _, err := l.AcquireStream(ctx, Subject{UserID: "v", IP: "a"}); err != nil {
+		t.Fatalf("stream after release = %v", err)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	if _, err := l.AllowRequest(context.Background(), Subject{UserID: "u"}); err != nil {
+		t.Fatal(err)
+	}
+	release, err := l.AcquireStream(context.Background(), Subject{UserID: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
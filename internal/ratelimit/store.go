@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// streamRetryAfter is what a caller turned away for running too many streams is told to
+// wait, as there is no telling when one of them ends
+const streamRetryAfter = 5 * time.Second
+
+// Store keeps the buckets and stream slots of every key
+type Store interface {
+	// Take takes a token from the bucket of key
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+	// Refund gives back a token taken from the bucket of key
+	Refund(ctx context.Context, key string, limit Limit) error
+	// Acquire takes one of max stream slots of key. The lease it returns is empty when
+	// none was free.
+	Acquire(ctx context.Context, key string, max int) (lease string, d Decision, err error)
+	// Release frees a slot taken by Acquire
+	Release(ctx context.Context, key, lease string) error
+}
+
+// Memory is a Store for a single instance
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	streams   map[string]map[string]struct{}
+	nextLease uint64
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	limit Limit
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*memoryBucket),
+		streams: make(map[string]map[string]struct{}),
+		now:     time.Now,
+	}
+}
+
+func (m *Memory) Take(_ context.Context, key string, limit Limit) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.prune(now)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		m.buckets[key] = b
+	}
+	b.limit = limit
+	return limit.Take(&b.Bucket, now), nil
+}
+
+func (m *Memory) Refund(_ context.Context, key string, limit Limit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if b, ok := m.buckets[key]; ok {
+		limit.Refund(&b.Bucket)
+	}
+	return nil
+}
+
+// prune forgets the buckets that have filled up again, which are the same as no bucket.
+// It runs at most once a minute.
+func (m *Memory) prune(now time.Time) {
+	if now.Sub(m.lastPrune) < time.Minute {
+		return
+	}
+	m.lastPrune = now
+	for key, b := range m.buckets {
+		if now.Sub(b.UpdatedAt) >= b.limit.Period {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func (m *Memory) Acquire(_ context.Context, key string, max int) (string, Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	held := m.streams[key]
+	if len(held) >= max {
+		return "", Decision{Limit: max, RetryAfter: streamRetryAfter}, nil
+	}
+	if held == nil {
+		held = make(map[string]struct{})
+		m.streams[key] = held
+	}
+	m.nextLease++
+	lease := strconv.FormatUint(m.nextLease, 10)
+	held[lease] = struct{}{}
+	return lease, Decision{Allowed: true, Limit: max, Remaining: max - len(held)}, nil
+}
+
+func (m *Memory) Release(_ context.Context, key, lease string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.streams[key], lease)
+	if len(m.streams[key]) == 0 {
+		delete(m.streams, key)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+const apiKeyColumns = `id, user_id, name, kind, prefix, salt, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+// scanAPIKey reads a row selected with apiKeyColumns
+func scanAPIKey(row pgx.Row, k *models.APIKey) error {
+	return row.Scan(&k.ID, &k.UserID, &k.Name, &k.Kind, &k.Prefix, &k.Salt, &k.KeyHash, &k.Scopes,
+		&k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+}
+
+type APIKeyRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewAPIKeyRepo constructor
+func NewAPIKeyRepo(db *pgxpool.Pool) *APIKeyRepo {
+	return &APIKeyRepo{
+		db: db,
+	}
+}
+
+// CreateAPIKey stores a key. It returns ErrConflict when the prefix is taken.
+func (r *APIKeyRepo) CreateAPIKey(ctx context.Context, params APIKeyCreateParams) (models.APIKey, error) {
+	var k models.APIKey
+	query := `INSERT INTO api_keys (user_id, name, kind, prefix, salt, key_hash, scopes, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING ` + apiKeyColumns
+	err := scanAPIKey(r.db.QueryRow(ctx, query, params.UserID, params.Name, params.Kind, params.Prefix,
+		params.Salt, params.KeyHash, params.Scopes, params.ExpiresAt), &k)
+	if isUniqueViolation(err) {
+		return models.APIKey{}, ErrConflict
+	}
+	if err != nil {
+		log.Printf("Error in creating api key: %v", err)
+		return models.APIKey{}, ErrInternal
+	}
+	return k, nil
+}
+
+// ListAPIKeys returns the keys of a user that are not revoked, newest first
+func (r *APIKeyRepo) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + `
+			  FROM api_keys
+			  WHERE user_id = $1 AND revoked_at IS NULL
+			  ORDER BY created_at DESC`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		log.Printf("Error in fetching api keys: %v", err)
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var k models.APIKey
+		if err := scanAPIKey(rows, &k); err != nil {
+			log.Printf("Error scanning api key: %v", err)
+			return nil, ErrInternal
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// GetAPIKeyByPrefix fetches a key by its visible prefix
+func (r *APIKeyRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	var k models.APIKey
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+	err := scanAPIKey(r.db.QueryRow(ctx, query, prefix), &k)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.APIKey{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching api key: %v", err)
+		return models.APIKey{}, ErrInternal
+	}
+	return k, nil
+}
+
+// RevokeAPIKey revokes a key of a user. It returns ErrNotFound for keys of other users.
+func (r *APIKeyRepo) RevokeAPIKey(ctx context.Context, id, userID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `UPDATE api_keys SET revoked_at = NOW()
+			  WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, id, userID)
+	if err != nil {
+		log.Printf("Error in revoking api key: %v", err)
+		return ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey records that a key was used. It writes at most once a minute per key.
+func (r *APIKeyRepo) TouchAPIKey(ctx context.Context, id uuid.UUID) {
+	_, err := r.db.Exec(ctx, `UPDATE api_keys SET last_used_at = NOW()
+			  WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, id)
+	if err != nil {
+		log.Printf("Error in touching api key: %v", err)
+	}
+}
@@ -2,12 +2,23 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"log"
+	"strings"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/typescript-any/llm-playground/internal/models"
 )
 
+const conversationColumns = `id, user_id, workspace_id, title, settings, source_conversation_id, created_at`
+
+// scanConversation reads a row selected with conversationColumns
+func scanConversation(row pgx.Row, conv *models.Conversation) error {
+	return row.Scan(&conv.ID, &conv.UserID, &conv.WorkspaceID, &conv.Title, &conv.Settings, &conv.SourceConversationID, &conv.CreatedAt)
+}
+
 type ConversationRepo struct {
 	db *pgxpool.Pool
 }
@@ -21,12 +32,10 @@ func NewConversationRepo(db *pgxpool.Pool) *ConversationRepo {
 
 func (r *ConversationRepo) CreateConversation(ctx context.Context, params ConversationCreateParams) (models.Conversation, error) {
 	var conv models.Conversation
-	query := `INSERT INTO conversations ( user_id, title)
-			  VALUES ($1, $2)
-		      RETURNING id, user_id, title, created_at`
-	err := r.db.QueryRow(ctx, query, params.UserID, params.Title).Scan(
-		&conv.ID, &conv.UserID, &conv.Title, &conv.CreatedAt,
-	)
+	query := `INSERT INTO conversations ( user_id, workspace_id, title, settings, source_conversation_id)
+			  VALUES ($1, $2, $3, $4, $5)
+		      RETURNING ` + conversationColumns
+	err := scanConversation(r.db.QueryRow(ctx, query, params.UserID, params.WorkspaceID, params.Title, params.Settings, params.SourceConversationID), &conv)
 
 	if err != nil {
 		log.Printf("Error in creating conversation: %v", err)
@@ -36,13 +45,21 @@ func (r *ConversationRepo) CreateConversation(ctx context.Context, params Conver
 	return conv, nil
 }
 
+// GetConversationsByUser lists the personal conversations of a user, or every conversation
+// of a workspace when WorkspaceID is set, newest first. Query filters on the title.
 func (r *ConversationRepo) GetConversationsByUser(ctx context.Context, params ConversationListParams) ([]models.Conversation, error) {
-	query := `SELECT id, user_id, title, created_at
+	owner := `user_id = $1 AND workspace_id IS NULL`
+	args := []any{params.UserID, params.Limit, params.Offset, likePattern(params.Query)}
+	if params.WorkspaceID != nil {
+		owner = `workspace_id = $1`
+		args[0] = *params.WorkspaceID
+	}
+	query := `SELECT ` + conversationColumns + `
 			  FROM conversations
-			  WHERE user_id = $1
+			  WHERE ` + owner + ` AND title ILIKE $4
 			  ORDER BY created_at DESC LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.Query(ctx, query, params.UserID, params.Limit, params.Offset)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		log.Printf("Error in fetching conversations: %v", err)
 		return nil, ErrInternal
@@ -52,7 +69,7 @@ func (r *ConversationRepo) GetConversationsByUser(ctx context.Context, params Co
 	var conversations []models.Conversation
 	for rows.Next() {
 		var conv models.Conversation
-		if err := rows.Scan(&conv.ID, &conv.UserID, &conv.Title, &conv.CreatedAt); err != nil {
+		if err := scanConversation(rows, &conv); err != nil {
 			log.Printf("Error scanning conversation: %v", err)
 			return nil, ErrInternal
 		}
@@ -64,3 +81,69 @@ func (r *ConversationRepo) GetConversationsByUser(ctx context.Context, params Co
 	}
 	return conversations, nil
 }
+
+// GetConversationByID fetches a single conversation
+func (r *ConversationRepo) GetConversationByID(ctx context.Context, id uuid.UUID) (models.Conversation, error) {
+	var conv models.Conversation
+	query := `SELECT ` + conversationColumns + `
+			  FROM conversations
+			  WHERE id = $1`
+	err := scanConversation(r.db.QueryRow(ctx, query, id), &conv)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Conversation{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching conversation: %v", err)
+		return models.Conversation{}, ErrInternal
+	}
+
+	return conv, nil
+}
+
+// mergedSettings merges the settings patch $3 into the stored settings and removes the
+// top-level keys the patch sets to null. Nulls nested in a value, such as a tool's JSON
+// schema, are kept.
+const mergedSettings = `(settings || COALESCE($3::jsonb, '{}'::jsonb))
+	- ARRAY(SELECT key FROM jsonb_each(COALESCE($3::jsonb, '{}'::jsonb)) WHERE value = 'null')`
+
+// UpdateConversation changes the title and merges a settings patch into the stored settings.
+// Keys set to null in the patch are removed.
+func (r *ConversationRepo) UpdateConversation(ctx context.Context, params ConversationUpdateParams) (models.Conversation, error) {
+	var conv models.Conversation
+	query := `UPDATE conversations
+			  SET title = COALESCE($2, title),
+			      settings = ` + mergedSettings + `
+			  WHERE id = $1
+			  RETURNING ` + conversationColumns
+	err := scanConversation(r.db.QueryRow(ctx, query, params.ID, params.Title, params.SettingsPatch), &conv)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Conversation{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in updating conversation: %v", err)
+		return models.Conversation{}, ErrInternal
+	}
+
+	return conv, nil
+}
+
+// DeleteConversation removes a conversation with its messages and summary
+func (r *ConversationRepo) DeleteConversation(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM conversations WHERE id = $1`, id)
+	if err != nil {
+		log.Printf("Error in deleting conversation: %v", err)
+		return ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// likeEscaper escapes the wildcards of ILIKE so a search matches its text literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern matches titles that contain q, or every title when q is empty
+func likePattern(q string) string {
+	return "%" + likeEscaper.Replace(q) + "%"
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to the database in TEST_DATABASE_URL, skipping the test without one
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("connecting to the test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestMergedSettings(t *testing.T) {
+	pool := testPool(t)
+
+	cases := []struct {
+		name     string
+		settings string
+		patch    json.RawMessage
+		want     string
+	}{
+		{
+			name:     "null removes a key",
+			settings: `{"model": "a", "temperature": 0.5}`,
+			patch:    json.RawMessage(`{"temperature": null}`),
+			want:     `{"model": "a"}`,
+		},
+		{
+			name:     "nested null is kept",
+			settings: `{"model": "a"}`,
+			patch:    json.RawMessage(`{"tools": [{"name": "search", "parameters": {"type": "object", "default": null}}]}`),
+			want:     `{"model": "a", "tools": [{"name": "search", "parameters": {"type": "object", "default": null}}]}`,
+		},
+		{
+			name:     "stored nulls are kept",
+			settings: `{"model": "a", "tools": [{"name": "search", "parameters": {"default": null}}]}`,
+			patch:    json.RawMessage(`{"model": "b"}`),
+			want:     `{"model": "b", "tools": [{"name": "search", "parameters": {"default": null}}]}`,
+		},
+		{
+			name:     "no patch",
+			settings: `{"model": "a"}`,
+			want:     `{"model": "a"}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// $2 stands in for the title so the patch keeps its place as $3
+			query := `SELECT ` + mergedSettings + ` FROM (SELECT $1::jsonb AS settings, $2::text AS title) c`
+			var got []byte
+			if err := pool.QueryRow(context.Background(), query, tc.settings, nil, tc.patch).Scan(&got); err != nil {
+				t.Fatalf("merging settings: %v", err)
+			}
+
+			var gotDoc, wantDoc any
+			if err := json.Unmarshal(got, &gotDoc); err != nil {
+				t.Fatalf("decoding %s: %v", got, err)
+			}
+			if err := json.Unmarshal([]byte(tc.want), &wantDoc); err != nil {
+				t.Fatalf("decoding %s: %v", tc.want, err)
+			}
+			if !reflect.DeepEqual(gotDoc, wantDoc) {
+				t.Fatalf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
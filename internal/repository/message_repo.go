@@ -2,14 +2,23 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/typescript-any/llm-playground/internal/models"
 )
 
+const messageColumns = `id, conversation_id, role, content, finish_reason, status, error, pinned, created_at`
+
+// scanMessage reads a row selected with messageColumns
+func scanMessage(row pgx.Row, m *models.Message) error {
+	return row.Scan(&m.ID, &m.ConversationID, &m.Role, &m.Content, &m.FinishReason, &m.Status, &m.Error, &m.Pinned, &m.CreatedAt)
+}
+
 type MessageRepo struct {
 	db *pgxpool.Pool
 }
@@ -33,18 +42,24 @@ func (r *MessageRepo) SaveMessage(ctx context.Context, params MessageSaveParams)
 	}
 
 	query := `
-		INSERT INTO messages (id, conversation_id, role, content, created_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, conversation_id, role, content, created_at
-	`
+		INSERT INTO messages (id, conversation_id, role, content, finish_reason, status, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + messageColumns
 
-	id := uuid.New()
+	id := params.ID
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
 	createdAt := time.Now()
+	status := params.Status
+	if status == "" {
+		status = models.MessageStatusComplete
+	}
 
-	row := r.db.QueryRow(ctx, query, id, params.ConversationID, params.Role, params.Content, createdAt)
+	row := r.db.QueryRow(ctx, query, id, params.ConversationID, params.Role, params.Content, params.FinishReason, status, params.Error, createdAt)
 
 	var m models.Message
-	if err := row.Scan(&m.ID, &m.ConversationID, &m.Role, &m.Content, &m.CreatedAt); err != nil {
+	if err := scanMessage(row, &m); err != nil {
 		fmt.Printf("Failed to save message %v", err)
 		return nil, ErrInternal
 	}
@@ -54,7 +69,7 @@ func (r *MessageRepo) SaveMessage(ctx context.Context, params MessageSaveParams)
 
 // List messages
 func (r *MessageRepo) GetMessages(ctx context.Context, convID uuid.UUID) ([]models.Message, error) {
-	query := `SELECT id, conversation_id, role, content, created_at from messages`
+	query := `SELECT ` + messageColumns + ` from messages`
 	rows, err := r.db.Query(ctx, query)
 
 	if err != nil {
@@ -66,7 +81,7 @@ func (r *MessageRepo) GetMessages(ctx context.Context, convID uuid.UUID) ([]mode
 	var messages []models.Message
 	for rows.Next() {
 		var message models.Message
-		if err := rows.Scan(&message.ID, &message.ConversationID, &message.Role, &message.Content, &message.CreatedAt); err != nil {
+		if err := scanMessage(rows, &message); err != nil {
 			return nil, ErrInternal
 		}
 		messages = append(messages, message)
@@ -81,13 +96,14 @@ func (r *MessageRepo) GetMessages(ctx context.Context, convID uuid.UUID) ([]mode
 
 // Get messages by conversation
 func (r *MessageRepo) GetMessagesByConversation(ctx context.Context, params MessageListParams) ([]models.Message, error) {
-	query := `SELECT id, conversation_id, role, content, created_at
+	query := `SELECT ` + messageColumns + `
 			  FROM messages
 			  WHERE conversation_id = $1
+			    AND ($3::timestamp IS NULL OR created_at > $3)
 			  ORDER BY created_at ASC
 			  LIMIT $2 
 			  `
-	rows, err := r.db.Query(ctx, query, params.ConversationID, params.Limit)
+	rows, err := r.db.Query(ctx, query, params.ConversationID, params.Limit, params.After)
 	if err != nil {
 		return nil, ErrInternal
 	}
@@ -96,7 +112,7 @@ func (r *MessageRepo) GetMessagesByConversation(ctx context.Context, params Mess
 	var messages []models.Message
 	for rows.Next() {
 		var message models.Message
-		if err := rows.Scan(&message.ID, &message.ConversationID, &message.Role, &message.Content, &message.CreatedAt); err != nil {
+		if err := scanMessage(rows, &message); err != nil {
 			return nil, ErrInternal
 		}
 		messages = append(messages, message)
@@ -108,3 +124,111 @@ func (r *MessageRepo) GetMessagesByConversation(ctx context.Context, params Mess
 	return messages, nil
 
 }
+
+// GetRecentMessages returns the newest messages of a conversation in chronological order
+func (r *MessageRepo) GetRecentMessages(ctx context.Context, params MessageListParams) ([]models.Message, error) {
+	query := `SELECT ` + messageColumns + `
+			  FROM (
+			      SELECT ` + messageColumns + `
+			      FROM messages
+			      WHERE conversation_id = $1
+			        AND ($3::timestamp IS NULL OR created_at > $3)
+			      ORDER BY created_at DESC
+			      LIMIT $2
+			  ) recent
+			  ORDER BY created_at ASC`
+	rows, err := r.db.Query(ctx, query, params.ConversationID, params.Limit, params.After)
+	if err != nil {
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	var messages []models.Message
+	for rows.Next() {
+		var message models.Message
+		if err := scanMessage(rows, &message); err != nil {
+			return nil, ErrInternal
+		}
+		messages = append(messages, message)
+	}
+
+	if len(messages) == 0 {
+		return nil, ErrNotFound
+	}
+	return messages, nil
+}
+
+// GetFirstMessageByRole returns the oldest message of a role in a conversation
+func (r *MessageRepo) GetFirstMessageByRole(ctx context.Context, convID uuid.UUID, role string) (*models.Message, error) {
+	query := `SELECT ` + messageColumns + `
+			  FROM messages
+			  WHERE conversation_id = $1 AND role = $2
+			  ORDER BY created_at ASC
+			  LIMIT 1`
+
+	var m models.Message
+	err := scanMessage(r.db.QueryRow(ctx, query, convID, role), &m)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, ErrInternal
+	}
+	return &m, nil
+}
+
+// GetPinnedMessages returns the pinned messages of a conversation in chronological
+// order, none when nothing is pinned
+func (r *MessageRepo) GetPinnedMessages(ctx context.Context, convID uuid.UUID) ([]models.Message, error) {
+	query := `SELECT ` + messageColumns + `
+			  FROM messages
+			  WHERE conversation_id = $1 AND pinned
+			  ORDER BY created_at ASC`
+	rows, err := r.db.Query(ctx, query, convID)
+	if err != nil {
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	var messages []models.Message
+	for rows.Next() {
+		var message models.Message
+		if err := scanMessage(rows, &message); err != nil {
+			return nil, ErrInternal
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, ErrInternal
+	}
+	return messages, nil
+}
+
+// SetMessagePinned pins or unpins a message of a conversation
+func (r *MessageRepo) SetMessagePinned(ctx context.Context, convID, id uuid.UUID, pinned bool) (*models.Message, error) {
+	query := `UPDATE messages SET pinned = $3
+			  WHERE id = $1 AND conversation_id = $2
+			  RETURNING ` + messageColumns
+
+	var m models.Message
+	err := scanMessage(r.db.QueryRow(ctx, query, id, convID, pinned), &m)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, ErrInternal
+	}
+	return &m, nil
+}
+
+// DeleteMessage removes a single message
+func (r *MessageRepo) DeleteMessage(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM messages WHERE id = $1`, id)
+	if err != nil {
+		return ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+const modelColumns = `id, input_price, output_price, context_window, created_at, updated_at`
+
+// scanModel reads a row selected with modelColumns
+func scanModel(row pgx.Row, m *models.ModelInfo) error {
+	return row.Scan(&m.ID, &m.InputPrice, &m.OutputPrice, &m.ContextWindow, &m.CreatedAt, &m.UpdatedAt)
+}
+
+type ModelRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewModelRepo constructor
+func NewModelRepo(db *pgxpool.Pool) *ModelRepo {
+	return &ModelRepo{
+		db: db,
+	}
+}
+
+// ListModels returns the models of the registry by id
+func (r *ModelRepo) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+modelColumns+` FROM model_registry ORDER BY id`)
+	if err != nil {
+		log.Printf("Error in fetching models: %v", err)
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	list := []models.ModelInfo{}
+	for rows.Next() {
+		var m models.ModelInfo
+		if err := scanModel(rows, &m); err != nil {
+			log.Printf("Error in scanning model: %v", err)
+			return nil, ErrInternal
+		}
+		list = append(list, m)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in iterating models: %v", err)
+		return nil, ErrInternal
+	}
+	return list, nil
+}
+
+// GetModel fetches a model of the registry
+func (r *ModelRepo) GetModel(ctx context.Context, id string) (models.ModelInfo, error) {
+	var m models.ModelInfo
+	err := scanModel(r.db.QueryRow(ctx, `SELECT `+modelColumns+` FROM model_registry WHERE id = $1`, id), &m)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ModelInfo{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching model: %v", err)
+		return models.ModelInfo{}, ErrInternal
+	}
+	return m, nil
+}
+
+// SaveModel adds a model to the registry or replaces its prices
+func (r *ModelRepo) SaveModel(ctx context.Context, params ModelSaveParams) (models.ModelInfo, error) {
+	var m models.ModelInfo
+	query := `INSERT INTO model_registry (id, input_price, output_price, context_window)
+			  VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (id) DO UPDATE
+			  SET input_price = EXCLUDED.input_price, output_price = EXCLUDED.output_price,
+			      context_window = EXCLUDED.context_window, updated_at = NOW()
+			  RETURNING ` + modelColumns
+	row := r.db.QueryRow(ctx, query, params.ID, params.InputPrice, params.OutputPrice, params.ContextWindow)
+	if err := scanModel(row, &m); err != nil {
+		log.Printf("Error in saving model: %v", err)
+		return models.ModelInfo{}, ErrInternal
+	}
+	return m, nil
+}
+
+// DeleteModel removes a model from the registry
+func (r *ModelRepo) DeleteModel(ctx context.Context, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM model_registry WHERE id = $1`, id)
+	if err != nil {
+		log.Printf("Error in deleting model: %v", err)
+		return ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+type OIDCLoginRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewOIDCLoginRepo constructor
+func NewOIDCLoginRepo(db *pgxpool.Pool) *OIDCLoginRepo {
+	return &OIDCLoginRepo{
+		db: db,
+	}
+}
+
+// SaveLogin stores a pending sign-in and drops the ones that were never completed
+func (r *OIDCLoginRepo) SaveLogin(ctx context.Context, login models.OIDCLogin) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM oidc_logins WHERE expires_at <= NOW()`); err != nil {
+		log.Printf("Error in deleting expired oidc logins: %v", err)
+	}
+
+	query := `INSERT INTO oidc_logins (state_hash, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4)`
+	if _, err := r.db.Exec(ctx, query, login.StateHash, login.Nonce, login.CodeVerifier, login.ExpiresAt); err != nil {
+		log.Printf("Error in creating oidc login: %v", err)
+		return ErrInternal
+	}
+	return nil
+}
+
+// TakeLogin removes a pending sign-in and returns it unless it has expired, so that
+// every state is completed at most once
+func (r *OIDCLoginRepo) TakeLogin(ctx context.Context, stateHash string) (models.OIDCLogin, error) {
+	var l models.OIDCLogin
+	query := `DELETE FROM oidc_logins WHERE state_hash = $1
+			  RETURNING state_hash, nonce, code_verifier, expires_at`
+	err := r.db.QueryRow(ctx, query, stateHash).Scan(&l.StateHash, &l.Nonce, &l.CodeVerifier, &l.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.OIDCLogin{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in taking oidc login: %v", err)
+		return models.OIDCLogin{}, ErrInternal
+	}
+	return l, nil
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+const organizationColumns = `id, name, created_at, updated_at`
+
+// scanOrganization reads a row selected with organizationColumns
+func scanOrganization(row pgx.Row, o *models.Organization) error {
+	return row.Scan(&o.ID, &o.Name, &o.CreatedAt, &o.UpdatedAt)
+}
+
+// membershipColumns selects from memberships m joined with users u
+const membershipColumns = `m.org_id, m.user_id, u.email, u.name, m.role, m.created_at, m.updated_at`
+
+// scanMembership reads a row selected with membershipColumns
+func scanMembership(row pgx.Row, m *models.Membership) error {
+	return row.Scan(&m.OrgID, &m.UserID, &m.Email, &m.Name, &m.Role, &m.CreatedAt, &m.UpdatedAt)
+}
+
+type OrganizationRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewOrganizationRepo constructor
+func NewOrganizationRepo(db *pgxpool.Pool) *OrganizationRepo {
+	return &OrganizationRepo{
+		db: db,
+	}
+}
+
+// CreateOrganization creates an organization with ownerID as its first owner
+func (r *OrganizationRepo) CreateOrganization(ctx context.Context, name string, ownerID uuid.UUID) (models.Organization, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Printf("Error in starting transaction: %v", err)
+		return models.Organization{}, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	var o models.Organization
+	query := `INSERT INTO organizations (name) VALUES ($1) RETURNING ` + organizationColumns
+	if err := scanOrganization(tx.QueryRow(ctx, query, name), &o); err != nil {
+		log.Printf("Error in creating organization: %v", err)
+		return models.Organization{}, ErrInternal
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO memberships (org_id, user_id, role) VALUES ($1, $2, $3)`,
+		o.ID, ownerID, models.MemberRoleOwner)
+	if err != nil {
+		log.Printf("Error in adding organization owner: %v", err)
+		return models.Organization{}, ErrInternal
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error in committing organization: %v", err)
+		return models.Organization{}, ErrInternal
+	}
+	o.Role = models.MemberRoleOwner
+	return o, nil
+}
+
+// ListOrganizations returns the organizations a user belongs to, with the user's role
+func (r *OrganizationRepo) ListOrganizations(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	query := `SELECT o.id, o.name, o.created_at, o.updated_at, m.role
+			  FROM organizations o
+			  JOIN memberships m ON m.org_id = o.id
+			  WHERE m.user_id = $1
+			  ORDER BY o.name`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		log.Printf("Error in fetching organizations: %v", err)
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		var o models.Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedAt, &o.UpdatedAt, &o.Role); err != nil {
+			log.Printf("Error scanning organization: %v", err)
+			return nil, ErrInternal
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, nil
+}
+
+// GetOrganization fetches a single organization
+func (r *OrganizationRepo) GetOrganization(ctx context.Context, id uuid.UUID) (models.Organization, error) {
+	var o models.Organization
+	query := `SELECT ` + organizationColumns + ` FROM organizations WHERE id = $1`
+	err := scanOrganization(r.db.QueryRow(ctx, query, id), &o)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Organization{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching organization: %v", err)
+		return models.Organization{}, ErrInternal
+	}
+	return o, nil
+}
+
+// GetMemberRole returns the role of a user in an organization, or ErrNotFound if they are not a member
+func (r *OrganizationRepo) GetMemberRole(ctx context.Context, orgID, userID uuid.UUID) (string, error) {
+	var role string
+	err := r.db.QueryRow(ctx, `SELECT role FROM memberships WHERE org_id = $1 AND user_id = $2`, orgID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching membership: %v", err)
+		return "", ErrInternal
+	}
+	return role, nil
+}
+
+// ListMembers returns the members of an organization in the order they joined
+func (r *OrganizationRepo) ListMembers(ctx context.Context, orgID uuid.UUID) ([]models.Membership, error) {
+	query := `SELECT ` + membershipColumns + `
+			  FROM memberships m
+			  JOIN users u ON u.id = m.user_id
+			  WHERE m.org_id = $1
+			  ORDER BY m.created_at`
+	rows, err := r.db.Query(ctx, query, orgID)
+	if err != nil {
+		log.Printf("Error in fetching members: %v", err)
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	members := []models.Membership{}
+	for rows.Next() {
+		var m models.Membership
+		if err := scanMembership(rows, &m); err != nil {
+			log.Printf("Error scanning member: %v", err)
+			return nil, ErrInternal
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// AddMember adds a user to an organization. It returns ErrConflict when they already are a member.
+func (r *OrganizationRepo) AddMember(ctx context.Context, params MembershipParams) (models.Membership, error) {
+	var m models.Membership
+	query := `WITH m AS (
+				  INSERT INTO memberships (org_id, user_id, role)
+				  VALUES ($1, $2, $3)
+				  RETURNING *
+			  )
+			  SELECT ` + membershipColumns + ` FROM m JOIN users u ON u.id = m.user_id`
+	err := scanMembership(r.db.QueryRow(ctx, query, params.OrgID, params.UserID, params.Role), &m)
+	if isUniqueViolation(err) {
+		return models.Membership{}, ErrConflict
+	}
+	if err != nil {
+		log.Printf("Error in adding member: %v", err)
+		return models.Membership{}, ErrInternal
+	}
+	return m, nil
+}
+
+// UpdateMemberRole changes the role of a member. It returns ErrConflict when that
+// would leave the organization without an owner.
+func (r *OrganizationRepo) UpdateMemberRole(ctx context.Context, params MembershipParams) (models.Membership, error) {
+	var m models.Membership
+	err := r.changeMember(ctx, params.OrgID, params.UserID, params.Role != models.MemberRoleOwner, func(tx pgx.Tx) error {
+		query := `WITH m AS (
+					  UPDATE memberships SET role = $3, updated_at = NOW()
+					  WHERE org_id = $1 AND user_id = $2
+					  RETURNING *
+				  )
+				  SELECT ` + membershipColumns + ` FROM m JOIN users u ON u.id = m.user_id`
+		return scanMembership(tx.QueryRow(ctx, query, params.OrgID, params.UserID, params.Role), &m)
+	})
+	if err != nil {
+		return models.Membership{}, err
+	}
+	return m, nil
+}
+
+// RemoveMember removes a user from an organization. It returns ErrConflict when they
+// are its last owner.
+func (r *OrganizationRepo) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	return r.changeMember(ctx, orgID, userID, true, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM memberships WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+		if err == nil && tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return err
+	})
+}
+
+// changeMember runs change on the membership of userID with the owners of the organization
+// locked. When dropsOwner, the change is refused if userID is the last owner.
+func (r *OrganizationRepo) changeMember(ctx context.Context, orgID, userID uuid.UUID, dropsOwner bool, change func(tx pgx.Tx) error) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Printf("Error in starting transaction: %v", err)
+		return ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT user_id FROM memberships WHERE org_id = $1 AND role = $2 FOR UPDATE`,
+		orgID, models.MemberRoleOwner)
+	if err != nil {
+		log.Printf("Error in locking owners: %v", err)
+		return ErrInternal
+	}
+	owners, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		log.Printf("Error in locking owners: %v", err)
+		return ErrInternal
+	}
+	if dropsOwner && len(owners) == 1 && owners[0] == userID {
+		return ErrConflict
+	}
+
+	err = change(tx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in changing membership: %v", err)
+		return ErrInternal
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error in committing membership: %v", err)
+		return ErrInternal
+	}
+	return nil
+}
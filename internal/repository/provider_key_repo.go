@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+const providerKeyColumns = `id, user_id, org_id, key_id, nonce, ciphertext, hint, created_at, updated_at`
+
+// scanProviderKey reads a row selected with providerKeyColumns
+func scanProviderKey(row pgx.Row, k *models.ProviderKey) error {
+	return row.Scan(&k.ID, &k.UserID, &k.OrgID, &k.KeyID, &k.Nonce, &k.Ciphertext, &k.Hint, &k.CreatedAt, &k.UpdatedAt)
+}
+
+type ProviderKeyRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewProviderKeyRepo constructor
+func NewProviderKeyRepo(db *pgxpool.Pool) *ProviderKeyRepo {
+	return &ProviderKeyRepo{
+		db: db,
+	}
+}
+
+// SaveProviderKey stores the provider key of a user or an organization, replacing the previous one
+func (r *ProviderKeyRepo) SaveProviderKey(ctx context.Context, params ProviderKeySaveParams) (models.ProviderKey, error) {
+	var k models.ProviderKey
+	conflict := `(user_id) WHERE user_id IS NOT NULL`
+	if params.OrgID != nil {
+		conflict = `(org_id) WHERE org_id IS NOT NULL`
+	}
+	query := `INSERT INTO provider_keys (user_id, org_id, key_id, nonce, ciphertext, hint)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT ` + conflict + ` DO UPDATE
+			  SET key_id = EXCLUDED.key_id, nonce = EXCLUDED.nonce, ciphertext = EXCLUDED.ciphertext,
+			      hint = EXCLUDED.hint, updated_at = NOW()
+			  RETURNING ` + providerKeyColumns
+	row := r.db.QueryRow(ctx, query, params.UserID, params.OrgID, params.KeyID, params.Nonce, params.Ciphertext, params.Hint)
+	if err := scanProviderKey(row, &k); err != nil {
+		log.Printf("Error in saving provider key: %v", err)
+		return models.ProviderKey{}, ErrInternal
+	}
+	return k, nil
+}
+
+// GetUserProviderKey fetches the provider key of a user
+func (r *ProviderKeyRepo) GetUserProviderKey(ctx context.Context, userID uuid.UUID) (models.ProviderKey, error) {
+	return r.getProviderKey(ctx, `user_id = $1`, userID)
+}
+
+// GetOrgProviderKey fetches the provider key of an organization
+func (r *ProviderKeyRepo) GetOrgProviderKey(ctx context.Context, orgID uuid.UUID) (models.ProviderKey, error) {
+	return r.getProviderKey(ctx, `org_id = $1`, orgID)
+}
+
+func (r *ProviderKeyRepo) getProviderKey(ctx context.Context, where string, id uuid.UUID) (models.ProviderKey, error) {
+	var k models.ProviderKey
+	query := `SELECT ` + providerKeyColumns + ` FROM provider_keys WHERE ` + where
+	err := scanProviderKey(r.db.QueryRow(ctx, query, id), &k)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ProviderKey{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching provider key: %v", err)
+		return models.ProviderKey{}, ErrInternal
+	}
+	return k, nil
+}
+
+// DeleteUserProviderKey removes the provider key of a user
+func (r *ProviderKeyRepo) DeleteUserProviderKey(ctx context.Context, userID uuid.UUID) error {
+	return r.deleteProviderKey(ctx, `user_id = $1`, userID)
+}
+
+// DeleteOrgProviderKey removes the provider key of an organization
+func (r *ProviderKeyRepo) DeleteOrgProviderKey(ctx context.Context, orgID uuid.UUID) error {
+	return r.deleteProviderKey(ctx, `org_id = $1`, orgID)
+}
+
+func (r *ProviderKeyRepo) deleteProviderKey(ctx context.Context, where string, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM provider_keys WHERE `+where, id)
+	if err != nil {
+		log.Printf("Error in deleting provider key: %v", err)
+		return ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListProviderKeysToRotate returns, in order of ID, up to limit provider keys after afterID
+// that are sealed under another master key than keyID
+func (r *ProviderKeyRepo) ListProviderKeysToRotate(ctx context.Context, keyID string, afterID uuid.UUID, limit int) ([]models.ProviderKey, error) {
+	query := `SELECT ` + providerKeyColumns + `
+			  FROM provider_keys
+			  WHERE key_id <> $1 AND id > $2
+			  ORDER BY id
+			  LIMIT $3`
+	rows, err := r.db.Query(ctx, query, keyID, afterID, limit)
+	if err != nil {
+		log.Printf("Error in fetching provider keys to rotate: %v", err)
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	var keys []models.ProviderKey
+	for rows.Next() {
+		var k models.ProviderKey
+		if err := scanProviderKey(rows, &k); err != nil {
+			log.Printf("Error in scanning provider key: %v", err)
+			return nil, ErrInternal
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in iterating provider keys: %v", err)
+		return nil, ErrInternal
+	}
+	return keys, nil
+}
+
+// ResealProviderKey stores a provider key encrypted under another master key. It reports
+// ErrConflict when the key was replaced or rotated since it was read.
+func (r *ProviderKeyRepo) ResealProviderKey(ctx context.Context, params ProviderKeyResealParams) error {
+	query := `UPDATE provider_keys
+			  SET key_id = $3, nonce = $4, ciphertext = $5
+			  WHERE id = $1 AND key_id = $2`
+	tag, err := r.db.Exec(ctx, query, params.ID, params.FromKeyID, params.KeyID, params.Nonce, params.Ciphertext)
+	if err != nil {
+		log.Printf("Error in resealing provider key: %v", err)
+		return ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrConflict
+	}
+	return nil
+}
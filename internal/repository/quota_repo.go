@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+const quotaColumns = `id, user_id, org_id, daily_tokens, monthly_tokens, daily_cost, monthly_cost, downgrade_model, created_at, updated_at`
+
+// scanQuota reads a row selected with quotaColumns
+func scanQuota(row pgx.Row, q *models.Quota) error {
+	return row.Scan(&q.ID, &q.UserID, &q.OrgID, &q.DailyTokens, &q.MonthlyTokens, &q.DailyCost, &q.MonthlyCost,
+		&q.DowngradeModel, &q.CreatedAt, &q.UpdatedAt)
+}
+
+type QuotaRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewQuotaRepo constructor
+func NewQuotaRepo(db *pgxpool.Pool) *QuotaRepo {
+	return &QuotaRepo{
+		db: db,
+	}
+}
+
+// GetQuota fetches the quota of a user or, with orgID, of an organization; with neither
+// it fetches the default quota of users
+func (r *QuotaRepo) GetQuota(ctx context.Context, userID, orgID *uuid.UUID) (models.Quota, error) {
+	var q models.Quota
+	query := `SELECT ` + quotaColumns + ` FROM quotas
+			  WHERE user_id IS NOT DISTINCT FROM $1 AND org_id IS NOT DISTINCT FROM $2`
+	err := scanQuota(r.db.QueryRow(ctx, query, userID, orgID), &q)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Quota{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching quota: %v", err)
+		return models.Quota{}, ErrInternal
+	}
+	return q, nil
+}
+
+// ListQuotas returns every quota, the default first
+func (r *QuotaRepo) ListQuotas(ctx context.Context) ([]models.Quota, error) {
+	query := `SELECT ` + quotaColumns + ` FROM quotas
+			  ORDER BY (user_id IS NOT NULL OR org_id IS NOT NULL), created_at`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		log.Printf("Error in fetching quotas: %v", err)
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	quotas := []models.Quota{}
+	for rows.Next() {
+		var q models.Quota
+		if err := scanQuota(rows, &q); err != nil {
+			log.Printf("Error in scanning quota: %v", err)
+			return nil, ErrInternal
+		}
+		quotas = append(quotas, q)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in iterating quotas: %v", err)
+		return nil, ErrInternal
+	}
+	return quotas, nil
+}
+
+// SaveQuota stores the limits of a quota, replacing those of the same owner
+func (r *QuotaRepo) SaveQuota(ctx context.Context, params QuotaSaveParams) (models.Quota, error) {
+	var q models.Quota
+	conflict := `((TRUE)) WHERE user_id IS NULL AND org_id IS NULL`
+	switch {
+	case params.UserID != nil:
+		conflict = `(user_id) WHERE user_id IS NOT NULL`
+	case params.OrgID != nil:
+		conflict = `(org_id) WHERE org_id IS NOT NULL`
+	}
+	query := `INSERT INTO quotas (user_id, org_id, daily_tokens, monthly_tokens, daily_cost, monthly_cost, downgrade_model)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  ON CONFLICT ` + conflict + ` DO UPDATE
+			  SET daily_tokens = EXCLUDED.daily_tokens, monthly_tokens = EXCLUDED.monthly_tokens,
+			      daily_cost = EXCLUDED.daily_cost, monthly_cost = EXCLUDED.monthly_cost,
+			      downgrade_model = EXCLUDED.downgrade_model, updated_at = NOW()
+			  RETURNING ` + quotaColumns
+	row := r.db.QueryRow(ctx, query, params.UserID, params.OrgID, params.DailyTokens, params.MonthlyTokens,
+		params.DailyCost, params.MonthlyCost, params.DowngradeModel)
+	if err := scanQuota(row, &q); err != nil {
+		log.Printf("Error in saving quota: %v", err)
+		return models.Quota{}, ErrInternal
+	}
+	return q, nil
+}
+
+// DeleteQuota removes the quota of a user, an organization or, with neither, the default
+func (r *QuotaRepo) DeleteQuota(ctx context.Context, userID, orgID *uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM quotas WHERE user_id IS NOT DISTINCT FROM $1 AND org_id IS NOT DISTINCT FROM $2`,
+		userID, orgID)
+	if err != nil {
+		log.Printf("Error in deleting quota: %v", err)
+		return ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetQuotaUsage returns what a subject spent in the day and the month starting at day and month
+func (r *QuotaRepo) GetQuotaUsage(ctx context.Context, subject string, day, month time.Time) (daily, monthly models.QuotaUsage, err error) {
+	query := `SELECT period, tokens, cost FROM quota_usage
+			  WHERE subject = $1 AND ((period = 'day' AND period_start = $2) OR (period = 'month' AND period_start = $3))`
+	rows, err := r.db.Query(ctx, query, subject, day, month)
+	if err != nil {
+		log.Printf("Error in fetching quota usage: %v", err)
+		return daily, monthly, ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var period string
+		var u models.QuotaUsage
+		if err := rows.Scan(&period, &u.Tokens, &u.Cost); err != nil {
+			log.Printf("Error in scanning quota usage: %v", err)
+			return daily, monthly, ErrInternal
+		}
+		if period == models.QuotaPeriodDay {
+			daily = u
+		} else {
+			monthly = u
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in iterating quota usage: %v", err)
+		return daily, monthly, ErrInternal
+	}
+	return daily, monthly, nil
+}
+
+// ChargeQuotaUsage adds usage to the periods of quota subjects, all or nothing. When a
+// charge would take usage past its limits nothing is charged and its index is returned;
+// otherwise the index is -1.
+func (r *QuotaRepo) ChargeQuotaUsage(ctx context.Context, charges []QuotaChargeParams) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Printf("Error in starting transaction: %v", err)
+		return -1, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO quota_usage (subject, period, period_start, tokens, cost)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (subject, period, period_start) DO UPDATE
+			  SET tokens = quota_usage.tokens + EXCLUDED.tokens, cost = quota_usage.cost + EXCLUDED.cost
+			  RETURNING tokens, cost`
+	for i, c := range charges {
+		var u models.QuotaUsage
+		err := tx.QueryRow(ctx, query, c.Subject, c.Period, c.PeriodStart, c.Tokens, c.Cost).Scan(&u.Tokens, &u.Cost)
+		if err != nil {
+			log.Printf("Error in charging quota usage: %v", err)
+			return -1, ErrInternal
+		}
+		if (c.MaxTokens != nil && u.Tokens > *c.MaxTokens) || (c.MaxCost != nil && u.Cost > *c.MaxCost) {
+			return i, nil
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error in committing quota usage: %v", err)
+		return -1, ErrInternal
+	}
+	return -1, nil
+}
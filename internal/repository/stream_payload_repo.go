@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StreamPayloadRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewStreamPayloadRepo constructor
+func NewStreamPayloadRepo(db *pgxpool.Pool) *StreamPayloadRepo {
+	return &StreamPayloadRepo{
+		db: db,
+	}
+}
+
+// SavePayload stores a payload and returns its id
+func (r *StreamPayloadRepo) SavePayload(ctx context.Context, payload []byte) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `INSERT INTO stream_payloads (payload) VALUES ($1) RETURNING id`, payload).Scan(&id)
+	if err != nil {
+		log.Printf("Error in saving stream payload: %v", err)
+		return 0, ErrInternal
+	}
+	return id, nil
+}
+
+// GetPayload returns a stored payload
+func (r *StreamPayloadRepo) GetPayload(ctx context.Context, id int64) ([]byte, error) {
+	var payload []byte
+	err := r.db.QueryRow(ctx, `SELECT payload FROM stream_payloads WHERE id = $1`, id).Scan(&payload)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching stream payload: %v", err)
+		return nil, ErrInternal
+	}
+	return payload, nil
+}
+
+// DeletePayloadsOlderThan removes payloads stored more than age ago
+func (r *StreamPayloadRepo) DeletePayloadsOlderThan(ctx context.Context, age time.Duration) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM stream_payloads WHERE created_at < NOW() - $1::interval`, age); err != nil {
+		log.Printf("Error in deleting stream payloads: %v", err)
+		return ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+type SummaryRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewSummaryRepo constructor
+func NewSummaryRepo(db *pgxpool.Pool) *SummaryRepo {
+	return &SummaryRepo{
+		db: db,
+	}
+}
+
+// GetSummary returns the stored summary of a conversation
+func (r *SummaryRepo) GetSummary(ctx context.Context, convID uuid.UUID) (models.Summary, error) {
+	var s models.Summary
+	query := `SELECT conversation_id, content, summarized_until, message_count, updated_at
+			  FROM conversation_summaries
+			  WHERE conversation_id = $1`
+	err := r.db.QueryRow(ctx, query, convID).Scan(
+		&s.ConversationID, &s.Content, &s.SummarizedUntil, &s.MessageCount, &s.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Summary{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching summary: %v", err)
+		return models.Summary{}, ErrInternal
+	}
+	return s, nil
+}
+
+// UpsertSummary stores a summary after new messages have been folded into it
+func (r *SummaryRepo) UpsertSummary(ctx context.Context, params SummaryUpsertParams) (models.Summary, error) {
+	var s models.Summary
+	query := `INSERT INTO conversation_summaries (conversation_id, content, summarized_until, message_count, updated_at)
+			  VALUES ($1, $2, $3, $4, NOW())
+			  ON CONFLICT (conversation_id) DO UPDATE
+			  SET content = EXCLUDED.content,
+			      summarized_until = EXCLUDED.summarized_until,
+			      message_count = EXCLUDED.message_count,
+			      updated_at = NOW()
+			  RETURNING conversation_id, content, summarized_until, message_count, updated_at`
+	err := r.db.QueryRow(ctx, query, params.ConversationID, params.Content, params.SummarizedUntil, params.MessageCount).Scan(
+		&s.ConversationID, &s.Content, &s.SummarizedUntil, &s.MessageCount, &s.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("Error in saving summary: %v", err)
+		return models.Summary{}, ErrInternal
+	}
+	return s, nil
+}
+
+// UpdateSummaryContent replaces the text of an existing summary without moving its boundary
+func (r *SummaryRepo) UpdateSummaryContent(ctx context.Context, convID uuid.UUID, content string) (models.Summary, error) {
+	var s models.Summary
+	query := `UPDATE conversation_summaries
+			  SET content = $2, updated_at = NOW()
+			  WHERE conversation_id = $1
+			  RETURNING conversation_id, content, summarized_until, message_count, updated_at`
+	err := r.db.QueryRow(ctx, query, convID, content).Scan(
+		&s.ConversationID, &s.Content, &s.SummarizedUntil, &s.MessageCount, &s.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Summary{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in updating summary: %v", err)
+		return models.Summary{}, ErrInternal
+	}
+	return s, nil
+}
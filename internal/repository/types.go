@@ -1,29 +1,198 @@
 package repository
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
 
 // ConversationCreateParams holds parameters for creating a conversation
 type ConversationCreateParams struct {
-	UserID uuid.UUID
-	Title  string
+	UserID               uuid.UUID
+	WorkspaceID          *uuid.UUID
+	Title                string
+	Settings             models.ConversationSettings
+	SourceConversationID *uuid.UUID
 }
 
-// ConversationListParams holds parameters for listing conversations
+// ConversationUpdateParams holds parameters for updating a conversation.
+// Nil fields are left unchanged.
+type ConversationUpdateParams struct {
+	ID            uuid.UUID
+	Title         *string
+	SettingsPatch json.RawMessage
+}
+
+// ConversationListParams holds parameters for listing conversations.
+// With WorkspaceID the conversations of the workspace are listed instead of the user's.
 type ConversationListParams struct {
-	UserID uuid.UUID
-	Offset int
-	Limit  int
+	UserID      uuid.UUID
+	WorkspaceID *uuid.UUID
+	Query       string // case-insensitive title search
+	Offset      int
+	Limit       int
 }
 
 // MessageSaveParams holds parameters for saving a message
 type MessageSaveParams struct {
+	ID             uuid.UUID // optional, generated when zero
 	ConversationID uuid.UUID
 	Role           string
 	Content        string
+	FinishReason   *string
+	Status         string // defaults to complete
+	Error          *string
 }
 
-// MessageListParams holds parameters for listing messages by conversation
+// MessageListParams holds parameters for listing messages by conversation.
+// When After is set only messages created after it are returned.
 type MessageListParams struct {
 	ConversationID uuid.UUID
+	After          *time.Time
 	Limit          int
 }
+
+// SummaryUpsertParams holds parameters for storing a conversation summary
+type SummaryUpsertParams struct {
+	ConversationID  uuid.UUID
+	Content         string
+	SummarizedUntil time.Time
+	MessageCount    int
+}
+
+// UserCreateParams holds parameters for creating an account
+type UserCreateParams struct {
+	Email        string
+	Name         string
+	PasswordHash string
+}
+
+// UserUpdateParams holds parameters for updating an account.
+// Nil fields are left unchanged.
+type UserUpdateParams struct {
+	ID           uuid.UUID
+	Name         *string
+	PasswordHash *string
+}
+
+// UserIdentityCreateParams holds parameters for creating an account for an external identity
+type UserIdentityCreateParams struct {
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+}
+
+// APIKeyCreateParams holds parameters for storing an API key
+type APIKeyCreateParams struct {
+	UserID    uuid.UUID
+	Name      string
+	Kind      string
+	Prefix    string
+	Salt      []byte
+	KeyHash   []byte
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// MembershipParams holds the role of a user in an organization
+type MembershipParams struct {
+	OrgID  uuid.UUID
+	UserID uuid.UUID
+	Role   string
+}
+
+// WorkspaceCreateParams holds parameters for creating a workspace
+type WorkspaceCreateParams struct {
+	OrgID           uuid.UUID
+	Name            string
+	AllowedModels   []string
+	DefaultSettings models.ConversationSettings
+}
+
+// WorkspaceUpdateParams holds parameters for updating a workspace.
+// Nil fields are left unchanged.
+type WorkspaceUpdateParams struct {
+	ID              uuid.UUID
+	Name            *string
+	AllowedModels   []string
+	DefaultSettings *models.ConversationSettings
+}
+
+// ProviderKeySaveParams holds an encrypted provider key of a user or, with OrgID, of an
+// organization. Saving replaces the previous key of the same owner.
+type ProviderKeySaveParams struct {
+	UserID     *uuid.UUID
+	OrgID      *uuid.UUID
+	KeyID      string
+	Nonce      []byte
+	Ciphertext []byte
+	Hint       string
+}
+
+// ProviderKeyResealParams holds a provider key encrypted again under another master key.
+// FromKeyID is the master key it was sealed under when it was read.
+type ProviderKeyResealParams struct {
+	ID         uuid.UUID
+	FromKeyID  string
+	KeyID      string
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// ModelSaveParams holds a model of the registry, with prices in USD per million tokens
+type ModelSaveParams struct {
+	ID            string
+	InputPrice    float64
+	OutputPrice   float64
+	ContextWindow int
+}
+
+// QuotaSaveParams holds the limits of a user or, with OrgID, of an organization; with
+// neither they are the default of every user. Saving replaces the previous limits of
+// the same owner.
+type QuotaSaveParams struct {
+	UserID         *uuid.UUID
+	OrgID          *uuid.UUID
+	DailyTokens    *int64
+	MonthlyTokens  *int64
+	DailyCost      *float64
+	MonthlyCost    *float64
+	DowngradeModel *string
+}
+
+// QuotaChargeParams holds usage charged to one period of a quota subject. With
+// MaxTokens or MaxCost the charge fails when it would take the usage past them.
+type QuotaChargeParams struct {
+	Subject     string
+	Period      string
+	PeriodStart time.Time
+	Tokens      int64
+	Cost        float64
+	MaxTokens   *int64
+	MaxCost     *float64
+}
+
+// UsageRecordParams holds one LLM call, priced by the caller
+type UsageRecordParams struct {
+	UserID           *uuid.UUID
+	OrgID            *uuid.UUID
+	ConversationID   *uuid.UUID
+	Model            string
+	Kind             string
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64
+}
+
+// UsageReportParams selects the calls made in [From, To), of UserID and OrgID when set,
+// and the models.UsageGroups to aggregate them by
+type UsageReportParams struct {
+	From    time.Time
+	To      time.Time
+	GroupBy []string
+	UserID  *uuid.UUID
+	OrgID   *uuid.UUID
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+// usageGroupColumns maps the dimensions of a usage report onto the expressions they group by
+var usageGroupColumns = map[string]string{
+	models.UsageGroupDay:   "(created_at AT TIME ZONE 'UTC')::date", // days start at midnight UTC
+	models.UsageGroupModel: "model",
+	models.UsageGroupUser:  "user_id",
+}
+
+type UsageRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewUsageRepo constructor
+func NewUsageRepo(db *pgxpool.Pool) *UsageRepo {
+	return &UsageRepo{
+		db: db,
+	}
+}
+
+// RecordUsage stores one LLM call
+func (r *UsageRepo) RecordUsage(ctx context.Context, params UsageRecordParams) error {
+	query := `INSERT INTO usage_events (user_id, org_id, conversation_id, model, kind, prompt_tokens, completion_tokens, cost)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.Exec(ctx, query, params.UserID, params.OrgID, params.ConversationID, params.Model, params.Kind,
+		params.PromptTokens, params.CompletionTokens, params.Cost)
+	if err != nil {
+		log.Printf("Error in recording usage: %v", err)
+		return ErrInternal
+	}
+	return nil
+}
+
+// UsageReport sums the calls selected by params per group, ordered by the groups
+func (r *UsageRepo) UsageReport(ctx context.Context, params UsageReportParams) ([]models.UsageRow, error) {
+	var groups []string
+	for _, g := range params.GroupBy {
+		column, ok := usageGroupColumns[g]
+		if !ok {
+			return nil, fmt.Errorf("unknown usage group %q", g)
+		}
+		groups = append(groups, column)
+	}
+
+	args := []any{params.From, params.To}
+	where := []string{"created_at >= $1", "created_at < $2"}
+	if params.UserID != nil {
+		args = append(args, *params.UserID)
+		where = append(where, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if params.OrgID != nil {
+		args = append(args, *params.OrgID)
+		where = append(where, fmt.Sprintf("org_id = $%d", len(args)))
+	}
+
+	selects := append(append([]string{}, groups...),
+		"COUNT(*)", "COALESCE(SUM(prompt_tokens), 0)", "COALESCE(SUM(completion_tokens), 0)", "COALESCE(SUM(cost), 0)")
+	query := `SELECT ` + strings.Join(selects, ", ") + ` FROM usage_events WHERE ` + strings.Join(where, " AND ")
+	if len(groups) > 0 {
+		query += ` GROUP BY ` + strings.Join(groups, ", ") + ` ORDER BY ` + strings.Join(groups, ", ")
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		log.Printf("Error in fetching usage report: %v", err)
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	report := []models.UsageRow{}
+	for rows.Next() {
+		var row models.UsageRow
+		dest := make([]any, 0, len(selects))
+		for _, g := range params.GroupBy {
+			switch g {
+			case models.UsageGroupDay:
+				dest = append(dest, &row.Day)
+			case models.UsageGroupModel:
+				dest = append(dest, &row.Model)
+			case models.UsageGroupUser:
+				dest = append(dest, &row.UserID)
+			}
+		}
+		dest = append(dest, &row.Calls, &row.PromptTokens, &row.CompletionTokens, &row.Cost)
+		if err := rows.Scan(dest...); err != nil {
+			log.Printf("Error in scanning usage report: %v", err)
+			return nil, ErrInternal
+		}
+		report = append(report, row)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in iterating usage report: %v", err)
+		return nil, ErrInternal
+	}
+	return report, nil
+}
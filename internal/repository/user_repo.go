@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+const userColumns = `id, email, name, password_hash, created_at, updated_at`
+
+// scanUser reads a row selected with userColumns
+func scanUser(row pgx.Row, u *models.User) error {
+	return row.Scan(&u.ID, &u.Email, &u.Name, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+type UserRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewUserRepo constructor
+func NewUserRepo(db *pgxpool.Pool) *UserRepo {
+	return &UserRepo{
+		db: db,
+	}
+}
+
+// CreateUser inserts an account. It returns ErrConflict when the email is taken.
+func (r *UserRepo) CreateUser(ctx context.Context, params UserCreateParams) (models.User, error) {
+	var u models.User
+	query := `INSERT INTO users (email, name, password_hash)
+			  VALUES ($1, $2, $3)
+			  RETURNING ` + userColumns
+	err := scanUser(r.db.QueryRow(ctx, query, params.Email, params.Name, params.PasswordHash), &u)
+	if isUniqueViolation(err) {
+		return models.User{}, ErrConflict
+	}
+	if err != nil {
+		log.Printf("Error in creating user: %v", err)
+		return models.User{}, ErrInternal
+	}
+	return u, nil
+}
+
+// GetUserByID fetches an account
+func (r *UserRepo) GetUserByID(ctx context.Context, id uuid.UUID) (models.User, error) {
+	var u models.User
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	err := scanUser(r.db.QueryRow(ctx, query, id), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching user: %v", err)
+		return models.User{}, ErrInternal
+	}
+	return u, nil
+}
+
+// GetUserByEmail fetches an account by email, ignoring case
+func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	var u models.User
+	query := `SELECT ` + userColumns + ` FROM users WHERE LOWER(email) = LOWER($1)`
+	err := scanUser(r.db.QueryRow(ctx, query, email), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching user by email: %v", err)
+		return models.User{}, ErrInternal
+	}
+	return u, nil
+}
+
+// UpdateUser changes the profile of an account. Nil fields are left unchanged.
+func (r *UserRepo) UpdateUser(ctx context.Context, params UserUpdateParams) (models.User, error) {
+	var u models.User
+	query := `UPDATE users
+			  SET name = COALESCE($2, name),
+			      password_hash = COALESCE($3, password_hash),
+			      updated_at = NOW()
+			  WHERE id = $1
+			  RETURNING ` + userColumns
+	err := scanUser(r.db.QueryRow(ctx, query, params.ID, params.Name, params.PasswordHash), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in updating user: %v", err)
+		return models.User{}, ErrInternal
+	}
+	return u, nil
+}
+
+// CreateSession stores the hash of a login token
+func (r *UserRepo) CreateSession(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO user_sessions (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := r.db.Exec(ctx, query, userID, tokenHash, expiresAt); err != nil {
+		log.Printf("Error in creating session: %v", err)
+		return ErrInternal
+	}
+	return nil
+}
+
+// GetUserBySession fetches the account of an unexpired login token
+func (r *UserRepo) GetUserBySession(ctx context.Context, tokenHash string) (models.User, error) {
+	var u models.User
+	query := `SELECT u.id, u.email, u.name, u.password_hash, u.created_at, u.updated_at
+			  FROM user_sessions s
+			  JOIN users u ON u.id = s.user_id
+			  WHERE s.token_hash = $1 AND s.expires_at > NOW()`
+	err := scanUser(r.db.QueryRow(ctx, query, tokenHash), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching session: %v", err)
+		return models.User{}, ErrInternal
+	}
+	return u, nil
+}
+
+// DeleteSession revokes a login token
+func (r *UserRepo) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM user_sessions WHERE token_hash = $1`, tokenHash); err != nil {
+		log.Printf("Error in deleting session: %v", err)
+		return ErrInternal
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes the login tokens of a user that have expired
+func (r *UserRepo) DeleteExpiredSessions(ctx context.Context, userID uuid.UUID) {
+	if _, err := r.db.Exec(ctx, `DELETE FROM user_sessions WHERE user_id = $1 AND expires_at <= NOW()`, userID); err != nil {
+		log.Printf("Error in deleting expired sessions: %v", err)
+	}
+}
+
+// GetUserByIdentity fetches the account linked to the subject of an external issuer
+func (r *UserRepo) GetUserByIdentity(ctx context.Context, issuer, subject string) (models.User, error) {
+	var u models.User
+	query := `SELECT u.id, u.email, u.name, u.password_hash, u.created_at, u.updated_at
+			  FROM user_identities i
+			  JOIN users u ON u.id = i.user_id
+			  WHERE i.issuer = $1 AND i.subject = $2`
+	err := scanUser(r.db.QueryRow(ctx, query, issuer, subject), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching user by identity: %v", err)
+		return models.User{}, ErrInternal
+	}
+	return u, nil
+}
+
+// CreateUserWithIdentity creates an account without a password and links it to the
+// subject of an external issuer. It returns ErrConflict when the email or the
+// identity is already taken.
+func (r *UserRepo) CreateUserWithIdentity(ctx context.Context, params UserIdentityCreateParams) (models.User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Printf("Error in starting transaction: %v", err)
+		return models.User{}, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	var u models.User
+	query := `INSERT INTO users (email, name, password_hash)
+			  VALUES ($1, $2, '')
+			  RETURNING ` + userColumns
+	err = scanUser(tx.QueryRow(ctx, query, params.Email, params.Name), &u)
+	if isUniqueViolation(err) {
+		return models.User{}, ErrConflict
+	}
+	if err != nil {
+		log.Printf("Error in creating user: %v", err)
+		return models.User{}, ErrInternal
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO user_identities (issuer, subject, user_id) VALUES ($1, $2, $3)`,
+		params.Issuer, params.Subject, u.ID)
+	if isUniqueViolation(err) {
+		return models.User{}, ErrConflict
+	}
+	if err != nil {
+		log.Printf("Error in linking identity: %v", err)
+		return models.User{}, ErrInternal
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error in committing user: %v", err)
+		return models.User{}, ErrInternal
+	}
+	return u, nil
+}
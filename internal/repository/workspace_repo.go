@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+const workspaceColumns = `id, org_id, name, allowed_models, default_settings, created_at, updated_at`
+
+// scanWorkspace reads a row selected with workspaceColumns
+func scanWorkspace(row pgx.Row, w *models.Workspace) error {
+	return row.Scan(&w.ID, &w.OrgID, &w.Name, &w.AllowedModels, &w.DefaultSettings, &w.CreatedAt, &w.UpdatedAt)
+}
+
+type WorkspaceRepo struct {
+	db *pgxpool.Pool
+}
+
+// NewWorkspaceRepo constructor
+func NewWorkspaceRepo(db *pgxpool.Pool) *WorkspaceRepo {
+	return &WorkspaceRepo{
+		db: db,
+	}
+}
+
+func (r *WorkspaceRepo) CreateWorkspace(ctx context.Context, params WorkspaceCreateParams) (models.Workspace, error) {
+	var w models.Workspace
+	allowed := params.AllowedModels
+	if allowed == nil {
+		allowed = []string{}
+	}
+	query := `INSERT INTO workspaces (org_id, name, allowed_models, default_settings)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING ` + workspaceColumns
+	err := scanWorkspace(r.db.QueryRow(ctx, query, params.OrgID, params.Name, allowed, params.DefaultSettings), &w)
+	if err != nil {
+		log.Printf("Error in creating workspace: %v", err)
+		return models.Workspace{}, ErrInternal
+	}
+	return w, nil
+}
+
+// ListWorkspaces returns the workspaces of an organization by name
+func (r *WorkspaceRepo) ListWorkspaces(ctx context.Context, orgID uuid.UUID) ([]models.Workspace, error) {
+	query := `SELECT ` + workspaceColumns + `
+			  FROM workspaces
+			  WHERE org_id = $1
+			  ORDER BY name`
+	rows, err := r.db.Query(ctx, query, orgID)
+	if err != nil {
+		log.Printf("Error in fetching workspaces: %v", err)
+		return nil, ErrInternal
+	}
+	defer rows.Close()
+
+	workspaces := []models.Workspace{}
+	for rows.Next() {
+		var w models.Workspace
+		if err := scanWorkspace(rows, &w); err != nil {
+			log.Printf("Error scanning workspace: %v", err)
+			return nil, ErrInternal
+		}
+		workspaces = append(workspaces, w)
+	}
+	return workspaces, nil
+}
+
+// GetWorkspace fetches a single workspace
+func (r *WorkspaceRepo) GetWorkspace(ctx context.Context, id uuid.UUID) (models.Workspace, error) {
+	var w models.Workspace
+	query := `SELECT ` + workspaceColumns + ` FROM workspaces WHERE id = $1`
+	err := scanWorkspace(r.db.QueryRow(ctx, query, id), &w)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Workspace{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching workspace: %v", err)
+		return models.Workspace{}, ErrInternal
+	}
+	return w, nil
+}
+
+// UpdateWorkspace renames a workspace and replaces its allowlist or default settings
+func (r *WorkspaceRepo) UpdateWorkspace(ctx context.Context, params WorkspaceUpdateParams) (models.Workspace, error) {
+	var w models.Workspace
+	query := `UPDATE workspaces
+			  SET name = COALESCE($2, name),
+			      allowed_models = COALESCE($3, allowed_models),
+			      default_settings = COALESCE($4, default_settings),
+			      updated_at = NOW()
+			  WHERE id = $1
+			  RETURNING ` + workspaceColumns
+	err := scanWorkspace(r.db.QueryRow(ctx, query, params.ID, params.Name, params.AllowedModels, params.DefaultSettings), &w)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Workspace{}, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in updating workspace: %v", err)
+		return models.Workspace{}, ErrInternal
+	}
+	return w, nil
+}
+
+// GetWorkspaceRole returns the role of a user in the organization of a workspace,
+// or ErrNotFound if the workspace does not exist or they are not a member
+func (r *WorkspaceRepo) GetWorkspaceRole(ctx context.Context, workspaceID, userID uuid.UUID) (string, error) {
+	var role string
+	query := `SELECT m.role
+			  FROM workspaces w
+			  JOIN memberships m ON m.org_id = w.org_id
+			  WHERE w.id = $1 AND m.user_id = $2`
+	err := r.db.QueryRow(ctx, query, workspaceID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Error in fetching workspace role: %v", err)
+		return "", ErrInternal
+	}
+	return role, nil
+}
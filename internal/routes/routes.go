@@ -4,17 +4,144 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/typescript-any/llm-playground/internal/handler"
 	"github.com/typescript-any/llm-playground/internal/middleware"
+	"github.com/typescript-any/llm-playground/internal/models"
 )
 
-func RegisterConversationRoutes(router fiber.Router, convHandler *handler.ConversationHandler, messageHandler *handler.MessageHandler) {
-	convGroup := router.Group("/conversations", middleware.AuthMiddleware)
+var (
+	conversationsRead  = middleware.RequireScope(models.ScopeConversationsRead)
+	conversationsWrite = middleware.RequireScope(models.ScopeConversationsWrite)
+	messagesRead       = middleware.RequireScope(models.ScopeMessagesRead)
+	messagesWrite      = middleware.RequireScope(models.ScopeMessagesWrite)
+	usageRead          = middleware.RequireScope(models.ScopeUsageRead)
+)
+
+func RegisterUserRoutes(router fiber.Router, auth fiber.Handler, userHandler *handler.UserHandler) {
+	authGroup := router.Group("/auth")
+
+	authGroup.Post("/register", userHandler.Register)
+	authGroup.Post("/login", userHandler.Login)
+	authGroup.Post("/logout", auth, userHandler.Logout)
+
+	userGroup := router.Group("/users", auth)
+
+	userGroup.Get("/me", userHandler.GetProfile)
+	userGroup.Patch("/me", middleware.RejectAPIKeys, userHandler.UpdateProfile)
+}
+
+// RegisterOIDCRoutes registers sign-in with the OpenID Connect provider. The browser is
+// sent to /login and comes back to /callback.
+func RegisterOIDCRoutes(router fiber.Router, oidcHandler *handler.OIDCHandler) {
+	oidcGroup := router.Group("/auth/oidc")
+
+	oidcGroup.Get("/login", oidcHandler.Login)
+	oidcGroup.Get("/callback", oidcHandler.Callback)
+}
+
+func RegisterAPIKeyRoutes(router fiber.Router, auth fiber.Handler, apiKeyHandler *handler.APIKeyHandler) {
+	keyGroup := router.Group("/keys", auth, middleware.RejectAPIKeys)
+
+	keyGroup.Post("/", apiKeyHandler.CreateAPIKey)
+	keyGroup.Get("/", apiKeyHandler.ListAPIKeys)
+	keyGroup.Delete("/:id", apiKeyHandler.RevokeAPIKey)
+}
+
+// RegisterOrganizationRoutes registers organizations, their members and workspaces.
+// Roles are checked by the service; API keys cannot manage organizations.
+func RegisterOrganizationRoutes(router fiber.Router, auth fiber.Handler, orgHandler *handler.OrganizationHandler) {
+	orgGroup := router.Group("/organizations", auth, middleware.RejectAPIKeys)
+
+	orgGroup.Post("/", orgHandler.CreateOrganization)
+	orgGroup.Get("/", orgHandler.ListOrganizations)
+	orgGroup.Get("/:id", orgHandler.GetOrganization)
+
+	orgGroup.Get("/:id/members", orgHandler.ListMembers)
+	orgGroup.Post("/:id/members", orgHandler.InviteMember)
+	orgGroup.Patch("/:id/members/:user_id", orgHandler.UpdateMember)
+	orgGroup.Delete("/:id/members/:user_id", orgHandler.RemoveMember)
+
+	orgGroup.Post("/:id/workspaces", orgHandler.CreateWorkspace)
+	orgGroup.Get("/:id/workspaces", orgHandler.ListWorkspaces)
+
+	workspaceGroup := router.Group("/workspaces", auth, middleware.RejectAPIKeys)
+
+	workspaceGroup.Get("/:id", orgHandler.GetWorkspace)
+	workspaceGroup.Patch("/:id", orgHandler.UpdateWorkspace)
+}
 
-	// Conversations
-	convGroup.Post("/", convHandler.CreateConversation)
-	convGroup.Get("/:user_id", convHandler.ListConversations)
-	convGroup.Post("/new", convHandler.CreateNewConversation)
+// RegisterProviderKeyRoutes registers the provider keys of the caller and of their
+// organizations. API keys cannot reach them.
+func RegisterProviderKeyRoutes(router fiber.Router, auth fiber.Handler, providerKeyHandler *handler.ProviderKeyHandler) {
+	keyGroup := router.Group("/provider-keys", auth, middleware.RejectAPIKeys)
+
+	keyGroup.Put("/me", providerKeyHandler.SetUserKey)
+	keyGroup.Get("/me", providerKeyHandler.GetUserKey)
+	keyGroup.Delete("/me", providerKeyHandler.DeleteUserKey)
+
+	keyGroup.Put("/organizations/:id", providerKeyHandler.SetOrgKey)
+	keyGroup.Get("/organizations/:id", providerKeyHandler.GetOrgKey)
+	keyGroup.Delete("/organizations/:id", providerKeyHandler.DeleteOrgKey)
+}
+
+// RegisterQuotaRoutes registers the model registry and quotas. Admins manage both; API
+// keys cannot reach the admin routes.
+func RegisterQuotaRoutes(router fiber.Router, auth fiber.Handler, quotaHandler *handler.QuotaHandler, modelHandler *handler.ModelHandler) {
+	router.Get("/models", auth, modelHandler.ListModels)
+	router.Get("/quotas/me", auth, quotaHandler.GetMyQuota)
+
+	adminGroup := router.Group("/admin", auth, middleware.RejectAPIKeys, middleware.RequireRole(models.AdminRole))
+
+	adminGroup.Put("/models/*", modelHandler.SaveModel)
+	adminGroup.Delete("/models/*", modelHandler.DeleteModel)
+
+	adminGroup.Get("/quotas", quotaHandler.ListQuotas)
+	for _, path := range []string{"/quotas/default", "/quotas/users/:user_id", "/quotas/organizations/:org_id"} {
+		adminGroup.Get(path, quotaHandler.GetQuota)
+		adminGroup.Put(path, quotaHandler.SetQuota)
+		adminGroup.Delete(path, quotaHandler.DeleteQuota)
+	}
+}
+
+// RegisterUsageRoutes registers the usage report. Admins see everyone's usage.
+func RegisterUsageRoutes(router fiber.Router, auth fiber.Handler, usageHandler *handler.UsageHandler) {
+	router.Get("/usage", auth, usageRead, usageHandler.GetUsage)
+}
+
+// RegisterConversationRoutes registers the conversation API. API keys only reach the
+// routes their scopes allow.
+func RegisterConversationRoutes(router fiber.Router, auth fiber.Handler, convHandler *handler.ConversationHandler, messageHandler *handler.MessageHandler, summaryHandler *handler.SummaryHandler) {
+	convGroup := router.Group("/conversations", auth)
+
+	// Conversations of the authenticated user and of their workspaces
+	convGroup.Post("/", conversationsWrite, convHandler.CreateConversation)
+	convGroup.Get("/", conversationsRead, convHandler.ListConversations)
+	convGroup.Post("/new", conversationsWrite, messagesWrite, convHandler.CreateNewConversation)
+	convGroup.Patch("/:id", conversationsWrite, convHandler.UpdateConversation)
+	convGroup.Post("/:id/title/regenerate", conversationsWrite, convHandler.RegenerateTitle)
+	convGroup.Post("/:id/replay", conversationsWrite, messagesWrite, convHandler.ReplayConversation)
+	convGroup.Get("/:id/events", conversationsRead, messagesRead, convHandler.StreamEvents)
 
 	// Messages inside conversation
-	convGroup.Post("/:id/messages", messageHandler.SendMessage)
-	convGroup.Post("/:id/messages/stream", messageHandler.StreamMessage)
+	convGroup.Get("/:id/messages", messagesRead, messageHandler.ListMessages)
+	convGroup.Post("/:id/messages", messagesWrite, messageHandler.SendMessage)
+	convGroup.Post("/:id/messages/stream", messagesWrite, messageHandler.StreamMessage)
+	convGroup.Post("/:id/messages/regenerate", messagesWrite, messageHandler.RegenerateMessage)
+	convGroup.Put("/:id/messages/:message_id/pin", messagesWrite, messageHandler.PinMessage)
+	convGroup.Delete("/:id/messages/:message_id/pin", messagesWrite, messageHandler.PinMessage)
+
+	// Rolling summary of older turns
+	convGroup.Get("/:id/summary", conversationsRead, summaryHandler.GetSummary)
+	convGroup.Put("/:id/summary", conversationsWrite, summaryHandler.UpdateSummary)
+}
+
+func RegisterGenerationRoutes(router fiber.Router, auth fiber.Handler, generationHandler *handler.GenerationHandler) {
+	genGroup := router.Group("/generations", auth)
+
+	genGroup.Post("/:id/cancel", messagesWrite, generationHandler.CancelGeneration)
+	genGroup.Get("/:id/events", messagesRead, generationHandler.StreamEvents)
+}
+
+func RegisterWebSocketRoutes(router fiber.Router, auth fiber.Handler, wsHandler *handler.WebSocketHandler) {
+	wsGroup := router.Group("/ws", auth)
+
+	wsGroup.Get("/conversations/:id", messagesRead, messagesWrite, wsHandler.Upgrade, wsHandler.Chat())
 }
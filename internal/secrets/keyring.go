@@ -0,0 +1,102 @@
+// Package secrets encrypts secrets at rest with AES-256-GCM under master keys taken
+// from the environment.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnknownKey means a secret was sealed under a master key that is no longer configured
+	ErrUnknownKey = errors.New("secret sealed under an unknown master key")
+	// ErrDecrypt means a secret was tampered with or belongs to another owner
+	ErrDecrypt = errors.New("secret cannot be decrypted")
+)
+
+// Sealed is an encrypted secret along with the master key that sealed it
+type Sealed struct {
+	KeyID      string
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// Keyring holds the master keys. New secrets are sealed under the active key; the others
+// only open secrets sealed before a rotation.
+type Keyring struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// ParseKeyring reads a comma separated list of id:key pairs, where every key is 32 bytes
+// in standard base64. The first pair is the active key.
+func ParseKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{keys: map[string]cipher.AEAD{}}
+	for _, entry := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, errors.New("master keys must be given as id:base64key")
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("master key %q is given twice", id)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != 32 {
+			return nil, fmt.Errorf("master key %q must be 32 bytes in base64", id)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if k.active == "" {
+			k.active = id
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// ActiveKeyID names the key new secrets are sealed under
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Seal encrypts plaintext under the active key. aad binds the secret to its owner: it
+// is not stored, and Open needs the same value.
+func (k *Keyring) Seal(plaintext, aad []byte) (Sealed, error) {
+	aead := k.keys[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return Sealed{}, err
+	}
+	return Sealed{
+		KeyID:      k.active,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, aad),
+	}, nil
+}
+
+// Open decrypts a sealed secret
+func (k *Keyring) Open(s Sealed, aad []byte) ([]byte, error) {
+	aead, ok := k.keys[s.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, s.KeyID)
+	}
+	if len(s.Nonce) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, s.Nonce, s.Ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,120 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+// newKey returns a random master key in the form ParseKeyring reads
+func newKey(t *testing.T, id string) string {
+	t.Helper()
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	k, err := ParseKeyring(newKey(t, "k1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, owner := []byte("sk-or-v1-0123456789abcdef"), []byte("user:1")
+
+	sealed, err := k.Seal(secret, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed.KeyID != "k1" {
+		t.Fatalf("sealed under %q, want k1", sealed.KeyID)
+	}
+	if bytes.Contains(sealed.Ciphertext, secret) {
+		t.Fatal("ciphertext contains the secret")
+	}
+	got, err := k.Open(sealed, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, secret) {
+		t.Fatalf("opened %q, want %q", got, secret)
+	}
+
+	again, _ := k.Seal(secret, owner)
+	if bytes.Equal(again.Nonce, sealed.Nonce) {
+		t.Fatal("nonce was reused")
+	}
+}
+
+func TestKeyringRejectsTampering(t *testing.T) {
+	k, _ := ParseKeyring(newKey(t, "k1"))
+	owner := []byte("user:1")
+	sealed, _ := k.Seal([]byte("sk-or-v1-0123456789abcdef"), owner)
+
+	flipped := sealed
+	flipped.Ciphertext = bytes.Clone(sealed.Ciphertext)
+	flipped.Ciphertext[0] ^= 1
+
+	cases := []struct {
+		name   string
+		sealed Sealed
+		owner  []byte
+		want   error
+	}{
+		{"other owner", sealed, []byte("user:2"), ErrDecrypt},
+		{"flipped bit", flipped, owner, ErrDecrypt},
+		{"short nonce", Sealed{KeyID: "k1", Nonce: sealed.Nonce[:4], Ciphertext: sealed.Ciphertext}, owner, ErrDecrypt},
+		{"unknown key", Sealed{KeyID: "k0", Nonce: sealed.Nonce, Ciphertext: sealed.Ciphertext}, owner, ErrUnknownKey},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := k.Open(tc.sealed, tc.owner); !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey, newKeySpec := newKey(t, "2025"), newKey(t, "2026")
+	before, _ := ParseKeyring(oldKey)
+	owner := []byte("org:1")
+	sealed, _ := before.Seal([]byte("sk-or-v1-0123456789abcdef"), owner)
+
+	// The new key goes first and the old one stays until every secret is sealed again
+	after, err := ParseKeyring(newKeySpec + "," + oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.ActiveKeyID() != "2026" {
+		t.Fatalf("active key %q, want 2026", after.ActiveKeyID())
+	}
+	plaintext, err := after.Open(sealed, owner)
+	if err != nil {
+		t.Fatalf("old secret no longer opens: %v", err)
+	}
+	resealed, _ := after.Seal(plaintext, owner)
+	if resealed.KeyID != "2026" {
+		t.Fatalf("resealed under %q, want 2026", resealed.KeyID)
+	}
+
+	rotated, _ := ParseKeyring(newKeySpec)
+	if _, err := rotated.Open(resealed, owner); err != nil {
+		t.Fatalf("resealed secret needs the old key: %v", err)
+	}
+	if _, err := rotated.Open(sealed, owner); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("got error %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestParseKeyringErrors(t *testing.T) {
+	short := "k1:" + base64.StdEncoding.EncodeToString(make([]byte, 16))
+	for _, spec := range []string{"", "k1", newKey(t, ""), "k1:not-base64!", short, newKey(t, "k1") + "," + newKey(t, "k1")} {
+		if _, err := ParseKeyring(spec); err == nil {
+			t.Errorf("ParseKeyring(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// Permission is what a caller needs to do with a conversation
+type Permission int
+
+const (
+	PermissionRead Permission = iota
+	PermissionWrite
+)
+
+// conversationLookup is the part of ConversationRepo that access checks need
+type conversationLookup interface {
+	GetConversationByID(ctx context.Context, id uuid.UUID) (models.Conversation, error)
+}
+
+// workspaceLookup is the part of WorkspaceRepo that access checks need
+type workspaceLookup interface {
+	GetWorkspace(ctx context.Context, id uuid.UUID) (models.Workspace, error)
+	GetWorkspaceRole(ctx context.Context, workspaceID, userID uuid.UUID) (string, error)
+}
+
+// Access decides which conversations a user may read and write.
+// Conversations a user may not reach are reported as repository.ErrNotFound,
+// so callers cannot tell them apart from conversations that do not exist.
+type Access struct {
+	conversations conversationLookup
+	workspaces    workspaceLookup
+}
+
+func NewAccess(repo *repository.ConversationRepo, workspaceRepo *repository.WorkspaceRepo) *Access {
+	return &Access{
+		conversations: repo,
+		workspaces:    workspaceRepo,
+	}
+}
+
+// Conversation loads a conversation on behalf of userID. Personal conversations are
+// reached by their owner alone, workspace conversations by the members of its organization.
+func (a *Access) Conversation(ctx context.Context, userID, convID uuid.UUID, perm Permission) (models.Conversation, error) {
+	conv, err := a.conversations.GetConversationByID(ctx, convID)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+	if conv.WorkspaceID != nil {
+		if err := a.Workspace(ctx, userID, *conv.WorkspaceID, perm); err != nil {
+			return models.Conversation{}, err
+		}
+		return conv, nil
+	}
+	if userID == uuid.Nil || conv.UserID != userID {
+		return models.Conversation{}, repository.ErrNotFound
+	}
+	return conv, nil
+}
+
+// Workspace checks that userID may use the conversations of a workspace. Every member
+// may read them; viewers get ErrForbidden when they try to write.
+func (a *Access) Workspace(ctx context.Context, userID, workspaceID uuid.UUID, perm Permission) error {
+	role, err := a.workspaces.GetWorkspaceRole(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if perm == PermissionWrite && role == models.MemberRoleViewer {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// workspace returns the workspace of a conversation, or nil for a personal one
+func (a *Access) workspace(ctx context.Context, conv models.Conversation) (*models.Workspace, error) {
+	if conv.WorkspaceID == nil {
+		return nil, nil
+	}
+	ws, err := a.workspaces.GetWorkspace(ctx, *conv.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
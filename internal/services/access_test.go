@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// fakeConversations serves conversations from memory
+type fakeConversations map[uuid.UUID]models.Conversation
+
+func (f fakeConversations) GetConversationByID(ctx context.Context, id uuid.UUID) (models.Conversation, error) {
+	conv, ok := f[id]
+	if !ok {
+		return models.Conversation{}, repository.ErrNotFound
+	}
+	return conv, nil
+}
+
+// fakeWorkspaces serves workspaces and the roles of their members from memory
+type fakeWorkspaces struct {
+	workspaces map[uuid.UUID]models.Workspace
+	roles      map[uuid.UUID]string // role of each user in every workspace
+}
+
+func (f fakeWorkspaces) GetWorkspace(ctx context.Context, id uuid.UUID) (models.Workspace, error) {
+	ws, ok := f.workspaces[id]
+	if !ok {
+		return models.Workspace{}, repository.ErrNotFound
+	}
+	return ws, nil
+}
+
+func (f fakeWorkspaces) GetWorkspaceRole(ctx context.Context, workspaceID, userID uuid.UUID) (string, error) {
+	role, ok := f.roles[userID]
+	if _, exists := f.workspaces[workspaceID]; !exists || !ok {
+		return "", repository.ErrNotFound
+	}
+	return role, nil
+}
+
+// newTestAccess returns an Access over one conversation owned by owner
+func newTestAccess(owner uuid.UUID) (*Access, uuid.UUID) {
+	conv := models.Conversation{ID: uuid.New(), UserID: owner, Title: "Private"}
+	return &Access{conversations: fakeConversations{conv.ID: conv}}, conv.ID
+}
+
+func TestAccessConversation(t *testing.T) {
+	owner, intruder := uuid.New(), uuid.New()
+	access, convID := newTestAccess(owner)
+	ctx := context.Background()
+
+	conv, err := access.Conversation(ctx, owner, convID, PermissionWrite)
+	if err != nil {
+		t.Fatalf("owner was denied: %v", err)
+	}
+	if conv.ID != convID {
+		t.Fatalf("got conversation %s, want %s", conv.ID, convID)
+	}
+
+	cases := []struct {
+		name   string
+		userID uuid.UUID
+		convID uuid.UUID
+	}{
+		{"other user", intruder, convID},
+		{"no user", uuid.Nil, convID},
+		{"missing conversation", owner, uuid.New()},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conv, err := access.Conversation(ctx, tc.userID, tc.convID, PermissionRead)
+			if err != repository.ErrNotFound {
+				t.Fatalf("got error %v, want repository.ErrNotFound", err)
+			}
+			if conv.ID != uuid.Nil {
+				t.Fatalf("leaked conversation %s", conv.ID)
+			}
+		})
+	}
+}
+
+func TestAccessWorkspaceConversation(t *testing.T) {
+	author, admin, viewer, outsider := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	wsID := uuid.New()
+	conv := models.Conversation{ID: uuid.New(), UserID: author, WorkspaceID: &wsID, Title: "Shared"}
+	access := &Access{
+		conversations: fakeConversations{conv.ID: conv},
+		workspaces: fakeWorkspaces{
+			workspaces: map[uuid.UUID]models.Workspace{wsID: {ID: wsID}},
+			// The author has since left the organization
+			roles: map[uuid.UUID]string{admin: models.MemberRoleAdmin, viewer: models.MemberRoleViewer},
+		},
+	}
+	ctx := context.Background()
+
+	cases := []struct {
+		name   string
+		userID uuid.UUID
+		perm   Permission
+		want   error
+	}{
+		{"admin reads", admin, PermissionRead, nil},
+		{"admin writes", admin, PermissionWrite, nil},
+		{"viewer reads", viewer, PermissionRead, nil},
+		{"viewer writes", viewer, PermissionWrite, ErrForbidden},
+		{"outsider reads", outsider, PermissionRead, repository.ErrNotFound},
+		{"former member reads", author, PermissionRead, repository.ErrNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := access.Conversation(ctx, tc.userID, conv.ID, tc.perm)
+			if err != tc.want {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+// TestCrossUserAccess calls every conversation and message operation as a user who does not
+// own the conversation. The services are built without repositories or a client, so an
+// operation that touched the conversation before checking access would panic.
+func TestCrossUserAccess(t *testing.T) {
+	owner, intruder := uuid.New(), uuid.New()
+	access, convID := newTestAccess(owner)
+	ctx := context.Background()
+
+	conversations := &ConversationService{access: access}
+	summaries := &SummaryService{access: access}
+	messages := &MessageService{access: access, summaries: summaries}
+	title := "Mine now"
+
+	operations := map[string]func() error{
+		"CheckAccess": func() error {
+			return conversations.CheckAccess(ctx, intruder, convID, PermissionRead)
+		},
+		"UpdateConversation": func() error {
+			_, err := conversations.UpdateConversation(ctx, ConversationUpdateParams{UserID: intruder, ID: convID, Title: &title})
+			return err
+		},
+		"RegenerateTitle": func() error {
+			_, err := conversations.RegenerateTitle(ctx, intruder, convID)
+			return err
+		},
+		"CreateReplay": func() error {
+			_, err := conversations.CreateReplay(ctx, ReplayCreateParams{UserID: intruder, SourceID: convID})
+			return err
+		},
+		"ListMessages": func() error {
+			_, err := messages.ListMessages(ctx, MessageListParams{UserID: intruder, ConversationID: convID, Limit: 10})
+			return err
+		},
+		"SendMessage": func() error {
+			_, err := messages.SendMessage(ctx, MessageSendParams{UserID: intruder, ConversationID: convID, Content: "hi"})
+			return err
+		},
+		"StreamMessage": func() error {
+			_, err := messages.StreamMessage(ctx, MessageStreamParams{UserID: intruder, ConversationID: convID, Content: "hi"})
+			return err
+		},
+		"RegenerateMessage": func() error {
+			_, err := messages.RegenerateMessage(ctx, MessageRegenerateParams{UserID: intruder, ConversationID: convID})
+			return err
+		},
+		"PinMessage": func() error {
+			_, err := messages.PinMessage(ctx, MessagePinParams{UserID: intruder, ConversationID: convID, MessageID: uuid.New(), Pinned: true})
+			return err
+		},
+		"GetSummary": func() error {
+			_, err := summaries.GetSummary(ctx, intruder, convID)
+			return err
+		},
+		"UpdateSummary": func() error {
+			_, err := summaries.UpdateSummary(ctx, SummaryUpdateParams{UserID: intruder, ConversationID: convID, Content: "rewritten"})
+			return err
+		},
+	}
+	for name, op := range operations {
+		t.Run(name, func(t *testing.T) {
+			if err := op(); !errors.Is(err, repository.ErrNotFound) {
+				t.Fatalf("got error %v, want repository.ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestCanManage(t *testing.T) {
+	cases := []struct {
+		actor, role string
+		want        bool
+	}{
+		{models.MemberRoleOwner, models.MemberRoleOwner, true},
+		{models.MemberRoleAdmin, models.MemberRoleAdmin, true},
+		{models.MemberRoleAdmin, models.MemberRoleViewer, true},
+		{models.MemberRoleAdmin, models.MemberRoleOwner, false},
+		{models.MemberRoleMember, models.MemberRoleViewer, false},
+		{models.MemberRoleViewer, models.MemberRoleViewer, false},
+		{"", models.MemberRoleViewer, false},
+	}
+	for _, tc := range cases {
+		if got := canManage(tc.actor, tc.role); got != tc.want {
+			t.Errorf("canManage(%q, %q) = %v, want %v", tc.actor, tc.role, got, tc.want)
+		}
+	}
+}
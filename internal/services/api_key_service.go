@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// apiKeyPrefix starts every API key, so keys are easy to spot in logs and secret scanners.
+// A key reads lp_<8 hex>_<secret>; "lp_<8 hex>" is its visible prefix.
+const (
+	apiKeyPrefix    = "lp_"
+	apiKeyPrefixLen = len(apiKeyPrefix) + 8
+	maxAPIKeys      = 50
+)
+
+// apiKeyStore is the part of APIKeyRepo that APIKeyService needs
+type apiKeyStore interface {
+	CreateAPIKey(ctx context.Context, params repository.APIKeyCreateParams) (models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id, userID uuid.UUID) error
+	TouchAPIKey(ctx context.Context, id uuid.UUID)
+}
+
+// userLookup is the part of UserRepo that authenticating an API key needs
+type userLookup interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (models.User, error)
+}
+
+type APIKeyService struct {
+	repo     apiKeyStore
+	userRepo userLookup
+}
+
+func NewAPIKeyService(repo *repository.APIKeyRepo, userRepo *repository.UserRepo) *APIKeyService {
+	return &APIKeyService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// LooksLikeAPIKey tells an API key apart from the other bearer tokens
+func LooksLikeAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// CreateAPIKey issues a key. The full key is only ever returned here.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, params APIKeyCreateParams) (CreatedAPIKey, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return CreatedAPIKey{}, fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	kind := params.Kind
+	if kind == "" {
+		kind = models.APIKeyKindPersonal
+	}
+	if kind != models.APIKeyKindPersonal && kind != models.APIKeyKindService {
+		return CreatedAPIKey{}, fmt.Errorf("%w: kind must be personal or service", ErrInvalidInput)
+	}
+	scopes, err := normalizeScopes(params.Scopes)
+	if err != nil {
+		return CreatedAPIKey{}, err
+	}
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		return CreatedAPIKey{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidInput)
+	}
+
+	existing, err := s.repo.ListAPIKeys(ctx, params.UserID)
+	if err != nil {
+		return CreatedAPIKey{}, err
+	}
+	if len(existing) >= maxAPIKeys {
+		return CreatedAPIKey{}, fmt.Errorf("%w: at most %d API keys per user", ErrInvalidInput, maxAPIKeys)
+	}
+
+	// Retry the rare prefix collision with a new key
+	for attempt := 0; ; attempt++ {
+		key, prefix, secret, err := generateAPIKey()
+		if err != nil {
+			return CreatedAPIKey{}, err
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return CreatedAPIKey{}, err
+		}
+
+		stored, err := s.repo.CreateAPIKey(ctx, repository.APIKeyCreateParams{
+			UserID:    params.UserID,
+			Name:      name,
+			Kind:      kind,
+			Prefix:    prefix,
+			Salt:      salt,
+			KeyHash:   hashAPIKey(salt, secret),
+			Scopes:    scopes,
+			ExpiresAt: params.ExpiresAt,
+		})
+		if errors.Is(err, repository.ErrConflict) && attempt < 3 {
+			continue
+		}
+		if err != nil {
+			return CreatedAPIKey{}, err
+		}
+		return CreatedAPIKey{APIKey: stored, Key: key}, nil
+	}
+}
+
+// ListAPIKeys returns the active keys of a user, without their secrets
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	return s.repo.ListAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey revokes a key of a user
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id, userID uuid.UUID) error {
+	return s.repo.RevokeAPIKey(ctx, id, userID)
+}
+
+// Authenticate resolves an API key into the identity of its owner, limited to the key's scopes
+func (s *APIKeyService) Authenticate(ctx context.Context, token string) (Identity, error) {
+	if len(token) <= apiKeyPrefixLen+1 || token[apiKeyPrefixLen] != '_' {
+		return Identity{}, ErrUnauthenticated
+	}
+	prefix, secret := token[:apiKeyPrefixLen], token[apiKeyPrefixLen+1:]
+
+	key, err := s.repo.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, repository.ErrNotFound) {
+		return Identity{}, ErrUnauthenticated
+	}
+	if err != nil {
+		return Identity{}, err
+	}
+	if subtle.ConstantTimeCompare(hashAPIKey(key.Salt, secret), key.KeyHash) != 1 {
+		return Identity{}, ErrUnauthenticated
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now())) {
+		return Identity{}, ErrUnauthenticated
+	}
+
+	// The owner may have been deleted since the key was issued
+	user, err := s.userRepo.GetUserByID(ctx, key.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return Identity{}, ErrUnauthenticated
+	}
+	if err != nil {
+		return Identity{}, err
+	}
+	s.repo.TouchAPIKey(ctx, key.ID)
+
+	return Identity{User: user, Method: AuthMethodAPIKey, Scopes: key.Scopes, APIKeyID: key.ID}, nil
+}
+
+// normalizeScopes checks that every scope exists and drops duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidInput)
+	}
+	seen := make(map[string]bool, len(scopes))
+	var out []string
+	for _, scope := range scopes {
+		valid := false
+		for _, known := range models.Scopes {
+			valid = valid || scope == known
+		}
+		if !valid {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidInput, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			out = append(out, scope)
+		}
+	}
+	return out, nil
+}
+
+// generateAPIKey returns a new key, its visible prefix and its secret part
+func generateAPIKey() (key, prefix, secret string, err error) {
+	raw := make([]byte, 4+32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", "", err
+	}
+	prefix = apiKeyPrefix + hex.EncodeToString(raw[:4])
+	secret = base64.RawURLEncoding.EncodeToString(raw[4:])
+	return prefix + "_" + secret, prefix, secret, nil
+}
+
+func hashAPIKey(salt []byte, secret string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(secret))
+	return h.Sum(nil)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// fakeAPIKeys keeps API keys in memory by prefix and counts the uses of each
+type fakeAPIKeys struct {
+	keys    map[string]models.APIKey
+	touched map[uuid.UUID]int
+}
+
+func (f *fakeAPIKeys) CreateAPIKey(ctx context.Context, params repository.APIKeyCreateParams) (models.APIKey, error) {
+	if _, ok := f.keys[params.Prefix]; ok {
+		return models.APIKey{}, repository.ErrConflict
+	}
+	key := models.APIKey{
+		ID:        uuid.New(),
+		UserID:    params.UserID,
+		Name:      params.Name,
+		Kind:      params.Kind,
+		Prefix:    params.Prefix,
+		Salt:      params.Salt,
+		KeyHash:   params.KeyHash,
+		Scopes:    params.Scopes,
+		ExpiresAt: params.ExpiresAt,
+	}
+	f.keys[key.Prefix] = key
+	return key, nil
+}
+
+func (f *fakeAPIKeys) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	var list []models.APIKey
+	for _, key := range f.keys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			list = append(list, key)
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeAPIKeys) GetAPIKeyByPrefix(ctx context.Context, prefix string) (models.APIKey, error) {
+	key, ok := f.keys[prefix]
+	if !ok {
+		return models.APIKey{}, repository.ErrNotFound
+	}
+	return key, nil
+}
+
+func (f *fakeAPIKeys) RevokeAPIKey(ctx context.Context, id, userID uuid.UUID) error {
+	for prefix, key := range f.keys {
+		if key.ID == id && key.UserID == userID {
+			now := time.Now()
+			key.RevokedAt = &now
+			f.keys[prefix] = key
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (f *fakeAPIKeys) TouchAPIKey(ctx context.Context, id uuid.UUID) {
+	f.touched[id]++
+}
+
+// fakeUsers serves users from memory
+type fakeUsers map[uuid.UUID]models.User
+
+func (f fakeUsers) GetUserByID(ctx context.Context, id uuid.UUID) (models.User, error) {
+	user, ok := f[id]
+	if !ok {
+		return models.User{}, repository.ErrNotFound
+	}
+	return user, nil
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, secret, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("generateAPIKey: %v", err)
+	}
+	if !LooksLikeAPIKey(key) || len(prefix) != apiKeyPrefixLen || key != prefix+"_"+secret {
+		t.Fatalf("got key %q with prefix %q and secret %q", key, prefix, secret)
+	}
+
+	other, _, _, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("generateAPIKey: %v", err)
+	}
+	if other == key {
+		t.Fatal("generated the same key twice")
+	}
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	cases := []struct {
+		name   string
+		scopes []string
+		want   []string
+		err    error
+	}{
+		{"none", nil, nil, ErrInvalidInput},
+		{"unknown", []string{models.ScopeMessagesRead, "admin"}, nil, ErrInvalidInput},
+		{"case matters", []string{"Messages:Read"}, nil, ErrInvalidInput},
+		{"duplicates", []string{models.ScopeMessagesRead, models.ScopeUsageRead, models.ScopeMessagesRead}, []string{models.ScopeMessagesRead, models.ScopeUsageRead}, nil},
+		{"every scope", models.Scopes, models.Scopes, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := normalizeScopes(tc.scopes)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, want %v", err, tc.err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("got scopes %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	owner := models.User{ID: uuid.New(), Email: "ada@example.com"}
+
+	cases := []struct {
+		name string
+		// token turns the issued key into the presented token, changing the stored key if needed
+		token func(key string, keys *fakeAPIKeys, users fakeUsers) string
+		err   error
+	}{
+		{"valid", func(key string, keys *fakeAPIKeys, users fakeUsers) string {
+			return key
+		}, nil},
+		{"prefix only", func(key string, keys *fakeAPIKeys, users fakeUsers) string {
+			return key[:apiKeyPrefixLen+1]
+		}, ErrUnauthenticated},
+		{"no separator", func(key string, keys *fakeAPIKeys, users fakeUsers) string {
+			return key[:apiKeyPrefixLen] + key[apiKeyPrefixLen+1:]
+		}, ErrUnauthenticated},
+		{"unknown prefix", func(key string, keys *fakeAPIKeys, users fakeUsers) string {
+			return apiKeyPrefix + "00000000" + key[apiKeyPrefixLen:]
+		}, ErrUnauthenticated},
+		{"wrong secret", func(key string, keys *fakeAPIKeys, users fakeUsers) string {
+			return key[:len(key)-1] + string(key[len(key)-1]^1)
+		}, ErrUnauthenticated},
+		{"another salt", func(key string, keys *fakeAPIKeys, users fakeUsers) string {
+			stored := keys.keys[key[:apiKeyPrefixLen]]
+			stored.Salt = make([]byte, len(stored.Salt))
+			keys.keys[stored.Prefix] = stored
+			return key
+		}, ErrUnauthenticated},
+		{"revoked", func(key string, keys *fakeAPIKeys, users fakeUsers) string {
+			stored := keys.keys[key[:apiKeyPrefixLen]]
+			keys.RevokeAPIKey(context.Background(), stored.ID, owner.ID)
+			return key
+		}, ErrUnauthenticated},
+		{"expired", func(key string, keys *fakeAPIKeys, users fakeUsers) string {
+			stored := keys.keys[key[:apiKeyPrefixLen]]
+			expired := time.Now().Add(-time.Second)
+			stored.ExpiresAt = &expired
+			keys.keys[stored.Prefix] = stored
+			return key
+		}, ErrUnauthenticated},
+		{"owner deleted", func(key string, keys *fakeAPIKeys, users fakeUsers) string {
+			delete(users, owner.ID)
+			return key
+		}, ErrUnauthenticated},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys := &fakeAPIKeys{keys: map[string]models.APIKey{}, touched: map[uuid.UUID]int{}}
+			users := fakeUsers{owner.ID: owner}
+			s := &APIKeyService{repo: keys, userRepo: users}
+			ctx := context.Background()
+
+			expires := time.Now().Add(time.Hour)
+			created, err := s.CreateAPIKey(ctx, APIKeyCreateParams{
+				UserID:    owner.ID,
+				Name:      "CI",
+				Scopes:    []string{models.ScopeMessagesRead},
+				ExpiresAt: &expires,
+			})
+			if err != nil {
+				t.Fatalf("CreateAPIKey: %v", err)
+			}
+
+			identity, err := s.Authenticate(ctx, tc.token(created.Key, keys, users))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, want %v", err, tc.err)
+			}
+			if tc.err != nil {
+				if keys.touched[created.ID] != 0 {
+					t.Fatal("rejected key was marked as used")
+				}
+				return
+			}
+			if identity.User.ID != owner.ID || identity.Method != AuthMethodAPIKey || identity.APIKeyID != created.ID ||
+				!slices.Equal(identity.Scopes, []string{models.ScopeMessagesRead}) {
+				t.Fatalf("got identity %+v, want the owner limited to the key's scopes", identity)
+			}
+			if keys.touched[created.ID] != 1 {
+				t.Fatalf("key marked as used %d times, want 1", keys.touched[created.ID])
+			}
+		})
+	}
+}
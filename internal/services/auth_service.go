@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/auth"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// Authentication methods reported in Identity.Method
+const (
+	AuthMethodSession = "session"
+	AuthMethodJWT     = "jwt"
+	AuthMethodAPIKey  = "api_key"
+)
+
+// AuthService resolves the bearer credentials every transport accepts into an Identity
+type AuthService struct {
+	repo    *repository.UserRepo
+	users   *UserService
+	apiKeys *APIKeyService
+	jwt     *auth.JWTVerifier // nil when JWT authentication is disabled
+	admins  map[uuid.UUID]bool
+}
+
+// NewAuthService returns an AuthService that grants models.AdminRole to adminIDs
+func NewAuthService(repo *repository.UserRepo, users *UserService, apiKeys *APIKeyService, jwt *auth.JWTVerifier, adminIDs []uuid.UUID) *AuthService {
+	admins := make(map[uuid.UUID]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+	return &AuthService{
+		repo:    repo,
+		users:   users,
+		apiKeys: apiKeys,
+		jwt:     jwt,
+		admins:  admins,
+	}
+}
+
+// Authenticate resolves a login token, an API key or a JWT into the caller's identity.
+// Configured admins are granted models.AdminRole, except through their API keys.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (Identity, error) {
+	if LooksLikeAPIKey(token) {
+		return s.apiKeys.Authenticate(ctx, token)
+	}
+
+	var identity Identity
+	if s.jwt != nil && auth.LooksLikeJWT(token) {
+		var err error
+		if identity, err = s.authenticateJWT(ctx, token); err != nil {
+			return Identity{}, err
+		}
+	} else {
+		user, err := s.users.Authenticate(ctx, token)
+		if err != nil {
+			return Identity{}, err
+		}
+		identity = Identity{User: user, Method: AuthMethodSession}
+	}
+
+	if s.admins[identity.User.ID] && !identity.HasRole(models.AdminRole) {
+		identity.Roles = append(identity.Roles, models.AdminRole)
+	}
+	return identity, nil
+}
+
+// authenticateJWT verifies a JWT and maps its subject onto an account, creating the
+// account the first time the subject is seen
+func (s *AuthService) authenticateJWT(ctx context.Context, token string) (Identity, error) {
+	claims, err := s.jwt.Verify(ctx, token)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	user, err := s.externalUser(ctx, claims)
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{User: user, Roles: claims.Roles, Method: AuthMethodJWT}, nil
+}
+
+// SignInWithOIDC issues a login token for the subject of a completed OIDC sign-in,
+// creating its account the first time the subject signs in
+func (s *AuthService) SignInWithOIDC(ctx context.Context, claims auth.Claims) (AuthSession, error) {
+	user, err := s.externalUser(ctx, claims)
+	if err != nil {
+		return AuthSession{}, err
+	}
+	s.repo.DeleteExpiredSessions(ctx, user.ID)
+	return s.users.newSession(ctx, user)
+}
+
+// externalUser returns the account linked to an external subject, provisioning it if needed
+func (s *AuthService) externalUser(ctx context.Context, claims auth.Claims) (models.User, error) {
+	user, err := s.repo.GetUserByIdentity(ctx, claims.Issuer, claims.Subject)
+	if errors.Is(err, repository.ErrNotFound) {
+		return s.provision(ctx, claims)
+	}
+	return user, err
+}
+
+// provision creates the account of a new external subject. Its email claim is only used
+// when no other account has it, so a token or a sign-in can never take over an existing account.
+func (s *AuthService) provision(ctx context.Context, claims auth.Claims) (models.User, error) {
+	params := repository.UserIdentityCreateParams{
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+		Name:    claims.Name,
+	}
+	if params.Email == "" {
+		params.Email = placeholderEmail(claims)
+	}
+
+	user, err := s.repo.CreateUserWithIdentity(ctx, params)
+	if !errors.Is(err, repository.ErrConflict) {
+		return user, err
+	}
+
+	// Either a concurrent request linked the subject first, or the email is taken
+	if user, err := s.repo.GetUserByIdentity(ctx, claims.Issuer, claims.Subject); err == nil {
+		return user, nil
+	}
+	params.Email = placeholderEmail(claims)
+	return s.repo.CreateUserWithIdentity(ctx, params)
+}
+
+// placeholderEmail is a stable, undeliverable address for a subject without a usable email
+func placeholderEmail(claims auth.Claims) string {
+	id := uuid.NewSHA1(uuid.NameSpaceURL, []byte(claims.Issuer+"\x00"+claims.Subject))
+	return id.String() + "@external.invalid"
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/openai/openai-go/option"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+// completionCall is what a completion request of a user needs besides its messages
+type completionCall struct {
+	userID   uuid.UUID
+	convID   uuid.UUID
+	kind     string // usage kind the call is recorded as
+	settings models.ConversationSettings
+	reqOpts  []option.RequestOption // select the provider key paying for the call
+	ws       *models.Workspace      // of a shared conversation
+}
+
+// billing charges the completion calls made for a user to their provider key, their
+// quotas and their usage, whether the call answers a message or titles a conversation
+type billing struct {
+	keys   *ProviderKeyService // nil when users cannot bring their own provider keys
+	quotas *QuotaService
+	usage  *UsageService
+}
+
+// requestOptions picks the provider key paying for a call of userID in ws
+func (b billing) requestOptions(ctx context.Context, userID uuid.UUID, ws *models.Workspace) ([]option.RequestOption, error) {
+	if b.keys == nil {
+		return nil, nil
+	}
+	return b.keys.requestOptions(ctx, userID, ws)
+}
+
+// reserve charges the estimated usage of a call to the quotas of its user and workspace.
+// Past a budget that downgrades, the call is switched to the cheaper model.
+func (b billing) reserve(ctx context.Context, call *completionCall, promptTokens int64) (*Reservation, error) {
+	params := QuotaReserveParams{
+		UserID:           call.userID,
+		Model:            call.settings.Model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: maxOutputTokens(call.settings),
+	}
+	if call.ws != nil {
+		params.OrgID = &call.ws.OrgID
+	}
+	r, err := b.quotas.Reserve(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if r.Model.ID != call.settings.Model {
+		if err := allowModel(call.ws, r.Model.ID); err != nil {
+			b.settle(ctx, call, r, 0, 0)
+			return nil, fmt.Errorf("%w: the downgrade model %q is not allowed in this workspace", ErrQuotaExceeded, r.Model.ID)
+		}
+		call.settings.Model = r.Model.ID
+	}
+	return r, nil
+}
+
+// settle charges the quotas the actual usage of a call in place of its reservation, and
+// records the call
+func (b billing) settle(ctx context.Context, call *completionCall, r *Reservation, promptTokens, completionTokens int64) {
+	b.quotas.Settle(ctx, r, promptTokens, completionTokens)
+
+	params := UsageRecordParams{
+		UserID:           &call.userID,
+		ConversationID:   &call.convID,
+		Model:            call.settings.Model,
+		Kind:             call.kind,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}
+	if call.ws != nil {
+		params.OrgID = &call.ws.OrgID
+	}
+	b.usage.Record(ctx, params)
+}
@@ -2,72 +2,287 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
 	"github.com/openai/openai-go"
-	"github.com/typescript-any/llm-playground/internal/llm"
 	"github.com/typescript-any/llm-playground/internal/models"
 	"github.com/typescript-any/llm-playground/internal/repository"
+	"github.com/typescript-any/llm-playground/internal/tokenizer"
 )
 
+// maxReplayMessages caps how much of a source conversation is replayed
+const maxReplayMessages = 1000
+
 type ConversationService struct {
-	repo *repository.ConversationRepo
+	repo        *repository.ConversationRepo
+	messageRepo *repository.MessageRepo
+	access      *Access
+	models      *ModelService
+	client      *openai.Client
+	titleModel  string
+	billing
 }
 
-func NewConversationService(repo *repository.ConversationRepo) *ConversationService {
-	return &ConversationService{repo: repo}
+func NewConversationService(repo *repository.ConversationRepo, messageRepo *repository.MessageRepo, access *Access, keys *ProviderKeyService, modelService *ModelService, quotas *QuotaService, usage *UsageService, client *openai.Client, titleModel string) *ConversationService {
+	return &ConversationService{
+		repo:        repo,
+		messageRepo: messageRepo,
+		access:      access,
+		models:      modelService,
+		client:      client,
+		titleModel:  titleModel,
+		billing:     billing{keys: keys, quotas: quotas, usage: usage},
+	}
 }
 
+// CreateConversation creates a personal conversation, or a shared one when WorkspaceID is set
 func (s *ConversationService) CreateConversation(ctx context.Context, params ConversationCreateParams) (models.Conversation, error) {
+	if params.WorkspaceID != nil {
+		if err := s.access.Workspace(ctx, params.UserID, *params.WorkspaceID, PermissionWrite); err != nil {
+			return models.Conversation{}, err
+		}
+	}
 	return s.repo.CreateConversation(ctx, repository.ConversationCreateParams{
-		UserID: params.UserID,
-		Title:  params.Title,
+		UserID:      params.UserID,
+		WorkspaceID: params.WorkspaceID,
+		Title:       params.Title,
 	})
 }
 
+// ListConversations lists the personal conversations of the user, or those of a
+// workspace the user is a member of when WorkspaceID is set
 func (s *ConversationService) ListConversations(ctx context.Context, params ConversationListParams) ([]models.Conversation, error) {
+	if params.WorkspaceID != nil {
+		if err := s.access.Workspace(ctx, params.UserID, *params.WorkspaceID, PermissionRead); err != nil {
+			return nil, err
+		}
+	}
 	return s.repo.GetConversationsByUser(ctx, repository.ConversationListParams{
-		UserID: params.UserID,
-		Offset: params.Offset,
-		Limit:  params.Limit,
+		UserID:      params.UserID,
+		WorkspaceID: params.WorkspaceID,
+		Query:       strings.TrimSpace(params.Query),
+		Offset:      params.Offset,
+		Limit:       params.Limit,
 	})
 }
 
+// CreateNewConversation creates a conversation with a placeholder title.
+// The real title is filled in later by GenerateTitle.
 func (s *ConversationService) CreateNewConversation(ctx context.Context, params ConversationNewParams) (models.Conversation, error) {
-	client := llm.NewClient()
+	return s.CreateConversation(ctx, ConversationCreateParams{
+		UserID:      params.UserID,
+		WorkspaceID: params.WorkspaceID,
+		Title:       PlaceholderTitle,
+	})
+}
+
+// DiscardNewConversation deletes a conversation made by CreateNewConversation whose first
+// reply could not start, so that a refused request leaves nothing behind
+func (s *ConversationService) DiscardNewConversation(ctx context.Context, userID, convID uuid.UUID) error {
+	if _, err := s.access.Conversation(ctx, userID, convID, PermissionWrite); err != nil {
+		return err
+	}
+	return s.repo.DeleteConversation(ctx, convID)
+}
 
-	prompt := `
-		You are a system that generates very short conversation titles.
+// CheckAccess reports repository.ErrNotFound unless userID may use the conversation with perm.
+// Transports call it before starting work that is not run by the services themselves.
+func (s *ConversationService) CheckAccess(ctx context.Context, userID, convID uuid.UUID, perm Permission) error {
+	_, err := s.access.Conversation(ctx, userID, convID, perm)
+	return err
+}
 
-		Example:
-		User: How can I format dates in React?
-		Title: Formatting Dates in React
+// GenerateTitle asks the title model for a short title based on the first user message and stores it.
+// The call is paid for by userID like their messages: with their provider key and out of their quotas.
+func (s *ConversationService) GenerateTitle(ctx context.Context, userID, convID uuid.UUID, content string) (models.Conversation, error) {
+	conv, err := s.access.Conversation(ctx, userID, convID, PermissionWrite)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+	ws, err := s.access.workspace(ctx, conv)
+	if err != nil {
+		return models.Conversation{}, err
+	}
 
-		User: ` + params.Content + `
-		Title:
-		`
+	maxTokens := int64(titleMaxTokens)
+	call := &completionCall{
+		userID:   userID,
+		convID:   convID,
+		kind:     models.UsageKindTitle,
+		settings: models.ConversationSettings{Model: defaultModel(s.titleModel), MaxTokens: &maxTokens},
+		ws:       ws,
+	}
+	if call.reqOpts, err = s.requestOptions(ctx, userID, ws); err != nil {
+		return models.Conversation{}, err
+	}
+	tok := tokenizer.For(call.settings.Model)
+	promptTokens := tok.Message(titlePrompt) + tok.Message(content) + tokenizer.ReplyPriming
+	reservation, err := s.reserve(ctx, call, int64(promptTokens))
+	if err != nil {
+		return models.Conversation{}, err
+	}
 
-	resp, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	resp, err := s.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
 		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(prompt),
+			openai.SystemMessage(titlePrompt),
+			openai.UserMessage(content),
 		},
-		Model:     defaultModel(params.Model),
-		MaxTokens: openai.Int(500),
+		Model:       call.settings.Model,
+		MaxTokens:   openai.Int(maxTokens),
+		Temperature: openai.Float(0.2),
+	}, call.reqOpts...)
+	if err != nil {
+		s.settle(context.Background(), call, reservation, 0, 0)
+		return models.Conversation{}, fmt.Errorf("failed to generate title: %w", err)
+	}
+	s.settle(context.Background(), call, reservation, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	if len(resp.Choices) == 0 {
+		return models.Conversation{}, fmt.Errorf("failed to generate title: empty response")
+	}
+
+	title := cleanTitle(resp.Choices[0].Message.Content)
+	if title == "" {
+		return models.Conversation{}, fmt.Errorf("failed to generate title: unusable output %q", resp.Choices[0].Message.Content)
+	}
+	log.Info("Generated title:", title)
+
+	return s.repo.UpdateConversation(ctx, repository.ConversationUpdateParams{
+		ID:    convID,
+		Title: &title,
 	})
+}
 
-	if err != nil || len(resp.Choices) == 0 {
-		log.Info("Error generating title:", err)
-		return s.repo.CreateConversation(ctx, repository.ConversationCreateParams{
-			UserID: params.UserID,
-			Title:  "New Conversation",
-		})
+// RegenerateTitle generates a fresh title from the first user message of the conversation
+func (s *ConversationService) RegenerateTitle(ctx context.Context, userID, convID uuid.UUID) (models.Conversation, error) {
+	if _, err := s.access.Conversation(ctx, userID, convID, PermissionWrite); err != nil {
+		return models.Conversation{}, err
 	}
 
-	title := resp.Choices[0].Message.Content
-	log.Info("Generated title:", title)
-	return s.repo.CreateConversation(ctx, repository.ConversationCreateParams{
-		UserID: params.UserID,
-		Title:  title,
+	first, err := s.messageRepo.GetFirstMessageByRole(ctx, convID, models.RoleUser)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+	return s.GenerateTitle(ctx, userID, convID, first.Content)
+}
+
+// UpdateConversation renames a conversation and/or patches its settings
+func (s *ConversationService) UpdateConversation(ctx context.Context, params ConversationUpdateParams) (models.Conversation, error) {
+	if params.Title != nil {
+		title := strings.TrimSpace(*params.Title)
+		if title == "" {
+			return models.Conversation{}, fmt.Errorf("%w: title cannot be empty", ErrInvalidInput)
+		}
+		params.Title = &title
+	}
+	var patch models.ConversationSettings
+	if len(params.SettingsPatch) > 0 {
+		var err error
+		if patch, err = decodeSettingsPatch(params.SettingsPatch); err != nil {
+			return models.Conversation{}, err
+		}
+	}
+	conv, err := s.access.Conversation(ctx, params.UserID, params.ID, PermissionWrite)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+	if len(params.SettingsPatch) > 0 {
+		if err := s.checkMergedSettings(ctx, conv, patch, params.SettingsPatch); err != nil {
+			return models.Conversation{}, err
+		}
+	}
+
+	return s.repo.UpdateConversation(ctx, repository.ConversationUpdateParams{
+		ID:            params.ID,
+		Title:         params.Title,
+		SettingsPatch: params.SettingsPatch,
+	})
+}
+
+// checkMergedSettings checks the settings a conversation will have once patched, since a
+// patch that is valid on its own may not be with the settings it keeps, such as a
+// max_tokens that does not fit the context window of a new model
+func (s *ConversationService) checkMergedSettings(ctx context.Context, conv models.Conversation, patch models.ConversationSettings, raw json.RawMessage) error {
+	ws, err := s.access.workspace(ctx, conv)
+	if err != nil {
+		return err
+	}
+	if patch.Model != "" {
+		if err := allowModel(ws, patch.Model); err != nil {
+			return err
+		}
+	}
+
+	merged, err := mergeSettings(conv.Settings, raw)
+	if err != nil {
+		return err
+	}
+	if err := validateSettings(merged); err != nil {
+		return err
+	}
+	model, err := s.models.lookup(ctx, defaultModel(workspaceSettings(ws, merged).Model))
+	if err != nil {
+		return err
+	}
+	return fitMaxTokens(model, merged)
+}
+
+// CreateReplay creates an empty conversation that copies the source settings with the given
+// overrides applied, and returns it together with the source user turns to re-send in order.
+// A replay of a shared conversation is shared in the same workspace.
+func (s *ConversationService) CreateReplay(ctx context.Context, params ReplayCreateParams) (*ReplayPlan, error) {
+	source, err := s.access.Conversation(ctx, params.UserID, params.SourceID, PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := s.access.workspace(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := resolveSettings(source.Settings, params.GenerationOptions)
+	if err := validateSettings(settings); err != nil {
+		return nil, err
+	}
+	if err := allowModel(ws, settings.Model); err != nil {
+		return nil, err
+	}
+
+	history, err := s.messageRepo.GetMessagesByConversation(ctx, repository.MessageListParams{
+		ConversationID: source.ID,
+		Limit:          maxReplayMessages,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	var turns []models.Message
+	for _, m := range history {
+		if m.Role == models.RoleUser {
+			turns = append(turns, m)
+		}
+	}
+	if len(turns) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	conv, err := s.repo.CreateConversation(ctx, repository.ConversationCreateParams{
+		UserID:               params.UserID,
+		WorkspaceID:          source.WorkspaceID,
+		Title:                "Replay: " + source.Title,
+		Settings:             settings,
+		SourceConversationID: &source.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	return &ReplayPlan{
+		Source:       source,
+		Conversation: conv,
+		Turns:        turns,
+	}, nil
 }
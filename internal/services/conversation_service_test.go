@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+func TestMergeSettings(t *testing.T) {
+	stored := models.ConversationSettings{Model: "big", Temperature: ptr(0.5), MaxTokens: ptr(int64(800))}
+
+	cases := []struct {
+		name  string
+		patch string
+		want  models.ConversationSettings
+	}{
+		{"replaces", `{"model": "small"}`, models.ConversationSettings{Model: "small", Temperature: ptr(0.5), MaxTokens: ptr(int64(800))}},
+		{"null removes", `{"temperature": null, "max_tokens": null}`, models.ConversationSettings{Model: "big"}},
+		{"adds", `{"system_prompt": "Be brief"}`, models.ConversationSettings{SystemPrompt: "Be brief", Model: "big", Temperature: ptr(0.5), MaxTokens: ptr(int64(800))}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := mergeSettings(stored, json.RawMessage(tc.patch))
+			if err != nil {
+				t.Fatalf("mergeSettings: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// The services are built without a repository, so a patch that got past the checks
+// would panic
+func TestUpdateConversationChecksMergedSettings(t *testing.T) {
+	owner := uuid.New()
+	registry := fakeModels{
+		"big":   {ID: "big", ContextWindow: 128000},
+		"small": {ID: "small", ContextWindow: 4096},
+	}
+
+	cases := []struct {
+		name   string
+		stored models.ConversationSettings
+		patch  string
+	}{
+		{"model too small for the stored max_tokens", models.ConversationSettings{Model: "big", MaxTokens: ptr(int64(8000))}, `{"model": "small"}`},
+		{"max_tokens too large for the stored model", models.ConversationSettings{Model: "small"}, `{"max_tokens": 5000}`},
+		{"stored settings out of range", models.ConversationSettings{Model: "big", Temperature: ptr(3.0)}, `{"top_p": 0.9}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conv := models.Conversation{ID: uuid.New(), UserID: owner, Settings: tc.stored}
+			s := &ConversationService{
+				access: &Access{conversations: fakeConversations{conv.ID: conv}},
+				models: &ModelService{repo: registry},
+			}
+
+			_, err := s.UpdateConversation(context.Background(), ConversationUpdateParams{
+				UserID:        owner,
+				ID:            conv.ID,
+				SettingsPatch: json.RawMessage(tc.patch),
+			})
+			if !errors.Is(err, ErrInvalidSettings) {
+				t.Fatalf("got error %v, want ErrInvalidSettings", err)
+			}
+		})
+	}
+}
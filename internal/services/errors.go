@@ -0,0 +1,21 @@
+package service
+
+import "errors"
+
+var (
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrInvalidSettings = errors.New("invalid conversation settings")
+
+	ErrNothingToRegenerate = errors.New("conversation has no user turn to answer")
+
+	ErrForbidden = errors.New("your role does not allow this")
+
+	ErrProviderKeyUnavailable = errors.New("provider key cannot be decrypted; set it again")
+
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	ErrContextTooLong = errors.New("prompt does not fit the context window of the model")
+
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrUnauthenticated    = errors.New("unauthenticated")
+)
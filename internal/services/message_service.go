@@ -2,84 +2,218 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
+	"github.com/google/uuid"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/packages/ssestream"
 	"github.com/typescript-any/llm-playground/internal/models"
 	"github.com/typescript-any/llm-playground/internal/repository"
+	"github.com/typescript-any/llm-playground/internal/tokenizer"
 )
 
+// messageStore is the part of MessageRepo that MessageService needs
+type messageStore interface {
+	SaveMessage(ctx context.Context, params repository.MessageSaveParams) (*models.Message, error)
+	GetMessagesByConversation(ctx context.Context, params repository.MessageListParams) ([]models.Message, error)
+	GetRecentMessages(ctx context.Context, params repository.MessageListParams) ([]models.Message, error)
+	GetPinnedMessages(ctx context.Context, convID uuid.UUID) ([]models.Message, error)
+	SetMessagePinned(ctx context.Context, convID, id uuid.UUID, pinned bool) (*models.Message, error)
+	DeleteMessage(ctx context.Context, id uuid.UUID) error
+}
+
 type MessageService struct {
-	repo   *repository.MessageRepo
-	client *openai.Client
+	repo      messageStore
+	access    *Access
+	summaries *SummaryService
+	models    *ModelService
+	client    *openai.Client
+	billing
+}
+
+// MessageStream is an in-flight completion along with the model it was sent to.
+// AssistantMessageID is reserved up front so it can be announced before the reply is saved.
+// Abort cancels the upstream request alone, with the given cause.
+type MessageStream struct {
+	Stream             *ssestream.Stream[openai.ChatCompletionChunk]
+	Acc                *openai.ChatCompletionAccumulator
+	Abort              context.CancelCauseFunc
+	Model              string
+	ConversationID     uuid.UUID
+	UserMessageID      uuid.UUID
+	AssistantMessageID uuid.UUID
+
+	call        *completionCall
+	reservation *Reservation // usage charged to quotas until RecordUsage
 }
 
 // Constructor function of MessageService
-func NewMessageService(r *repository.MessageRepo, c *openai.Client) *MessageService {
+func NewMessageService(r *repository.MessageRepo, a *Access, ss *SummaryService, keys *ProviderKeyService, m *ModelService, q *QuotaService, u *UsageService, c *openai.Client) *MessageService {
 	return &MessageService{
-		repo:   r,
-		client: c,
+		repo:      r,
+		access:    a,
+		summaries: ss,
+		models:    m,
+		client:    c,
+		billing:   billing{keys: keys, quotas: q, usage: u},
 	}
 }
 
-func defaultModel(model string) string {
-	if model == "" {
-		return "gpt-4o"
+// prepare resolves the effective settings of a conversation for one request by userID:
+// the request overrides, then the conversation settings, then the workspace defaults.
+// It also picks the provider key paying for the call.
+func (s *MessageService) prepare(ctx context.Context, userID, convID uuid.UUID, opts GenerationOptions) (*completionCall, error) {
+	conv, err := s.access.Conversation(ctx, userID, convID, PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := s.access.workspace(ctx, conv)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := resolveSettings(workspaceSettings(ws, conv.Settings), opts)
+	if err := validateSettings(settings); err != nil {
+		return nil, err
+	}
+	if err := allowModel(ws, settings.Model); err != nil {
+		return nil, err
 	}
-	return model
+
+	call := &completionCall{userID: userID, convID: convID, kind: models.UsageKindChat, settings: settings, ws: ws}
+	if call.reqOpts, err = s.requestOptions(ctx, userID, ws); err != nil {
+		return nil, err
+	}
+	return call, nil
 }
 
-func (s *MessageService) SendMessage(ctx context.Context, params MessageSendParams) (*models.ChatMessage, error) {
+// history loads the stored summary, the pinned messages and the most recent turns the
+// summary does not cover
+func (s *MessageService) history(ctx context.Context, convID uuid.UUID) (conversationContext, error) {
+	summary, err := s.summaries.summaryFor(ctx, convID)
+	if err != nil {
+		return conversationContext{}, err
+	}
+	pinned, err := s.repo.GetPinnedMessages(ctx, convID)
+	if err != nil {
+		return conversationContext{}, err
+	}
+
+	params := repository.MessageListParams{
+		ConversationID: convID,
+		Limit:          maxContextMessages,
+	}
+	if summary != nil {
+		params.After = &summary.SummarizedUntil
+	}
+	recent, err := s.repo.GetRecentMessages(ctx, params)
+	if err != nil {
+		return conversationContext{}, err
+	}
+	return conversationContext{summary: summary, pinned: pinned, recent: recent}, nil
+}
+
+// buildPrompt fits a conversation into the context window of the model of a call and
+// charges the prompt and the longest reply to the quotas. A call downgraded past a
+// budget is fitted again for the cheaper model.
+func (s *MessageService) buildPrompt(ctx context.Context, call *completionCall, cc conversationContext) (*prompt, *Reservation, error) {
+	requested := call.settings.Model
+	model, err := s.models.lookup(ctx, requested)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := buildPrompt(model, call.settings, cc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reservation, err := s.reserve(ctx, call, p.tokens)
+	if err != nil {
+		return nil, nil, err
+	}
+	if call.settings.Model != requested {
+		if p, err = buildPrompt(reservation.Model, call.settings, cc); err != nil {
+			s.settle(context.Background(), call, reservation, 0, 0)
+			return nil, nil, err
+		}
+	}
+	return p, reservation, nil
+}
+
+// startTurn saves the user message of a new turn and builds the prompt replying to it.
+// When the prompt cannot be built the message is deleted again, so a failed turn leaves
+// no unanswered message behind.
+func (s *MessageService) startTurn(ctx context.Context, call *completionCall, content string) (userMessage *models.Message, p *prompt, r *Reservation, err error) {
 	// 1. Save user message
-	_, err := s.repo.SaveMessage(ctx, repository.MessageSaveParams{
-		ConversationID: params.ConversationID,
+	userMessage, err = s.repo.SaveMessage(ctx, repository.MessageSaveParams{
+		ConversationID: call.convID,
 		Role:           models.RoleUser,
-		Content:        params.Content,
+		Content:        content,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, fmt.Errorf("failed to save user message: %w", err)
 	}
+	savedID := userMessage.ID
+	defer func() {
+		if err != nil {
+			s.repo.DeleteMessage(context.Background(), savedID)
+		}
+	}()
 
-	// 2. Get conversation history
-	history, err := s.repo.GetMessagesByConversation(ctx, repository.MessageListParams{
-		ConversationID: params.ConversationID,
-		Limit:          100, // Get all recent messages for context
-	})
+	// 2. Fetch the summary, pinned messages and recent history, which ends with the
+	// user message just saved
+	history, err := s.history(ctx, call.convID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get user history: %w", err)
+	}
+
+	// 3. Fit them into the context window and charge the quotas, dropping the turn when
+	// it does not fit or the quotas are used up
+	p, r, err = s.buildPrompt(ctx, call, history)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return userMessage, p, r, nil
+}
+
+func (s *MessageService) SendMessage(ctx context.Context, params MessageSendParams) (*models.ChatMessage, error) {
+	call, err := s.prepare(ctx, params.UserID, params.ConversationID, params.GenerationOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert history to openai message
-	var messages []openai.ChatCompletionMessageParamUnion
-	for _, m := range history {
-		if m.Role == models.RoleUser {
-			messages = append(messages, openai.UserMessage(m.Content))
-		} else {
-			messages = append(messages, openai.AssistantMessage(m.Content))
-		}
+	// 1-3. Save the user message and build the prompt
+	_, prompt, reservation, err := s.startTurn(ctx, call, params.Content)
+	if err != nil {
+		return nil, err
 	}
 
-	// 3. Call OpenRouter via go-openai
-	resp, err := s.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages:  messages,
-		Model:     defaultModel(params.Model),
-		MaxTokens: openai.Int(500),
-	})
+	// 4. Call OpenRouter via go-openai
+	resp, err := s.client.Chat.Completions.New(ctx, completionParams(call.settings, prompt.messages), call.reqOpts...)
 	if err != nil {
+		s.settle(context.Background(), call, reservation, 0, 0)
 		return nil, err
 	}
+	s.settle(context.Background(), call, reservation, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("failed to get a reply: empty response")
+	}
 
 	reply := resp.Choices[0].Message.Content
+	finishReason := resp.Choices[0].FinishReason
 
-	// 4. Save assistant reply
+	// 5. Save assistant reply
 	if _, err := s.repo.SaveMessage(ctx, repository.MessageSaveParams{
 		ConversationID: params.ConversationID,
 		Role:           models.RoleAssistant,
 		Content:        reply,
+		FinishReason:   &finishReason,
 	}); err != nil {
 		return nil, err
 	}
+	s.summaries.Schedule(params.ConversationID)
 
 	return &models.ChatMessage{
 		Role:    models.RoleAssistant,
@@ -87,66 +221,157 @@ func (s *MessageService) SendMessage(ctx context.Context, params MessageSendPara
 	}, nil
 }
 
-func (s *MessageService) StreamMessage(ctx context.Context, params MessageStreamParams) (*ssestream.Stream[openai.ChatCompletionChunk], *openai.ChatCompletionAccumulator, error) {
-	// 1. Save user message
-	_, err := s.repo.SaveMessage(ctx, repository.MessageSaveParams{
-		ConversationID: params.ConversationID,
-		Role:           models.RoleUser,
-		Content:        params.Content,
-	})
+func (s *MessageService) StreamMessage(ctx context.Context, params MessageStreamParams) (*MessageStream, error) {
+	call, err := s.prepare(ctx, params.UserID, params.ConversationID, params.GenerationOptions)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to save user message: %w", err)
+		return nil, err
 	}
 
-	// 2. Fetch recent history
-	history, err := s.repo.GetMessagesByConversation(ctx, repository.MessageListParams{
+	// 1-3. Save the user message and build the prompt
+	userMessage, prompt, reservation, err := s.startTurn(ctx, call, params.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Create streaming request
+	return s.openStream(ctx, params.ConversationID, userMessage.ID, call, prompt.messages, reservation), nil
+}
+
+// RegenerateMessage drops the last assistant reply, if any, and streams a new reply to the last user turn
+func (s *MessageService) RegenerateMessage(ctx context.Context, params MessageRegenerateParams) (*MessageStream, error) {
+	call, err := s.prepare(ctx, params.UserID, params.ConversationID, params.GenerationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := s.repo.GetRecentMessages(ctx, repository.MessageListParams{
 		ConversationID: params.ConversationID,
-		Limit:          20,
+		Limit:          1,
 	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNothingToRegenerate
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get user history: %w", err)
+		return nil, err
+	}
+	if last[0].Role != models.RoleAssistant && last[0].Role != models.RoleUser {
+		return nil, ErrNothingToRegenerate
 	}
 
-	// 3. Convert history into SDK messages
-	var messages []openai.ChatCompletionMessageParamUnion
-	for _, message := range history {
-		switch message.Role {
-		case models.RoleUser:
-			messages = append(messages, openai.UserMessage(message.Content))
-
-		case models.RoleAssistant:
-			messages = append(messages, openai.AssistantMessage(message.Content))
+	// Load the history before the reply is dropped, so a used up quota leaves it in place
+	history, err := s.history(ctx, params.ConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user history: %w", err)
+	}
+	if n := len(history.recent); last[0].Role == models.RoleAssistant && n > 0 && history.recent[n-1].ID == last[0].ID {
+		history.recent = history.recent[:n-1]
+		history.pinned = slices.DeleteFunc(history.pinned, func(m models.Message) bool { return m.ID == last[0].ID })
+	}
+	if len(history.recent) == 0 || history.recent[len(history.recent)-1].Role != models.RoleUser {
+		return nil, ErrNothingToRegenerate
+	}
+	userMessage := history.recent[len(history.recent)-1]
 
-		case models.RoleSystem:
-			messages = append(messages, openai.SystemMessage(message.Content))
-		default:
-			// default to user if unknown
-			messages = append(messages, openai.UserMessage(message.Content))
+	prompt, reservation, err := s.buildPrompt(ctx, call, history)
+	if err != nil {
+		return nil, err
+	}
+	if last[0].Role == models.RoleAssistant {
+		if err := s.repo.DeleteMessage(ctx, last[0].ID); err != nil {
+			s.settle(context.Background(), call, reservation, 0, 0)
+			return nil, fmt.Errorf("failed to delete previous reply: %w", err)
 		}
 	}
 
-	// 4. Add the current user message to the end
-	messages = append(messages, openai.UserMessage(params.Content))
+	return s.openStream(ctx, params.ConversationID, userMessage.ID, call, prompt.messages, reservation), nil
+}
 
-	// 5. Create streaming request
-	stream := s.client.Chat.Completions.NewStreaming(ctx, openai.ChatCompletionNewParams{
-		Model:       defaultModel(params.Model),
-		Messages:    messages,
-		MaxTokens:   openai.Int(500),
-		Temperature: openai.Float(0.7),
-		TopP:        openai.Float(1.0),
-	})
+// openStream starts a streaming completion that replies to the given user message
+func (s *MessageService) openStream(ctx context.Context, convID, userMessageID uuid.UUID, call *completionCall, messages []openai.ChatCompletionMessageParamUnion, reservation *Reservation) *MessageStream {
+	settings := call.settings
+	if settings.Temperature == nil {
+		settings.Temperature = openai.Ptr(0.7)
+	}
+
+	params := completionParams(settings, messages)
+	// Ask for a final usage chunk so token counts can be streamed and recorded
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	ctx, abort := context.WithCancelCause(ctx)
+	stream := s.client.Chat.Completions.NewStreaming(ctx, params, call.reqOpts...)
 	acc := openai.ChatCompletionAccumulator{}
 
-	return stream, &acc, nil
+	return &MessageStream{
+		Stream:             stream,
+		Acc:                &acc,
+		Abort:              abort,
+		Model:              settings.Model,
+		ConversationID:     convID,
+		UserMessageID:      userMessageID,
+		AssistantMessageID: uuid.New(),
+		call:               call,
+		reservation:        reservation,
+	}
+}
+
+// RecordUsage records what a stream used and charges it to the quotas in place of its
+// reservation. A stream cut short before its usage arrived is charged its prompt and the
+// content it got out, or nothing when it got nothing out.
+func (s *MessageService) RecordUsage(ctx context.Context, ms *MessageStream, usage openai.CompletionUsage, content string) {
+	if ms.reservation == nil {
+		return
+	}
+	prompt, completion := usage.PromptTokens, usage.CompletionTokens
+	if usage.TotalTokens == 0 && content != "" {
+		prompt, completion = ms.reservation.PromptTokens, int64(tokenizer.For(ms.call.settings.Model).Count(content))
+	}
+	s.settle(ctx, ms.call, ms.reservation, prompt, completion)
+}
+
+// ListMessages returns the messages of a conversation in chronological order
+func (s *MessageService) ListMessages(ctx context.Context, params MessageListParams) ([]models.Message, error) {
+	if _, err := s.access.Conversation(ctx, params.UserID, params.ConversationID, PermissionRead); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.repo.GetMessagesByConversation(ctx, repository.MessageListParams{
+		ConversationID: params.ConversationID,
+		Limit:          params.Limit,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return []models.Message{}, nil
+	}
+	return messages, err
+}
 
+// PinMessage pins or unpins a message. Pinned messages are sent with every prompt of the
+// conversation, even once the summary covers them.
+func (s *MessageService) PinMessage(ctx context.Context, params MessagePinParams) (*models.Message, error) {
+	if _, err := s.access.Conversation(ctx, params.UserID, params.ConversationID, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.repo.SetMessagePinned(ctx, params.ConversationID, params.MessageID, params.Pinned)
 }
 
 // SaveAssistantMessage persists the assistant text after streaming completes.
 func (s *MessageService) SaveAssistantMessage(ctx context.Context, params MessageSaveParams) (*models.Message, error) {
-	return s.repo.SaveMessage(ctx, repository.MessageSaveParams{
+	save := repository.MessageSaveParams{
+		ID:             params.ID,
 		ConversationID: params.ConversationID,
 		Role:           models.RoleAssistant,
 		Content:        params.Content,
-	})
+		Status:         params.Status,
+	}
+	if params.FinishReason != "" {
+		save.FinishReason = &params.FinishReason
+	}
+	if params.Error != "" {
+		save.Error = &params.Error
+	}
+	m, err := s.repo.SaveMessage(ctx, save)
+	if err != nil {
+		return nil, err
+	}
+	s.summaries.Schedule(params.ConversationID)
+	return m, nil
 }
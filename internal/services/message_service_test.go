@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// fakeMessages keeps saved messages in memory and fails to load the pinned ones with pinnedErr
+type fakeMessages struct {
+	messages  map[uuid.UUID]models.Message
+	pinnedErr error
+}
+
+func (f *fakeMessages) SaveMessage(ctx context.Context, params repository.MessageSaveParams) (*models.Message, error) {
+	m := models.Message{ID: uuid.New(), ConversationID: params.ConversationID, Role: params.Role, Content: params.Content}
+	f.messages[m.ID] = m
+	return &m, nil
+}
+
+func (f *fakeMessages) GetMessagesByConversation(ctx context.Context, params repository.MessageListParams) ([]models.Message, error) {
+	var list []models.Message
+	for _, m := range f.messages {
+		if m.ConversationID == params.ConversationID && (params.After == nil || m.CreatedAt.After(*params.After)) {
+			list = append(list, m)
+		}
+	}
+	if len(list) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	slices.SortFunc(list, func(a, b models.Message) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return list, nil
+}
+
+func (f *fakeMessages) GetRecentMessages(ctx context.Context, params repository.MessageListParams) ([]models.Message, error) {
+	return f.GetMessagesByConversation(ctx, params)
+}
+
+func (f *fakeMessages) GetPinnedMessages(ctx context.Context, convID uuid.UUID) ([]models.Message, error) {
+	return nil, f.pinnedErr
+}
+
+func (f *fakeMessages) SetMessagePinned(ctx context.Context, convID, id uuid.UUID, pinned bool) (*models.Message, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakeMessages) DeleteMessage(ctx context.Context, id uuid.UUID) error {
+	if _, ok := f.messages[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(f.messages, id)
+	return nil
+}
+
+// fakeSummaries keeps the summary of each conversation in memory
+type fakeSummaries map[uuid.UUID]models.Summary
+
+func (f fakeSummaries) GetSummary(ctx context.Context, convID uuid.UUID) (models.Summary, error) {
+	summary, ok := f[convID]
+	if !ok {
+		return models.Summary{}, repository.ErrNotFound
+	}
+	return summary, nil
+}
+
+func (f fakeSummaries) UpsertSummary(ctx context.Context, params repository.SummaryUpsertParams) (models.Summary, error) {
+	summary := models.Summary{
+		ConversationID:  params.ConversationID,
+		Content:         params.Content,
+		SummarizedUntil: params.SummarizedUntil,
+		MessageCount:    params.MessageCount,
+	}
+	f[params.ConversationID] = summary
+	return summary, nil
+}
+
+func (fakeSummaries) UpdateSummaryContent(ctx context.Context, convID uuid.UUID, content string) (models.Summary, error) {
+	return models.Summary{}, repository.ErrInternal
+}
+
+func TestFailedHistoryDropsUserMessage(t *testing.T) {
+	owner := uuid.New()
+	access, convID := newTestAccess(owner)
+	messages := &fakeMessages{messages: map[uuid.UUID]models.Message{}, pinnedErr: repository.ErrInternal}
+	s := &MessageService{
+		repo:      messages,
+		access:    access,
+		summaries: &SummaryService{repo: fakeSummaries{}},
+	}
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		send func() error
+	}{
+		{"send", func() error {
+			_, err := s.SendMessage(ctx, MessageSendParams{UserID: owner, ConversationID: convID, Content: "Hello"})
+			return err
+		}},
+		{"stream", func() error {
+			_, err := s.StreamMessage(ctx, MessageStreamParams{UserID: owner, ConversationID: convID, Content: "Hello"})
+			return err
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.send(); !errors.Is(err, repository.ErrInternal) {
+				t.Fatalf("got error %v, want repository.ErrInternal", err)
+			}
+			if n := len(messages.messages); n != 0 {
+				t.Fatalf("%d user messages left behind, want none", n)
+			}
+		})
+	}
+}
+
+// newTestCompletions returns a client whose chat completions all answer response,
+// and the number of calls made through it
+func newTestCompletions(t *testing.T, response string) (*openai.Client, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(srv.Close)
+	client := openai.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test"), option.WithMaxRetries(0))
+	return &client, &calls
+}
+
+func TestSendMessageWithoutChoices(t *testing.T) {
+	// The provider answers with usage but no choice
+	client, _ := newTestCompletions(t, `{"id": "x", "object": "chat.completion", "model": "big", "choices": [], "usage": {"prompt_tokens": 12, "completion_tokens": 0, "total_tokens": 12}}`)
+
+	owner := uuid.New()
+	access, convID := newTestAccess(owner)
+	messages := &fakeMessages{messages: map[uuid.UUID]models.Message{}}
+	quotas, _ := newTestQuotas()
+	usage, events := newTestUsage()
+	s := &MessageService{
+		repo:      messages,
+		access:    access,
+		summaries: &SummaryService{repo: fakeSummaries{}},
+		models:    quotas.models,
+		client:    client,
+		billing:   billing{quotas: quotas, usage: usage},
+	}
+
+	_, err := s.SendMessage(context.Background(), MessageSendParams{
+		UserID:            owner,
+		ConversationID:    convID,
+		Content:           "Hello",
+		GenerationOptions: GenerationOptions{Model: "big"},
+	})
+	if err == nil {
+		t.Fatal("a response without choices was accepted")
+	}
+	if len(events.events) != 1 || events.events[0].PromptTokens != 12 {
+		t.Fatalf("recorded %+v, want the prompt tokens of the call", events.events)
+	}
+	for _, m := range messages.messages {
+		if m.Role == models.RoleAssistant {
+			t.Fatalf("saved an assistant reply %+v", m)
+		}
+	}
+}
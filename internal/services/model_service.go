@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// modelStore is the part of ModelRepo the model registry needs
+type modelStore interface {
+	ListModels(ctx context.Context) ([]models.ModelInfo, error)
+	GetModel(ctx context.Context, id string) (models.ModelInfo, error)
+	SaveModel(ctx context.Context, params repository.ModelSaveParams) (models.ModelInfo, error)
+	DeleteModel(ctx context.Context, id string) error
+}
+
+// ModelService keeps the registry of models with their prices and context windows
+type ModelService struct {
+	repo modelStore
+}
+
+func NewModelService(repo *repository.ModelRepo) *ModelService {
+	return &ModelService{
+		repo: repo,
+	}
+}
+
+// ListModels returns the models of the registry
+func (s *ModelService) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	return s.repo.ListModels(ctx)
+}
+
+// SaveModel adds a model to the registry or replaces its prices
+func (s *ModelService) SaveModel(ctx context.Context, params ModelSaveParams) (models.ModelInfo, error) {
+	params.ID = strings.TrimSpace(params.ID)
+	if params.ID == "" {
+		return models.ModelInfo{}, fmt.Errorf("%w: model id is required", ErrInvalidInput)
+	}
+	if params.InputPrice < 0 || params.OutputPrice < 0 || params.ContextWindow < 0 {
+		return models.ModelInfo{}, fmt.Errorf("%w: prices and context window cannot be negative", ErrInvalidInput)
+	}
+	return s.repo.SaveModel(ctx, repository.ModelSaveParams{
+		ID:            params.ID,
+		InputPrice:    params.InputPrice,
+		OutputPrice:   params.OutputPrice,
+		ContextWindow: params.ContextWindow,
+	})
+}
+
+// DeleteModel removes a model from the registry
+func (s *ModelService) DeleteModel(ctx context.Context, id string) error {
+	return s.repo.DeleteModel(ctx, id)
+}
+
+// lookup returns a model of the registry. A model missing from it is free and of
+// unknown context window.
+func (s *ModelService) lookup(ctx context.Context, id string) (models.ModelInfo, error) {
+	m, err := s.repo.GetModel(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.ModelInfo{ID: id}, nil
+	}
+	return m, err
+}
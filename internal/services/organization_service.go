@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// roleRank orders the membership roles. Managing a member takes at least the admin
+// role and a role at least as high as both the member's current and new role.
+var roleRank = map[string]int{
+	models.MemberRoleViewer: 1,
+	models.MemberRoleMember: 2,
+	models.MemberRoleAdmin:  3,
+	models.MemberRoleOwner:  4,
+}
+
+// canManage reports whether a member with role actor may grant or take away role
+func canManage(actor, role string) bool {
+	return roleRank[actor] >= roleRank[models.MemberRoleAdmin] && roleRank[actor] >= roleRank[role]
+}
+
+type OrganizationService struct {
+	repo       *repository.OrganizationRepo
+	workspaces *repository.WorkspaceRepo
+	userRepo   *repository.UserRepo
+}
+
+func NewOrganizationService(repo *repository.OrganizationRepo, workspaces *repository.WorkspaceRepo, userRepo *repository.UserRepo) *OrganizationService {
+	return &OrganizationService{
+		repo:       repo,
+		workspaces: workspaces,
+		userRepo:   userRepo,
+	}
+}
+
+// CreateOrganization creates an organization owned by userID
+func (s *OrganizationService) CreateOrganization(ctx context.Context, userID uuid.UUID, name string) (models.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.Organization{}, fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	return s.repo.CreateOrganization(ctx, name, userID)
+}
+
+// ListOrganizations returns the organizations of a user
+func (s *OrganizationService) ListOrganizations(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	return s.repo.ListOrganizations(ctx, userID)
+}
+
+// GetOrganization returns an organization of which userID is a member, with their role
+func (s *OrganizationService) GetOrganization(ctx context.Context, userID, orgID uuid.UUID) (models.Organization, error) {
+	role, err := s.repo.GetMemberRole(ctx, orgID, userID)
+	if err != nil {
+		return models.Organization{}, err
+	}
+	org, err := s.repo.GetOrganization(ctx, orgID)
+	if err != nil {
+		return models.Organization{}, err
+	}
+	org.Role = role
+	return org, nil
+}
+
+// ListMembers returns the members of an organization to one of its members
+func (s *OrganizationService) ListMembers(ctx context.Context, userID, orgID uuid.UUID) ([]models.Membership, error) {
+	if _, err := s.repo.GetMemberRole(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, orgID)
+}
+
+// InviteMember adds the account with the given email to an organization
+func (s *OrganizationService) InviteMember(ctx context.Context, params MemberInviteParams) (models.Membership, error) {
+	role := params.Role
+	if role == "" {
+		role = models.MemberRoleMember
+	}
+	if err := s.authorizeRole(ctx, params.ActorID, params.OrgID, role); err != nil {
+		return models.Membership{}, err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, strings.TrimSpace(params.Email))
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Membership{}, fmt.Errorf("%w: no account uses this email", ErrInvalidInput)
+	}
+	if err != nil {
+		return models.Membership{}, err
+	}
+
+	return s.repo.AddMember(ctx, repository.MembershipParams{
+		OrgID:  params.OrgID,
+		UserID: user.ID,
+		Role:   role,
+	})
+}
+
+// UpdateMemberRole changes the role of a member. The last owner cannot be demoted.
+func (s *OrganizationService) UpdateMemberRole(ctx context.Context, params MemberUpdateParams) (models.Membership, error) {
+	if err := s.authorizeRole(ctx, params.ActorID, params.OrgID, params.Role); err != nil {
+		return models.Membership{}, err
+	}
+	if err := s.authorizeMember(ctx, params.ActorID, params.OrgID, params.UserID); err != nil {
+		return models.Membership{}, err
+	}
+
+	return s.repo.UpdateMemberRole(ctx, repository.MembershipParams{
+		OrgID:  params.OrgID,
+		UserID: params.UserID,
+		Role:   params.Role,
+	})
+}
+
+// RemoveMember removes a member from an organization. Anyone may leave; the last owner
+// may not.
+func (s *OrganizationService) RemoveMember(ctx context.Context, actorID, orgID, userID uuid.UUID) error {
+	if actorID != userID {
+		if err := s.authorizeMember(ctx, actorID, orgID, userID); err != nil {
+			return err
+		}
+	}
+	return s.repo.RemoveMember(ctx, orgID, userID)
+}
+
+// authorizeRole checks that actorID may grant role in an organization
+func (s *OrganizationService) authorizeRole(ctx context.Context, actorID, orgID uuid.UUID, role string) error {
+	if _, ok := roleRank[role]; !ok {
+		return fmt.Errorf("%w: role must be owner, admin, member or viewer", ErrInvalidInput)
+	}
+	actor, err := s.repo.GetMemberRole(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if !canManage(actor, role) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// authorizeMember checks that actorID may change the membership of userID
+func (s *OrganizationService) authorizeMember(ctx context.Context, actorID, orgID, userID uuid.UUID) error {
+	actor, err := s.repo.GetMemberRole(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	current, err := s.repo.GetMemberRole(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if !canManage(actor, current) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// CreateWorkspace creates a workspace in an organization. It takes the admin role.
+func (s *OrganizationService) CreateWorkspace(ctx context.Context, params WorkspaceCreateParams) (models.Workspace, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return models.Workspace{}, fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	allowed, err := workspacePolicy(params.AllowedModels, params.DefaultSettings)
+	if err != nil {
+		return models.Workspace{}, err
+	}
+	if err := s.authorizeAdmin(ctx, params.UserID, params.OrgID); err != nil {
+		return models.Workspace{}, err
+	}
+
+	return s.workspaces.CreateWorkspace(ctx, repository.WorkspaceCreateParams{
+		OrgID:           params.OrgID,
+		Name:            name,
+		AllowedModels:   allowed,
+		DefaultSettings: params.DefaultSettings,
+	})
+}
+
+// ListWorkspaces returns the workspaces of an organization to one of its members
+func (s *OrganizationService) ListWorkspaces(ctx context.Context, userID, orgID uuid.UUID) ([]models.Workspace, error) {
+	if _, err := s.repo.GetMemberRole(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.workspaces.ListWorkspaces(ctx, orgID)
+}
+
+// GetWorkspace returns a workspace to a member of its organization
+func (s *OrganizationService) GetWorkspace(ctx context.Context, userID, workspaceID uuid.UUID) (models.Workspace, error) {
+	if _, err := s.workspaces.GetWorkspaceRole(ctx, workspaceID, userID); err != nil {
+		return models.Workspace{}, err
+	}
+	return s.workspaces.GetWorkspace(ctx, workspaceID)
+}
+
+// UpdateWorkspace renames a workspace or replaces its allowlist or defaults. It takes the admin role.
+func (s *OrganizationService) UpdateWorkspace(ctx context.Context, params WorkspaceUpdateParams) (models.Workspace, error) {
+	if params.Name != nil {
+		name := strings.TrimSpace(*params.Name)
+		if name == "" {
+			return models.Workspace{}, fmt.Errorf("%w: name cannot be empty", ErrInvalidInput)
+		}
+		params.Name = &name
+	}
+
+	role, err := s.workspaces.GetWorkspaceRole(ctx, params.ID, params.UserID)
+	if err != nil {
+		return models.Workspace{}, err
+	}
+	if roleRank[role] < roleRank[models.MemberRoleAdmin] {
+		return models.Workspace{}, ErrForbidden
+	}
+
+	// The allowlist and the defaults are checked against each other as they will be stored
+	current, err := s.workspaces.GetWorkspace(ctx, params.ID)
+	if err != nil {
+		return models.Workspace{}, err
+	}
+	allowed, defaults := current.AllowedModels, current.DefaultSettings
+	if params.AllowedModels != nil {
+		allowed = params.AllowedModels
+	}
+	if params.DefaultSettings != nil {
+		defaults = *params.DefaultSettings
+	}
+	if allowed, err = workspacePolicy(allowed, defaults); err != nil {
+		return models.Workspace{}, err
+	}
+
+	update := repository.WorkspaceUpdateParams{
+		ID:              params.ID,
+		Name:            params.Name,
+		DefaultSettings: params.DefaultSettings,
+	}
+	if params.AllowedModels != nil {
+		update.AllowedModels = allowed
+	}
+	return s.workspaces.UpdateWorkspace(ctx, update)
+}
+
+// authorizeAdmin checks that userID is an admin or owner of an organization
+func (s *OrganizationService) authorizeAdmin(ctx context.Context, userID, orgID uuid.UUID) error {
+	role, err := s.repo.GetMemberRole(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if roleRank[role] < roleRank[models.MemberRoleAdmin] {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// workspacePolicy cleans up a model allowlist and checks the default settings against it
+func workspacePolicy(allowedModels []string, defaults models.ConversationSettings) ([]string, error) {
+	allowed := []string{}
+	seen := make(map[string]bool, len(allowedModels))
+	for _, model := range allowedModels {
+		model = strings.TrimSpace(model)
+		if model == "" || seen[model] {
+			continue
+		}
+		seen[model] = true
+		allowed = append(allowed, model)
+	}
+
+	if err := validateSettings(defaults); err != nil {
+		return nil, err
+	}
+	if defaults.Model != "" {
+		if err := allowModel(&models.Workspace{AllowedModels: allowed}, defaults.Model); err != nil {
+			return nil, err
+		}
+	}
+	return allowed, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/openai/openai-go"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/tokenizer"
+)
+
+const (
+	// fallbackContextWindow is assumed for models whose context window the registry does not know
+	fallbackContextWindow = 8192
+	// maxContextMessages caps how many recent messages are loaded to fill a context window
+	maxContextMessages = 500
+)
+
+// conversationContext is what a conversation offers a prompt: the summary of its older
+// turns, its pinned messages and its newest turns, each oldest first
+type conversationContext struct {
+	summary *models.Summary
+	pinned  []models.Message
+	recent  []models.Message
+}
+
+// prompt holds the messages of a completion request fitted to the context window of its model
+type prompt struct {
+	messages []openai.ChatCompletionMessageParamUnion
+	tokens   int64 // prompt tokens, as the tokenizer of the model counts them
+}
+
+// buildPrompt fits a conversation into the context window of model, less the tokens
+// reserved for the reply. The system prompt, the summary, the pinned messages and the
+// newest turn always go in, or buildPrompt fails with ErrContextTooLong; then as many of
+// the turns before the newest as fit, newest first. Messages go out oldest first.
+func buildPrompt(model models.ModelInfo, settings models.ConversationSettings, cc conversationContext) (*prompt, error) {
+	tok := tokenizer.For(settings.Model)
+	window := int64(model.ContextWindow)
+	if window == 0 {
+		window = fallbackContextWindow
+	}
+	budget := window - maxOutputTokens(settings) - tokenizer.ReplyPriming
+
+	var head []openai.ChatCompletionMessageParamUnion
+	used := int64(0)
+	if settings.SystemPrompt != "" {
+		head = append(head, openai.SystemMessage(settings.SystemPrompt))
+		used += int64(tok.Message(settings.SystemPrompt))
+	}
+	if cc.summary != nil && cc.summary.Content != "" {
+		content := "Summary of the earlier part of this conversation:\n" + cc.summary.Content
+		head = append(head, openai.SystemMessage(content))
+		used += int64(tok.Message(content))
+	}
+	if len(settings.Tools) > 0 {
+		// Tool definitions are sent as JSON schemas, counted here as their JSON
+		tools, _ := json.Marshal(settings.Tools)
+		used += int64(tok.Count(string(tools)))
+	}
+
+	// Pinned messages and the newest turn are required; the turns before it fill what is left
+	chosen := map[uuid.UUID]models.Message{}
+	for _, m := range cc.pinned {
+		if m.Content != "" {
+			chosen[m.ID] = m
+			used += int64(tok.Message(m.Content))
+		}
+	}
+	if n := len(cc.recent); n > 0 {
+		if newest := cc.recent[n-1]; newest.Content != "" && !newest.Pinned {
+			chosen[newest.ID] = newest
+			used += int64(tok.Message(newest.Content))
+		}
+	}
+	if used > budget {
+		return nil, fmt.Errorf("%w: the system prompt, summary, pinned messages and last turn take %d tokens, and %s leaves room for %d with %d reserved for the reply",
+			ErrContextTooLong, used, settings.Model, max(budget, 0), maxOutputTokens(settings))
+	}
+	for i := len(cc.recent) - 2; i >= 0; i-- {
+		m := cc.recent[i]
+		// Replies interrupted before any output carry nothing worth resending
+		if m.Content == "" || m.Pinned {
+			continue
+		}
+		cost := int64(tok.Message(m.Content))
+		if used+cost > budget {
+			break
+		}
+		chosen[m.ID] = m
+		used += cost
+	}
+
+	turns := make([]models.Message, 0, len(chosen))
+	for _, m := range chosen {
+		turns = append(turns, m)
+	}
+	sort.Slice(turns, func(i, j int) bool {
+		return turns[i].CreatedAt.Before(turns[j].CreatedAt)
+	})
+
+	messages := head
+	for _, m := range turns {
+		messages = append(messages, chatMessage(m))
+	}
+	return &prompt{messages: messages, tokens: used + tokenizer.ReplyPriming}, nil
+}
+
+// chatMessage converts a stored message into an SDK message
+func chatMessage(m models.Message) openai.ChatCompletionMessageParamUnion {
+	switch m.Role {
+	case models.RoleAssistant:
+		return openai.AssistantMessage(m.Content)
+	case models.RoleSystem:
+		return openai.SystemMessage(m.Content)
+	default:
+		// default to user if unknown
+		return openai.UserMessage(m.Content)
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openai/openai-go"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/tokenizer"
+)
+
+// testTurns returns n alternating user and assistant messages, a minute apart, each
+// "turn <i>" followed by padding words
+func testTurns(n, padding int) []models.Message {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	turns := make([]models.Message, n)
+	for i := range turns {
+		role := models.RoleUser
+		if i%2 == 1 {
+			role = models.RoleAssistant
+		}
+		turns[i] = models.Message{
+			ID:        uuid.New(),
+			Role:      role,
+			Content:   "turn " + string(rune('A'+i)) + strings.Repeat(" word", padding),
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		}
+	}
+	return turns
+}
+
+// promptContents returns the text of every message of a prompt
+func promptContents(t *testing.T, p *prompt) []string {
+	t.Helper()
+	var contents []string
+	for _, m := range p.messages {
+		switch {
+		case m.OfSystem != nil:
+			contents = append(contents, m.OfSystem.Content.OfString.Value)
+		case m.OfUser != nil:
+			contents = append(contents, m.OfUser.Content.OfString.Value)
+		case m.OfAssistant != nil:
+			contents = append(contents, m.OfAssistant.Content.OfString.Value)
+		default:
+			t.Fatalf("unexpected message %+v", m)
+		}
+	}
+	return contents
+}
+
+func TestBuildPromptKeepsNewestTurns(t *testing.T) {
+	turns := testTurns(10, 100)
+	settings := models.ConversationSettings{Model: "gpt-4o", SystemPrompt: "Be brief.", MaxTokens: openai.Ptr(int64(100))}
+	perTurn := int64(tokenizer.For("gpt-4o").Message(turns[0].Content))
+	// Room for the system prompt and three turns, not four
+	window := 100 + tokenizer.ReplyPriming + int64(tokenizer.For("gpt-4o").Message("Be brief.")) + 3*perTurn + perTurn/2
+
+	p, err := buildPrompt(models.ModelInfo{ID: "gpt-4o", ContextWindow: int(window)}, settings, conversationContext{recent: turns})
+	if err != nil {
+		t.Fatalf("buildPrompt failed: %v", err)
+	}
+	contents := promptContents(t, p)
+	if len(contents) != 4 || contents[0] != "Be brief." {
+		t.Fatalf("got %d messages, want the system prompt and three turns", len(contents))
+	}
+	for i, c := range contents[1:] {
+		if c != turns[7+i].Content {
+			t.Fatalf("message %d is %.8q, want the newest turns in order", i+1, c)
+		}
+	}
+	if p.tokens > window-100 {
+		t.Fatalf("prompt of %d tokens leaves no room for the reply", p.tokens)
+	}
+}
+
+func TestBuildPromptKeepsPinnedMessages(t *testing.T) {
+	turns := testTurns(10, 100)
+	turns[1].Pinned = true
+	settings := models.ConversationSettings{Model: "gpt-4o", MaxTokens: openai.Ptr(int64(100))}
+	perTurn := int64(tokenizer.For("gpt-4o").Message(turns[0].Content))
+	summary := &models.Summary{Content: "Earlier."}
+	cc := conversationContext{summary: summary, pinned: []models.Message{turns[1]}, recent: turns[4:]}
+
+	// The pinned message and the newest turn leave no room for the summary
+	window := 100 + tokenizer.ReplyPriming + 2*perTurn
+	_, err := buildPrompt(models.ModelInfo{ContextWindow: int(window)}, settings, cc)
+	if !errors.Is(err, ErrContextTooLong) {
+		t.Fatalf("err = %v, want ErrContextTooLong without room for the summary", err)
+	}
+
+	window += perTurn + perTurn/2
+	p, err := buildPrompt(models.ModelInfo{ContextWindow: int(window)}, settings, cc)
+	if err != nil {
+		t.Fatalf("buildPrompt failed: %v", err)
+	}
+	contents := promptContents(t, p)
+	want := []string{"Summary of the earlier part of this conversation:\nEarlier.", turns[1].Content, turns[8].Content, turns[9].Content}
+	if len(contents) != len(want) {
+		t.Fatalf("got %d messages, want %d", len(contents), len(want))
+	}
+	for i := range want {
+		if contents[i] != want[i] {
+			t.Fatalf("message %d is %.30q, want %.30q", i, contents[i], want[i])
+		}
+	}
+}
+
+func TestBuildPromptSendsEachMessageOnce(t *testing.T) {
+	turns := testTurns(4, 0)
+	turns[2].Pinned = true
+	turns[3].Content = "" // interrupted before any output
+	cc := conversationContext{pinned: []models.Message{turns[2]}, recent: turns}
+
+	p, err := buildPrompt(models.ModelInfo{}, models.ConversationSettings{Model: "gpt-4o"}, cc)
+	if err != nil {
+		t.Fatalf("buildPrompt failed: %v", err)
+	}
+	contents := promptContents(t, p)
+	if len(contents) != 3 || contents[2] != turns[2].Content {
+		t.Fatalf("got %q, want the three turns with content once each", contents)
+	}
+}
+
+func TestBuildPromptTooLong(t *testing.T) {
+	turns := testTurns(1, 1000)
+	settings := models.ConversationSettings{Model: "gpt-4o", MaxTokens: openai.Ptr(int64(500))}
+
+	_, err := buildPrompt(models.ModelInfo{ContextWindow: 800}, settings, conversationContext{recent: turns})
+	if !errors.Is(err, ErrContextTooLong) {
+		t.Fatalf("err = %v, want ErrContextTooLong", err)
+	}
+}
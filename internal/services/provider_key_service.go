@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+	"github.com/openai/openai-go/option"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	"github.com/typescript-any/llm-playground/internal/secrets"
+)
+
+const (
+	minProviderKeyLength = 16
+	// rotateBatchSize is how many provider keys Rotate re-encrypts per query
+	rotateBatchSize = 100
+)
+
+// ProviderKeyService keeps the provider keys users and organizations bring, encrypted
+// under the master keys of the keyring, and picks the one that pays for a request
+type ProviderKeyService struct {
+	repo    *repository.ProviderKeyRepo
+	orgRepo *repository.OrganizationRepo
+	keyring *secrets.Keyring
+}
+
+func NewProviderKeyService(repo *repository.ProviderKeyRepo, orgRepo *repository.OrganizationRepo, keyring *secrets.Keyring) *ProviderKeyService {
+	return &ProviderKeyService{
+		repo:    repo,
+		orgRepo: orgRepo,
+		keyring: keyring,
+	}
+}
+
+// SetUserKey stores the provider key of a user, replacing the previous one
+func (s *ProviderKeyService) SetUserKey(ctx context.Context, userID uuid.UUID, key string) (models.ProviderKey, error) {
+	return s.save(ctx, repository.ProviderKeySaveParams{UserID: &userID}, key)
+}
+
+// GetUserKey returns the provider key of a user, without the key itself
+func (s *ProviderKeyService) GetUserKey(ctx context.Context, userID uuid.UUID) (models.ProviderKey, error) {
+	return s.repo.GetUserProviderKey(ctx, userID)
+}
+
+// DeleteUserKey removes the provider key of a user
+func (s *ProviderKeyService) DeleteUserKey(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.DeleteUserProviderKey(ctx, userID)
+}
+
+// SetOrgKey stores the provider key of an organization. It takes the admin role.
+func (s *ProviderKeyService) SetOrgKey(ctx context.Context, userID, orgID uuid.UUID, key string) (models.ProviderKey, error) {
+	if err := s.authorizeAdmin(ctx, userID, orgID); err != nil {
+		return models.ProviderKey{}, err
+	}
+	return s.save(ctx, repository.ProviderKeySaveParams{OrgID: &orgID}, key)
+}
+
+// GetOrgKey returns the provider key of an organization to one of its members
+func (s *ProviderKeyService) GetOrgKey(ctx context.Context, userID, orgID uuid.UUID) (models.ProviderKey, error) {
+	if _, err := s.orgRepo.GetMemberRole(ctx, orgID, userID); err != nil {
+		return models.ProviderKey{}, err
+	}
+	return s.repo.GetOrgProviderKey(ctx, orgID)
+}
+
+// DeleteOrgKey removes the provider key of an organization. It takes the admin role.
+func (s *ProviderKeyService) DeleteOrgKey(ctx context.Context, userID, orgID uuid.UUID) error {
+	if err := s.authorizeAdmin(ctx, userID, orgID); err != nil {
+		return err
+	}
+	return s.repo.DeleteOrgProviderKey(ctx, orgID)
+}
+
+func (s *ProviderKeyService) save(ctx context.Context, params repository.ProviderKeySaveParams, key string) (models.ProviderKey, error) {
+	key = strings.TrimSpace(key)
+	if len(key) < minProviderKeyLength || strings.ContainsAny(key, " \t\r\n") {
+		return models.ProviderKey{}, fmt.Errorf("%w: provider key must be at least %d characters without spaces", ErrInvalidInput, minProviderKeyLength)
+	}
+
+	sealed, err := s.keyring.Seal([]byte(key), keyOwner(params.UserID, params.OrgID))
+	if err != nil {
+		return models.ProviderKey{}, err
+	}
+	params.KeyID, params.Nonce, params.Ciphertext = sealed.KeyID, sealed.Nonce, sealed.Ciphertext
+	params.Hint = "…" + key[len(key)-4:]
+	return s.repo.SaveProviderKey(ctx, params)
+}
+
+// authorizeAdmin checks that userID is an admin or owner of an organization
+func (s *ProviderKeyService) authorizeAdmin(ctx context.Context, userID, orgID uuid.UUID) error {
+	role, err := s.orgRepo.GetMemberRole(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if roleRank[role] < roleRank[models.MemberRoleAdmin] {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// requestOptions returns the options that make a provider call of userID pay with the
+// key that applies: in a workspace the key of its organization, then the key of the
+// user. Without either the call uses the server key.
+func (s *ProviderKeyService) requestOptions(ctx context.Context, userID uuid.UUID, ws *models.Workspace) ([]option.RequestOption, error) {
+	if ws != nil {
+		k, err := s.repo.GetOrgProviderKey(ctx, ws.OrgID)
+		if err == nil {
+			return s.open(k)
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+	k, err := s.repo.GetUserProviderKey(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.open(k)
+}
+
+// open decrypts a provider key into request options
+func (s *ProviderKeyService) open(k models.ProviderKey) ([]option.RequestOption, error) {
+	key, err := s.keyring.Open(sealedKey(k), keyOwner(k.UserID, k.OrgID))
+	if err != nil {
+		log.Errorf("Error opening provider key %s: %v", k.ID, err)
+		return nil, ErrProviderKeyUnavailable
+	}
+	return []option.RequestOption{option.WithAPIKey(string(key))}, nil
+}
+
+// Rotate encrypts every provider key that is not sealed under the active master key
+// again under it, and returns how many it re-encrypted. Keys that cannot be decrypted
+// are logged and left as they are, and make Rotate fail once it has done the others.
+func (s *ProviderKeyService) Rotate(ctx context.Context) (int, error) {
+	active := s.keyring.ActiveKeyID()
+	rotated, failed := 0, 0
+	after := uuid.Nil
+	for {
+		batch, err := s.repo.ListProviderKeysToRotate(ctx, active, after, rotateBatchSize)
+		if err != nil {
+			return rotated, err
+		}
+
+		for _, k := range batch {
+			after = k.ID
+			aad := keyOwner(k.UserID, k.OrgID)
+			key, err := s.keyring.Open(sealedKey(k), aad)
+			if err != nil {
+				log.Errorf("Error opening provider key %s: %v", k.ID, err)
+				failed++
+				continue
+			}
+			sealed, err := s.keyring.Seal(key, aad)
+			if err != nil {
+				return rotated, err
+			}
+			err = s.repo.ResealProviderKey(ctx, repository.ProviderKeyResealParams{
+				ID:         k.ID,
+				FromKeyID:  k.KeyID,
+				KeyID:      sealed.KeyID,
+				Nonce:      sealed.Nonce,
+				Ciphertext: sealed.Ciphertext,
+			})
+			switch {
+			case err == nil:
+				rotated++
+			case errors.Is(err, repository.ErrConflict):
+				// Replaced meanwhile, so already sealed under the active key
+			default:
+				return rotated, err
+			}
+		}
+		if len(batch) < rotateBatchSize {
+			break
+		}
+	}
+	if failed > 0 {
+		return rotated, fmt.Errorf("%d provider key(s) cannot be decrypted", failed)
+	}
+	return rotated, nil
+}
+
+func sealedKey(k models.ProviderKey) secrets.Sealed {
+	return secrets.Sealed{KeyID: k.KeyID, Nonce: k.Nonce, Ciphertext: k.Ciphertext}
+}
+
+// keyOwner is the additional data a provider key is sealed with, so a key copied onto
+// another owner's row does not decrypt
+func keyOwner(userID, orgID *uuid.UUID) []byte {
+	if orgID != nil {
+		return []byte("org:" + orgID.String())
+	}
+	return []byte("user:" + userID.String())
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// quotaStore is the part of QuotaRepo that quotas need
+type quotaStore interface {
+	GetQuota(ctx context.Context, userID, orgID *uuid.UUID) (models.Quota, error)
+	ListQuotas(ctx context.Context) ([]models.Quota, error)
+	SaveQuota(ctx context.Context, params repository.QuotaSaveParams) (models.Quota, error)
+	DeleteQuota(ctx context.Context, userID, orgID *uuid.UUID) error
+	GetQuotaUsage(ctx context.Context, subject string, day, month time.Time) (daily, monthly models.QuotaUsage, err error)
+	ChargeQuotaUsage(ctx context.Context, charges []repository.QuotaChargeParams) (int, error)
+}
+
+// QuotaService enforces the daily and monthly token and cost budgets of users and
+// organizations. A call is charged its estimated usage before it is made, so concurrent
+// calls cannot overrun a budget together, and corrected once its usage is known.
+type QuotaService struct {
+	repo   quotaStore
+	models *ModelService
+	now    func() time.Time
+}
+
+func NewQuotaService(repo *repository.QuotaRepo, modelService *ModelService) *QuotaService {
+	return &QuotaService{
+		repo:   repo,
+		models: modelService,
+		now:    time.Now,
+	}
+}
+
+// Reservation is the estimated usage of a call, charged to the quotas that apply to it
+// until Settle replaces it with the actual usage
+type Reservation struct {
+	Model            models.ModelInfo // the model to call, a downgrade of the requested one past a budget
+	PromptTokens     int64
+	CompletionTokens int64
+
+	charges []repository.QuotaChargeParams
+}
+
+// appliedQuota is a quota along with the subject its usage is counted under
+type appliedQuota struct {
+	quota   models.Quota
+	subject string
+	owner   string // who the quota belongs to, in error messages
+}
+
+// Reserve charges the estimated usage of a call to the quota of its user, or the default
+// quota of users, and to the quota of its organization. Past a budget with a downgrade
+// model the call is charged on that model, which the budget no longer stops; past any
+// other budget Reserve fails with ErrQuotaExceeded.
+func (s *QuotaService) Reserve(ctx context.Context, params QuotaReserveParams) (*Reservation, error) {
+	quotas, err := s.applicable(ctx, params.UserID, params.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	model, err := s.models.lookup(ctx, params.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reservation{Model: model, PromptTokens: params.PromptTokens, CompletionTokens: params.CompletionTokens}
+	exceeded, err := s.charge(ctx, r, quotas, "")
+	if err != nil || exceeded < 0 {
+		return r, err
+	}
+
+	q := quotas[exceeded/2].quota
+	if q.DowngradeModel == nil || *q.DowngradeModel == model.ID {
+		return nil, quotaError(quotas[exceeded/2], r.charges[exceeded])
+	}
+	if r.Model, err = s.models.lookup(ctx, *q.DowngradeModel); err != nil {
+		return nil, err
+	}
+	if exceeded, err = s.charge(ctx, r, quotas, r.Model.ID); err != nil {
+		return nil, err
+	}
+	if exceeded >= 0 {
+		return nil, quotaError(quotas[exceeded/2], r.charges[exceeded])
+	}
+	return r, nil
+}
+
+// Settle replaces the estimate charged by Reserve with the actual usage of the call
+func (s *QuotaService) Settle(ctx context.Context, r *Reservation, promptTokens, completionTokens int64) {
+	if len(r.charges) == 0 {
+		return
+	}
+	tokens := promptTokens + completionTokens - r.PromptTokens - r.CompletionTokens
+	cost := r.Model.Cost(promptTokens, completionTokens) - r.Model.Cost(r.PromptTokens, r.CompletionTokens)
+	charges := make([]repository.QuotaChargeParams, len(r.charges))
+	for i, c := range r.charges {
+		charges[i] = repository.QuotaChargeParams{
+			Subject:     c.Subject,
+			Period:      c.Period,
+			PeriodStart: c.PeriodStart,
+			Tokens:      tokens,
+			Cost:        cost,
+		}
+	}
+	if _, err := s.repo.ChargeQuotaUsage(ctx, charges); err != nil {
+		log.Errorf("Error settling quota usage of %s: %v", r.Model.ID, err)
+	}
+}
+
+// charge charges the estimate of r to every period of quotas, without the limits of the
+// quotas that downgrade to softModel. It returns the index of the charge that went over
+// its limits, or -1.
+func (s *QuotaService) charge(ctx context.Context, r *Reservation, quotas []appliedQuota, softModel string) (int, error) {
+	day, month := periodStarts(s.now())
+	tokens := r.PromptTokens + r.CompletionTokens
+	cost := r.Model.Cost(r.PromptTokens, r.CompletionTokens)
+
+	r.charges = r.charges[:0]
+	for _, a := range quotas {
+		q := a.quota
+		daily := repository.QuotaChargeParams{
+			Subject: a.subject, Period: models.QuotaPeriodDay, PeriodStart: day,
+			Tokens: tokens, Cost: cost, MaxTokens: q.DailyTokens, MaxCost: q.DailyCost,
+		}
+		monthly := repository.QuotaChargeParams{
+			Subject: a.subject, Period: models.QuotaPeriodMonth, PeriodStart: month,
+			Tokens: tokens, Cost: cost, MaxTokens: q.MonthlyTokens, MaxCost: q.MonthlyCost,
+		}
+		if softModel != "" && q.DowngradeModel != nil && *q.DowngradeModel == softModel {
+			daily.MaxTokens, daily.MaxCost = nil, nil
+			monthly.MaxTokens, monthly.MaxCost = nil, nil
+		}
+		r.charges = append(r.charges, daily, monthly)
+	}
+	if len(r.charges) == 0 {
+		return -1, nil
+	}
+	return s.repo.ChargeQuotaUsage(ctx, r.charges)
+}
+
+// applicable returns the quotas a call of userID counts against: that of the user, or
+// else the default, and that of orgID
+func (s *QuotaService) applicable(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) ([]appliedQuota, error) {
+	var quotas []appliedQuota
+	q, err := s.userQuota(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if q != nil {
+		quotas = append(quotas, appliedQuota{quota: *q, subject: "user:" + userID.String(), owner: "your account"})
+	}
+	if orgID != nil {
+		q, err := s.repo.GetQuota(ctx, nil, orgID)
+		switch {
+		case err == nil:
+			quotas = append(quotas, appliedQuota{quota: q, subject: "org:" + orgID.String(), owner: "your organization"})
+		case !errors.Is(err, repository.ErrNotFound):
+			return nil, err
+		}
+	}
+	return quotas, nil
+}
+
+// userQuota returns the quota of a user, or else the default quota, or nil without either
+func (s *QuotaService) userQuota(ctx context.Context, userID uuid.UUID) (*models.Quota, error) {
+	q, err := s.repo.GetQuota(ctx, &userID, nil)
+	if errors.Is(err, repository.ErrNotFound) {
+		q, err = s.repo.GetQuota(ctx, nil, nil)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// quotaError tells which budget a charge went over
+func quotaError(a appliedQuota, c repository.QuotaChargeParams) error {
+	period := "daily"
+	if c.Period == models.QuotaPeriodMonth {
+		period = "monthly"
+	}
+	return fmt.Errorf("%w: the %s budget of %s is used up", ErrQuotaExceeded, period, a.owner)
+}
+
+// periodStarts returns the UTC day and month that t falls in
+func periodStarts(t time.Time) (day, month time.Time) {
+	t = t.UTC()
+	day = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
+
+// Status returns the quota that applies to a user, nil without one, and what the user
+// spent today and this month
+func (s *QuotaService) Status(ctx context.Context, userID uuid.UUID) (models.QuotaStatus, error) {
+	q, err := s.userQuota(ctx, userID)
+	if err != nil {
+		return models.QuotaStatus{}, err
+	}
+	status := models.QuotaStatus{Quota: q}
+	day, month := periodStarts(s.now())
+	status.Daily, status.Monthly, err = s.repo.GetQuotaUsage(ctx, "user:"+userID.String(), day, month)
+	return status, err
+}
+
+// ListQuotas returns every quota
+func (s *QuotaService) ListQuotas(ctx context.Context) ([]models.Quota, error) {
+	return s.repo.ListQuotas(ctx)
+}
+
+// GetQuota returns the quota of a user, an organization or, with neither, the default,
+// with what was spent against it. The default quota is spent by each user on their own,
+// so it carries no usage.
+func (s *QuotaService) GetQuota(ctx context.Context, userID, orgID *uuid.UUID) (models.QuotaStatus, error) {
+	q, err := s.repo.GetQuota(ctx, userID, orgID)
+	if err != nil {
+		return models.QuotaStatus{}, err
+	}
+	status := models.QuotaStatus{Quota: &q}
+	day, month := periodStarts(s.now())
+	switch {
+	case userID != nil:
+		status.Daily, status.Monthly, err = s.repo.GetQuotaUsage(ctx, "user:"+userID.String(), day, month)
+	case orgID != nil:
+		status.Daily, status.Monthly, err = s.repo.GetQuotaUsage(ctx, "org:"+orgID.String(), day, month)
+	}
+	return status, err
+}
+
+// SetQuota replaces the limits of a quota. A downgrade model has to be in the registry,
+// so what it costs is known.
+func (s *QuotaService) SetQuota(ctx context.Context, params QuotaSetParams) (models.Quota, error) {
+	for _, limit := range []*int64{params.DailyTokens, params.MonthlyTokens} {
+		if limit != nil && *limit < 0 {
+			return models.Quota{}, fmt.Errorf("%w: token budgets cannot be negative", ErrInvalidInput)
+		}
+	}
+	for _, limit := range []*float64{params.DailyCost, params.MonthlyCost} {
+		if limit != nil && *limit < 0 {
+			return models.Quota{}, fmt.Errorf("%w: cost budgets cannot be negative", ErrInvalidInput)
+		}
+	}
+	if params.DowngradeModel != nil {
+		_, err := s.models.repo.GetModel(ctx, *params.DowngradeModel)
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.Quota{}, fmt.Errorf("%w: downgrade model %q is not in the model registry", ErrInvalidInput, *params.DowngradeModel)
+		}
+		if err != nil {
+			return models.Quota{}, err
+		}
+	}
+	return s.repo.SaveQuota(ctx, repository.QuotaSaveParams{
+		UserID:         params.UserID,
+		OrgID:          params.OrgID,
+		DailyTokens:    params.DailyTokens,
+		MonthlyTokens:  params.MonthlyTokens,
+		DailyCost:      params.DailyCost,
+		MonthlyCost:    params.MonthlyCost,
+		DowngradeModel: params.DowngradeModel,
+	})
+}
+
+// DeleteQuota removes the quota of a user, an organization or, with neither, the default
+func (s *QuotaService) DeleteQuota(ctx context.Context, userID, orgID *uuid.UUID) error {
+	return s.repo.DeleteQuota(ctx, userID, orgID)
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// fakeModels serves the model registry from memory
+type fakeModels map[string]models.ModelInfo
+
+func (f fakeModels) ListModels(ctx context.Context) ([]models.ModelInfo, error) {
+	var list []models.ModelInfo
+	for _, m := range f {
+		list = append(list, m)
+	}
+	return list, nil
+}
+
+func (f fakeModels) GetModel(ctx context.Context, id string) (models.ModelInfo, error) {
+	m, ok := f[id]
+	if !ok {
+		return models.ModelInfo{}, repository.ErrNotFound
+	}
+	return m, nil
+}
+
+func (f fakeModels) SaveModel(ctx context.Context, params repository.ModelSaveParams) (models.ModelInfo, error) {
+	m := models.ModelInfo{ID: params.ID, InputPrice: params.InputPrice, OutputPrice: params.OutputPrice, ContextWindow: params.ContextWindow}
+	f[m.ID] = m
+	return m, nil
+}
+
+func (f fakeModels) DeleteModel(ctx context.Context, id string) error {
+	delete(f, id)
+	return nil
+}
+
+// fakeQuotas keeps quotas and their usage in memory, charging like QuotaRepo does:
+// all charges or, past a limit, none
+type fakeQuotas struct {
+	quotas map[string]models.Quota
+	usage  map[string]models.QuotaUsage
+}
+
+func newFakeQuotas() *fakeQuotas {
+	return &fakeQuotas{quotas: map[string]models.Quota{}, usage: map[string]models.QuotaUsage{}}
+}
+
+func quotaKey(userID, orgID *uuid.UUID) string {
+	switch {
+	case userID != nil:
+		return "user:" + userID.String()
+	case orgID != nil:
+		return "org:" + orgID.String()
+	}
+	return "default"
+}
+
+func (f *fakeQuotas) GetQuota(ctx context.Context, userID, orgID *uuid.UUID) (models.Quota, error) {
+	q, ok := f.quotas[quotaKey(userID, orgID)]
+	if !ok {
+		return models.Quota{}, repository.ErrNotFound
+	}
+	return q, nil
+}
+
+func (f *fakeQuotas) ListQuotas(ctx context.Context) ([]models.Quota, error) {
+	var list []models.Quota
+	for _, q := range f.quotas {
+		list = append(list, q)
+	}
+	return list, nil
+}
+
+func (f *fakeQuotas) SaveQuota(ctx context.Context, params repository.QuotaSaveParams) (models.Quota, error) {
+	q := models.Quota{
+		ID:             uuid.New(),
+		UserID:         params.UserID,
+		OrgID:          params.OrgID,
+		DailyTokens:    params.DailyTokens,
+		MonthlyTokens:  params.MonthlyTokens,
+		DailyCost:      params.DailyCost,
+		MonthlyCost:    params.MonthlyCost,
+		DowngradeModel: params.DowngradeModel,
+	}
+	f.quotas[quotaKey(params.UserID, params.OrgID)] = q
+	return q, nil
+}
+
+func (f *fakeQuotas) DeleteQuota(ctx context.Context, userID, orgID *uuid.UUID) error {
+	delete(f.quotas, quotaKey(userID, orgID))
+	return nil
+}
+
+func usageKey(subject, period string) string {
+	return subject + "/" + period
+}
+
+func (f *fakeQuotas) GetQuotaUsage(ctx context.Context, subject string, day, month time.Time) (daily, monthly models.QuotaUsage, err error) {
+	return f.usage[usageKey(subject, models.QuotaPeriodDay)], f.usage[usageKey(subject, models.QuotaPeriodMonth)], nil
+}
+
+func (f *fakeQuotas) ChargeQuotaUsage(ctx context.Context, charges []repository.QuotaChargeParams) (int, error) {
+	next := map[string]models.QuotaUsage{}
+	for i, c := range charges {
+		key := usageKey(c.Subject, c.Period)
+		u, ok := next[key]
+		if !ok {
+			u = f.usage[key]
+		}
+		u.Tokens += c.Tokens
+		u.Cost += c.Cost
+		if (c.MaxTokens != nil && u.Tokens > *c.MaxTokens) || (c.MaxCost != nil && u.Cost > *c.MaxCost) {
+			return i, nil
+		}
+		next[key] = u
+	}
+	for key, u := range next {
+		f.usage[key] = u
+	}
+	return -1, nil
+}
+
+// newTestQuotas returns a QuotaService over an expensive and a cheap model
+func newTestQuotas() (*QuotaService, *fakeQuotas) {
+	store := newFakeQuotas()
+	registry := fakeModels{
+		"big":   {ID: "big", InputPrice: 10, OutputPrice: 30},
+		"small": {ID: "small", InputPrice: 1, OutputPrice: 2},
+	}
+	return &QuotaService{
+		repo:   store,
+		models: &ModelService{repo: registry},
+		now:    func() time.Time { return time.Date(2025, 3, 14, 23, 30, 0, 0, time.UTC) },
+	}, store
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestQuotaReserveWithinBudget(t *testing.T) {
+	quotas, store := newTestQuotas()
+	ctx := context.Background()
+	userID := uuid.New()
+	store.quotas["default"] = models.Quota{DailyTokens: ptr(int64(1000))}
+
+	r, err := quotas.Reserve(ctx, QuotaReserveParams{UserID: userID, Model: "big", PromptTokens: 300, CompletionTokens: 200})
+	if err != nil {
+		t.Fatalf("reserve failed: %v", err)
+	}
+	if r.Model.ID != "big" {
+		t.Fatalf("model = %s, want big", r.Model.ID)
+	}
+	status, _ := quotas.Status(ctx, userID)
+	if status.Daily.Tokens != 500 || status.Monthly.Tokens != 500 {
+		t.Fatalf("usage = %+v/%+v, want 500 tokens each", status.Daily, status.Monthly)
+	}
+
+	quotas.Settle(ctx, r, 280, 100)
+	status, _ = quotas.Status(ctx, userID)
+	if status.Daily.Tokens != 380 {
+		t.Fatalf("settled usage = %d tokens, want 380", status.Daily.Tokens)
+	}
+	if want := (280*10 + 100*30) / 1e6; status.Daily.Cost < want-1e-12 || status.Daily.Cost > want+1e-12 {
+		t.Fatalf("settled cost = %v, want %v", status.Daily.Cost, want)
+	}
+}
+
+func TestQuotaReserveExceeded(t *testing.T) {
+	quotas, store := newTestQuotas()
+	ctx := context.Background()
+	userID, orgID := uuid.New(), uuid.New()
+	store.quotas["org:"+orgID.String()] = models.Quota{MonthlyCost: ptr(0.01)}
+
+	if _, err := quotas.Reserve(ctx, QuotaReserveParams{UserID: userID, OrgID: &orgID, Model: "big", PromptTokens: 500}); err != nil {
+		t.Fatalf("first reserve failed: %v", err)
+	}
+	_, err := quotas.Reserve(ctx, QuotaReserveParams{UserID: userID, OrgID: &orgID, Model: "big", PromptTokens: 600})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want ErrQuotaExceeded", err)
+	}
+	// A rejected call charges nothing
+	if u := store.usage[usageKey("org:"+orgID.String(), models.QuotaPeriodMonth)]; u.Tokens != 500 {
+		t.Fatalf("org usage = %d tokens, want 500", u.Tokens)
+	}
+}
+
+func TestQuotaReserveDowngrade(t *testing.T) {
+	quotas, store := newTestQuotas()
+	ctx := context.Background()
+	userID := uuid.New()
+	store.quotas["user:"+userID.String()] = models.Quota{DailyTokens: ptr(int64(100)), DowngradeModel: ptr("small")}
+
+	r, err := quotas.Reserve(ctx, QuotaReserveParams{UserID: userID, Model: "big", PromptTokens: 150})
+	if err != nil {
+		t.Fatalf("reserve failed: %v", err)
+	}
+	if r.Model.ID != "small" {
+		t.Fatalf("model = %s, want the downgrade", r.Model.ID)
+	}
+	// The downgrade keeps being charged, past the budget
+	status, _ := quotas.Status(ctx, userID)
+	if status.Daily.Tokens != 150 {
+		t.Fatalf("usage = %d tokens, want 150", status.Daily.Tokens)
+	}
+}
+
+func TestQuotaWithoutQuotas(t *testing.T) {
+	quotas, store := newTestQuotas()
+
+	r, err := quotas.Reserve(context.Background(), QuotaReserveParams{UserID: uuid.New(), Model: "unknown", PromptTokens: 1 << 20})
+	if err != nil {
+		t.Fatalf("reserve failed: %v", err)
+	}
+	if len(store.usage) != 0 {
+		t.Fatalf("usage was charged without a quota: %v", store.usage)
+	}
+	quotas.Settle(context.Background(), r, 10, 10)
+}
+
+func TestQuotaSetValidates(t *testing.T) {
+	quotas, _ := newTestQuotas()
+	ctx := context.Background()
+
+	if _, err := quotas.SetQuota(ctx, QuotaSetParams{DailyTokens: ptr(int64(-1))}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("negative budget: err = %v, want ErrInvalidInput", err)
+	}
+	if _, err := quotas.SetQuota(ctx, QuotaSetParams{DowngradeModel: ptr("unknown")}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("unknown downgrade: err = %v, want ErrInvalidInput", err)
+	}
+	if _, err := quotas.SetQuota(ctx, QuotaSetParams{MonthlyCost: ptr(5.0), DowngradeModel: ptr("small")}); err != nil {
+		t.Fatalf("valid quota: %v", err)
+	}
+}
+
+func TestPeriodStarts(t *testing.T) {
+	at := time.Date(2025, 3, 1, 1, 30, 0, 0, time.FixedZone("CET", 2*3600))
+	day, month := periodStarts(at)
+	if want := time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC); !day.Equal(want) {
+		t.Fatalf("day = %v, want %v", day, want)
+	}
+	if want := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC); !month.Equal(want) {
+		t.Fatalf("month = %v, want %v", month, want)
+	}
+}
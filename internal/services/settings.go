@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/shared"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+const (
+	fallbackModel     = "gpt-4o"
+	fallbackMaxTokens = 500
+)
+
+func defaultModel(model string) string {
+	if model == "" {
+		return fallbackModel
+	}
+	return model
+}
+
+// resolveSettings layers the per-request overrides on top of the conversation settings
+func resolveSettings(settings models.ConversationSettings, opts GenerationOptions) models.ConversationSettings {
+	if opts.Model != "" {
+		settings.Model = opts.Model
+	}
+	if opts.SystemPrompt != nil {
+		settings.SystemPrompt = *opts.SystemPrompt
+	}
+	if opts.Temperature != nil {
+		settings.Temperature = opts.Temperature
+	}
+	if opts.TopP != nil {
+		settings.TopP = opts.TopP
+	}
+	if opts.MaxTokens != nil {
+		settings.MaxTokens = opts.MaxTokens
+	}
+	settings.Model = defaultModel(settings.Model)
+	return settings
+}
+
+// workspaceSettings fills what the conversation settings leave unset from the workspace defaults.
+// Without a model, the first model the workspace allows is used.
+func workspaceSettings(ws *models.Workspace, settings models.ConversationSettings) models.ConversationSettings {
+	if ws == nil {
+		return settings
+	}
+	defaults := ws.DefaultSettings
+	if settings.SystemPrompt == "" {
+		settings.SystemPrompt = defaults.SystemPrompt
+	}
+	if settings.Model == "" {
+		settings.Model = defaults.Model
+	}
+	if settings.Model == "" && len(ws.AllowedModels) > 0 {
+		settings.Model = ws.AllowedModels[0]
+	}
+	if settings.Temperature == nil {
+		settings.Temperature = defaults.Temperature
+	}
+	if settings.TopP == nil {
+		settings.TopP = defaults.TopP
+	}
+	if settings.MaxTokens == nil {
+		settings.MaxTokens = defaults.MaxTokens
+	}
+	if settings.Tools == nil {
+		settings.Tools = defaults.Tools
+	}
+	return settings
+}
+
+// allowModel checks a model against the allowlist of a workspace, if any
+func allowModel(ws *models.Workspace, model string) error {
+	if ws == nil || len(ws.AllowedModels) == 0 {
+		return nil
+	}
+	for _, allowed := range ws.AllowedModels {
+		if allowed == model {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: model %q is not allowed in this workspace", ErrInvalidSettings, model)
+}
+
+// maxOutputTokens is the most a completion with settings may generate
+func maxOutputTokens(settings models.ConversationSettings) int64 {
+	if settings.MaxTokens != nil {
+		return *settings.MaxTokens
+	}
+	return fallbackMaxTokens
+}
+
+// completionParams builds the SDK request from resolved settings
+func completionParams(settings models.ConversationSettings, messages []openai.ChatCompletionMessageParamUnion) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Model:     settings.Model,
+		Messages:  messages,
+		MaxTokens: openai.Int(maxOutputTokens(settings)),
+	}
+	if settings.Temperature != nil {
+		params.Temperature = openai.Float(*settings.Temperature)
+	}
+	if settings.TopP != nil {
+		params.TopP = openai.Float(*settings.TopP)
+	}
+
+	for _, tool := range settings.Tools {
+		fn := shared.FunctionDefinitionParam{
+			Name:       tool.Name,
+			Parameters: tool.Parameters,
+		}
+		if tool.Description != "" {
+			fn.Description = openai.String(tool.Description)
+		}
+		params.Tools = append(params.Tools, openai.ChatCompletionToolParam{Function: fn})
+	}
+	return params
+}
+
+// validateSettings checks the ranges of any settings that are present
+func validateSettings(settings models.ConversationSettings) error {
+	if t := settings.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return fmt.Errorf("%w: temperature must be between 0 and 2", ErrInvalidSettings)
+	}
+	if p := settings.TopP; p != nil && (*p <= 0 || *p > 1) {
+		return fmt.Errorf("%w: top_p must be greater than 0 and at most 1", ErrInvalidSettings)
+	}
+	if m := settings.MaxTokens; m != nil && *m <= 0 {
+		return fmt.Errorf("%w: max_tokens must be positive", ErrInvalidSettings)
+	}
+
+	seen := make(map[string]bool, len(settings.Tools))
+	for _, tool := range settings.Tools {
+		name := strings.TrimSpace(tool.Name)
+		if name == "" {
+			return fmt.Errorf("%w: tool name is required", ErrInvalidSettings)
+		}
+		if seen[name] {
+			return fmt.Errorf("%w: duplicate tool %q", ErrInvalidSettings, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// fitMaxTokens checks that the max_tokens of settings, if set, leaves room for a prompt
+// in the context window of model
+func fitMaxTokens(model models.ModelInfo, settings models.ConversationSettings) error {
+	if settings.MaxTokens == nil || model.ContextWindow == 0 {
+		return nil
+	}
+	if *settings.MaxTokens >= int64(model.ContextWindow) {
+		return fmt.Errorf("%w: max_tokens must be less than the %d token context window of %s", ErrInvalidSettings, model.ContextWindow, model.ID)
+	}
+	return nil
+}
+
+// mergeSettings applies a patch to settings the way the repository stores it: the keys
+// of the patch replace those of settings, and keys set to null are removed
+func mergeSettings(settings models.ConversationSettings, patch json.RawMessage) (models.ConversationSettings, error) {
+	stored, err := json.Marshal(settings)
+	if err != nil {
+		return models.ConversationSettings{}, err
+	}
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal(stored, &doc); err != nil {
+		return models.ConversationSettings{}, err
+	}
+	var changes map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return models.ConversationSettings{}, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	for key, value := range changes {
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			delete(doc, key)
+			continue
+		}
+		doc[key] = value
+	}
+
+	merged, err := json.Marshal(doc)
+	if err != nil {
+		return models.ConversationSettings{}, err
+	}
+	var out models.ConversationSettings
+	if err := json.Unmarshal(merged, &out); err != nil {
+		return models.ConversationSettings{}, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	return out, nil
+}
+
+// decodeSettingsPatch parses and validates a partial settings document
+func decodeSettingsPatch(patch json.RawMessage) (models.ConversationSettings, error) {
+	var settings models.ConversationSettings
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&settings); err != nil {
+		return models.ConversationSettings{}, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	return settings, validateSettings(settings)
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+	"github.com/openai/openai-go"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	"github.com/typescript-any/llm-playground/internal/tokenizer"
+)
+
+const (
+	summaryMaxTokens = 600
+	summaryTimeout   = time.Minute
+)
+
+const summaryPrompt = `You maintain a running summary of a conversation between a user and an assistant.
+You receive the current summary (possibly empty) and the turns that happened after it.
+Rewrite the summary so it also covers the new turns. Keep facts, decisions, user preferences,
+code or data the user shared, and open questions. Drop pleasantries.
+Reply with the updated summary only, in at most 300 words.`
+
+// SummaryConfig tunes when older turns are folded into the summary
+type SummaryConfig struct {
+	Model      string
+	KeepRecent int // turns always sent verbatim
+	BatchSize  int // minimum number of turns folded at once
+}
+
+// summaryStore is the part of SummaryRepo that SummaryService needs
+type summaryStore interface {
+	GetSummary(ctx context.Context, convID uuid.UUID) (models.Summary, error)
+	UpsertSummary(ctx context.Context, params repository.SummaryUpsertParams) (models.Summary, error)
+	UpdateSummaryContent(ctx context.Context, convID uuid.UUID, content string) (models.Summary, error)
+}
+
+type SummaryService struct {
+	repo        summaryStore
+	messageRepo messageStore
+	access      *Access
+	client      *openai.Client
+	cfg         SummaryConfig
+	billing
+
+	// refreshing guards against concurrent refreshes of the same conversation
+	refreshing sync.Map
+}
+
+func NewSummaryService(repo *repository.SummaryRepo, messageRepo *repository.MessageRepo, access *Access, keys *ProviderKeyService, quotas *QuotaService, usage *UsageService, client *openai.Client, cfg SummaryConfig) *SummaryService {
+	return &SummaryService{
+		repo:        repo,
+		messageRepo: messageRepo,
+		access:      access,
+		client:      client,
+		cfg:         cfg,
+		billing:     billing{keys: keys, quotas: quotas, usage: usage},
+	}
+}
+
+// GetSummary returns the stored summary of a conversation of userID
+func (s *SummaryService) GetSummary(ctx context.Context, userID, convID uuid.UUID) (models.Summary, error) {
+	if _, err := s.access.Conversation(ctx, userID, convID, PermissionRead); err != nil {
+		return models.Summary{}, err
+	}
+	return s.repo.GetSummary(ctx, convID)
+}
+
+// UpdateSummary replaces the summary text. Later refreshes build on the edited text.
+func (s *SummaryService) UpdateSummary(ctx context.Context, params SummaryUpdateParams) (models.Summary, error) {
+	content := strings.TrimSpace(params.Content)
+	if content == "" {
+		return models.Summary{}, fmt.Errorf("%w: summary cannot be empty", ErrInvalidInput)
+	}
+	if _, err := s.access.Conversation(ctx, params.UserID, params.ConversationID, PermissionWrite); err != nil {
+		return models.Summary{}, err
+	}
+	return s.repo.UpdateSummaryContent(ctx, params.ConversationID, content)
+}
+
+// summaryFor returns the summary used when assembling history, or nil if there is none
+func (s *SummaryService) summaryFor(ctx context.Context, convID uuid.UUID) (*models.Summary, error) {
+	summary, err := s.repo.GetSummary(ctx, convID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// Schedule refreshes the summary in the background
+func (s *SummaryService) Schedule(convID uuid.UUID) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+		defer cancel()
+
+		if err := s.Refresh(ctx, convID); err != nil {
+			log.Errorf("Error refreshing summary for %s: %v", convID, err)
+		}
+	}()
+}
+
+// Refresh folds turns older than the KeepRecent window into the summary once
+// at least BatchSize of them have accumulated.
+func (s *SummaryService) Refresh(ctx context.Context, convID uuid.UUID) error {
+	if _, busy := s.refreshing.LoadOrStore(convID, struct{}{}); busy {
+		return nil
+	}
+	defer s.refreshing.Delete(convID)
+
+	current, err := s.summaryFor(ctx, convID)
+	if err != nil {
+		return err
+	}
+
+	params := repository.MessageListParams{
+		ConversationID: convID,
+		Limit:          s.cfg.KeepRecent + 4*s.cfg.BatchSize,
+	}
+	if current != nil {
+		params.After = &current.SummarizedUntil
+	}
+	pending, err := s.messageRepo.GetMessagesByConversation(ctx, params)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	foldCount := len(pending) - s.cfg.KeepRecent
+	if foldCount < s.cfg.BatchSize {
+		return nil
+	}
+	fold := pending[:foldCount]
+
+	call, err := s.summaryCall(ctx, convID)
+	if err != nil {
+		return err
+	}
+	content, err := s.summarize(ctx, call, current, fold)
+	if errors.Is(err, ErrQuotaExceeded) {
+		// The summary catches up once the budget allows it; replies fit the prompt without it
+		log.Infof("Skipping summary refresh for %s: %v", convID, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	upsert := repository.SummaryUpsertParams{
+		ConversationID:  convID,
+		Content:         content,
+		SummarizedUntil: fold[len(fold)-1].CreatedAt,
+		MessageCount:    len(fold),
+	}
+	if current != nil {
+		upsert.MessageCount += current.MessageCount
+	}
+	_, err = s.repo.UpsertSummary(ctx, upsert)
+	return err
+}
+
+// summaryCall prepares the call refreshing the summary of a conversation. It is paid for
+// like a message of the conversation's owner: with the provider key of its organization
+// or of the owner, and out of their quotas.
+func (s *SummaryService) summaryCall(ctx context.Context, convID uuid.UUID) (*completionCall, error) {
+	conv, err := s.access.conversations.GetConversationByID(ctx, convID)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := s.access.workspace(ctx, conv)
+	if err != nil {
+		return nil, err
+	}
+
+	maxTokens := int64(summaryMaxTokens)
+	call := &completionCall{
+		userID:   conv.UserID,
+		convID:   convID,
+		kind:     models.UsageKindSummary,
+		settings: models.ConversationSettings{Model: defaultModel(s.cfg.Model), MaxTokens: &maxTokens},
+		ws:       ws,
+	}
+	if call.reqOpts, err = s.requestOptions(ctx, conv.UserID, ws); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+// summarize asks the summary model to merge new turns into the current summary.
+// It fails with ErrQuotaExceeded, without calling the model, past the budget of the call.
+func (s *SummaryService) summarize(ctx context.Context, call *completionCall, current *models.Summary, turns []models.Message) (string, error) {
+	var b strings.Builder
+	b.WriteString("Current summary:\n")
+	if current != nil {
+		b.WriteString(current.Content)
+	} else {
+		b.WriteString("(none)")
+	}
+	b.WriteString("\n\nNew turns:\n")
+	for _, m := range turns {
+		fmt.Fprintf(&b, "%s: %s\n\n", m.Role, m.Content)
+	}
+
+	tok := tokenizer.For(call.settings.Model)
+	promptTokens := tok.Message(summaryPrompt) + tok.Message(b.String()) + tokenizer.ReplyPriming
+	reservation, err := s.reserve(ctx, call, int64(promptTokens))
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(summaryPrompt),
+			openai.UserMessage(b.String()),
+		},
+		Model:       call.settings.Model,
+		MaxTokens:   openai.Int(*call.settings.MaxTokens),
+		Temperature: openai.Float(0.2),
+	}, call.reqOpts...)
+	if err != nil {
+		s.settle(context.Background(), call, reservation, 0, 0)
+		return "", fmt.Errorf("failed to summarize: %w", err)
+	}
+	s.settle(context.Background(), call, reservation, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("failed to summarize: empty response")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
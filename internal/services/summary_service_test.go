@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+// newTestSummaries returns a SummaryService folding the turns of a conversation of owner
+// that holds four turns, so that the two oldest are due for the summary
+func newTestSummaries(t *testing.T, owner uuid.UUID, response string) (*SummaryService, uuid.UUID, fakeSummaries, *atomic.Int32) {
+	access, convID := newTestAccess(owner)
+	messages := &fakeMessages{messages: map[uuid.UUID]models.Message{}}
+	start := time.Date(2025, 3, 14, 12, 0, 0, 0, time.UTC)
+	for i, role := range []string{models.RoleUser, models.RoleAssistant, models.RoleUser, models.RoleAssistant} {
+		m := models.Message{ID: uuid.New(), ConversationID: convID, Role: role, Content: "turn", CreatedAt: start.Add(time.Duration(i) * time.Minute)}
+		messages.messages[m.ID] = m
+	}
+	client, calls := newTestCompletions(t, response)
+	quotas, _ := newTestQuotas()
+	usage, _ := newTestUsage()
+	summaries := fakeSummaries{}
+	return &SummaryService{
+		repo:        summaries,
+		messageRepo: messages,
+		access:      access,
+		client:      client,
+		cfg:         SummaryConfig{Model: "big", KeepRecent: 2, BatchSize: 2},
+		billing:     billing{quotas: quotas, usage: usage},
+	}, convID, summaries, calls
+}
+
+func TestSummaryRefreshChargesOwner(t *testing.T) {
+	owner := uuid.New()
+	s, convID, summaries, _ := newTestSummaries(t, owner, `{"id": "x", "object": "chat.completion", "model": "big", "choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "They said hello."}}], "usage": {"prompt_tokens": 90, "completion_tokens": 10, "total_tokens": 100}}`)
+	s.quotas.repo.(*fakeQuotas).quotas["default"] = models.Quota{DailyTokens: ptr(int64(10000))}
+	ctx := context.Background()
+
+	if err := s.Refresh(ctx, convID); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if summary := summaries[convID]; summary.Content != "They said hello." || summary.MessageCount != 2 {
+		t.Fatalf("summary = %+v, want the two oldest turns folded", summary)
+	}
+
+	status, _ := s.quotas.Status(ctx, owner)
+	if status.Daily.Tokens != 100 {
+		t.Fatalf("owner charged %d tokens, want 100", status.Daily.Tokens)
+	}
+	events := s.usage.repo.(*fakeUsage).events
+	if len(events) != 1 || events[0].Kind != models.UsageKindSummary || *events[0].UserID != owner {
+		t.Fatalf("recorded %+v, want one summary call of the owner", events)
+	}
+}
+
+func TestSummaryRefreshOverQuota(t *testing.T) {
+	owner := uuid.New()
+	s, convID, summaries, calls := newTestSummaries(t, owner, `{}`)
+	s.quotas.repo.(*fakeQuotas).quotas["user:"+owner.String()] = models.Quota{DailyTokens: ptr(int64(10))}
+
+	if err := s.Refresh(context.Background(), convID); err != nil {
+		t.Fatalf("refresh over quota = %v, want it skipped", err)
+	}
+	if _, ok := summaries[convID]; ok || calls.Load() != 0 {
+		t.Fatalf("summary model called %d times past the quota, want none", calls.Load())
+	}
+	if events := s.usage.repo.(*fakeUsage).events; len(events) != 0 {
+		t.Fatalf("recorded %+v, want no call", events)
+	}
+}
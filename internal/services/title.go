@@ -0,0 +1,51 @@
+package service
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// PlaceholderTitle is stored until the generated title is available
+const PlaceholderTitle = "New Conversation"
+
+const (
+	titleMaxTokens = 20
+	titleMaxRunes  = 80
+)
+
+const titlePrompt = `You generate very short conversation titles.
+Reply with the title only: at most six words, no quotes, no trailing punctuation.
+
+Example:
+User: How can I format dates in React?
+Formatting Dates in React`
+
+// cleanTitle normalizes raw model output into a usable title.
+// It keeps the first non-empty line, drops "Title:" prefixes, wrapping quotes
+// and markdown, and truncates overly long titles on a word boundary.
+func cleanTitle(raw string) string {
+	var title string
+	for _, line := range strings.Split(raw, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			title = line
+			break
+		}
+	}
+
+	title = strings.TrimLeft(title, "#*_ ")
+	if len(title) >= 6 && strings.EqualFold(title[:6], "title:") {
+		title = title[6:]
+	}
+	title = strings.Trim(title, " \t\"'`*_“”‘’")
+	title = strings.TrimRight(title, ".!?:;, ")
+	title = strings.Join(strings.Fields(title), " ")
+
+	if utf8.RuneCountInString(title) > titleMaxRunes {
+		runes := []rune(title)[:titleMaxRunes]
+		title = string(runes)
+		if i := strings.LastIndex(title, " "); i > 0 {
+			title = title[:i]
+		}
+	}
+	return title
+}
@@ -1,43 +1,295 @@
 package service
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"time"
 
-// ConversationCreateParams holds parameters for creating a conversation
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+// ConversationCreateParams holds parameters for creating a conversation.
+// With WorkspaceID the conversation is shared in that workspace.
 type ConversationCreateParams struct {
-	UserID uuid.UUID
-	Title  string
+	UserID      uuid.UUID
+	WorkspaceID *uuid.UUID
+	Title       string
 }
 
-// ConversationListParams holds parameters for listing conversations
+// ConversationListParams holds parameters for listing conversations.
+// Query searches the titles.
 type ConversationListParams struct {
-	UserID uuid.UUID
-	Offset int
-	Limit  int
+	UserID      uuid.UUID
+	WorkspaceID *uuid.UUID
+	Query       string
+	Offset      int
+	Limit       int
 }
 
 // ConversationNewParams holds parameters for creating a new conversation with AI-generated title
 type ConversationNewParams struct {
-	UserID  uuid.UUID
-	Content string
-	Model   string
+	UserID      uuid.UUID
+	WorkspaceID *uuid.UUID
+	Content     string
+}
+
+// ConversationUpdateParams holds parameters for updating a conversation.
+// SettingsPatch is merged into the stored settings; null keys are cleared.
+type ConversationUpdateParams struct {
+	UserID        uuid.UUID
+	ID            uuid.UUID
+	Title         *string
+	SettingsPatch json.RawMessage
+}
+
+// GenerationOptions holds per-request overrides of the conversation settings
+type GenerationOptions struct {
+	Model        string
+	SystemPrompt *string
+	Temperature  *float64
+	TopP         *float64
+	MaxTokens    *int64
 }
 
 // MessageSendParams holds parameters for sending a message
 type MessageSendParams struct {
+	UserID         uuid.UUID
 	ConversationID uuid.UUID
 	Content        string
-	Model          string
+	GenerationOptions
 }
 
 // MessageStreamParams holds parameters for streaming a message
 type MessageStreamParams struct {
+	UserID         uuid.UUID
 	ConversationID uuid.UUID
 	Content        string
-	Model          string
+	GenerationOptions
+}
+
+// MessageRegenerateParams holds parameters for regenerating the last reply
+type MessageRegenerateParams struct {
+	UserID         uuid.UUID
+	ConversationID uuid.UUID
+	GenerationOptions
 }
 
-// MessageSaveParams holds parameters for saving an assistant message
+// MessageSaveParams holds parameters for saving an assistant message.
+// ID is the id announced when the stream started.
+// Partial replies carry an interrupted or error Status and the Error detail.
 type MessageSaveParams struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+	Content        string
+	FinishReason   string
+	Status         string
+	Error          string
+}
+
+// SummaryUpdateParams holds parameters for editing a conversation summary
+type SummaryUpdateParams struct {
+	UserID         uuid.UUID
 	ConversationID uuid.UUID
 	Content        string
 }
+
+// ReplayCreateParams holds parameters for replaying a conversation against another model
+type ReplayCreateParams struct {
+	UserID   uuid.UUID
+	SourceID uuid.UUID
+	GenerationOptions
+}
+
+// ReplayPlan is a freshly created replay conversation and the user turns to re-send into it
+type ReplayPlan struct {
+	Source       models.Conversation
+	Conversation models.Conversation
+	Turns        []models.Message
+}
+
+// MessageListParams holds parameters for listing the messages of a conversation
+type MessageListParams struct {
+	UserID         uuid.UUID
+	ConversationID uuid.UUID
+	Limit          int
+}
+
+// MessagePinParams holds parameters for pinning or unpinning a message
+type MessagePinParams struct {
+	UserID         uuid.UUID
+	ConversationID uuid.UUID
+	MessageID      uuid.UUID
+	Pinned         bool
+}
+
+// UserRegisterParams holds parameters for creating an account
+type UserRegisterParams struct {
+	Email    string
+	Name     string
+	Password string
+}
+
+// UserLoginParams holds the credentials of a login
+type UserLoginParams struct {
+	Email    string
+	Password string
+}
+
+// UserUpdateParams holds parameters for updating a profile.
+// NewPassword is only applied when CurrentPassword matches.
+type UserUpdateParams struct {
+	ID              uuid.UUID
+	Name            *string
+	CurrentPassword string
+	NewPassword     *string
+}
+
+// AuthSession is a logged in user and the bearer token that authenticates it
+type AuthSession struct {
+	User      models.User `json:"user"`
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	User     models.User
+	Roles    []string  // granted by the identity provider, empty for login tokens
+	Method   string    // one of the AuthMethod constants
+	Scopes   []string  // what an API key may do; other methods may do everything
+	APIKeyID uuid.UUID // set when authenticated with an API key
+}
+
+// HasScope reports whether the identity may act within scope. Only API keys are limited.
+func (i Identity) HasScope(scope string) bool {
+	if i.Method != AuthMethodAPIKey {
+		return true
+	}
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the identity was granted role
+func (i Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyCreateParams holds parameters for issuing an API key
+type APIKeyCreateParams struct {
+	UserID    uuid.UUID
+	Name      string
+	Kind      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// CreatedAPIKey is a newly issued key. Key is the full secret, shown only once.
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// MemberInviteParams holds parameters for adding an account to an organization.
+// Role defaults to member.
+type MemberInviteParams struct {
+	ActorID uuid.UUID
+	OrgID   uuid.UUID
+	Email   string
+	Role    string
+}
+
+// MemberUpdateParams holds parameters for changing the role of a member
+type MemberUpdateParams struct {
+	ActorID uuid.UUID
+	OrgID   uuid.UUID
+	UserID  uuid.UUID
+	Role    string
+}
+
+// WorkspaceCreateParams holds parameters for creating a workspace
+type WorkspaceCreateParams struct {
+	UserID          uuid.UUID
+	OrgID           uuid.UUID
+	Name            string
+	AllowedModels   []string
+	DefaultSettings models.ConversationSettings
+}
+
+// WorkspaceUpdateParams holds parameters for updating a workspace.
+// Nil fields are left unchanged; an empty AllowedModels allows every model.
+type WorkspaceUpdateParams struct {
+	UserID          uuid.UUID
+	ID              uuid.UUID
+	Name            *string
+	AllowedModels   []string
+	DefaultSettings *models.ConversationSettings
+}
+
+// ModelSaveParams holds a model of the registry, with prices in USD per million tokens
+type ModelSaveParams struct {
+	ID            string
+	InputPrice    float64
+	OutputPrice   float64
+	ContextWindow int
+}
+
+// QuotaSetParams holds the limits of a user or, with OrgID, of an organization; with
+// neither they are the default of every user. Nil limits do not apply.
+type QuotaSetParams struct {
+	UserID         *uuid.UUID
+	OrgID          *uuid.UUID
+	DailyTokens    *int64
+	MonthlyTokens  *int64
+	DailyCost      *float64
+	MonthlyCost    *float64
+	DowngradeModel *string
+}
+
+// QuotaReserveParams holds the estimated usage of a call of UserID, in a workspace of
+// OrgID if set
+type QuotaReserveParams struct {
+	UserID           uuid.UUID
+	OrgID            *uuid.UUID
+	Model            string
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// UsageRecordParams holds one LLM call, in a conversation and a workspace of OrgID when set
+type UsageRecordParams struct {
+	UserID           *uuid.UUID
+	OrgID            *uuid.UUID
+	ConversationID   *uuid.UUID
+	Model            string
+	Kind             string
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// UsageReportParams asks CallerID for the usage in [From, To) aggregated by GroupBy,
+// narrowed to UserID and OrgID when set. Only admins see the usage of others.
+type UsageReportParams struct {
+	CallerID uuid.UUID
+	Admin    bool
+	From     time.Time
+	To       time.Time
+	GroupBy  []string
+	UserID   *uuid.UUID
+	OrgID    *uuid.UUID
+}
+
+// UsageReport holds the rows of a usage report and the groups they are aggregated by,
+// in order and without duplicates
+type UsageReport struct {
+	GroupBy []string
+	Rows    []models.UsageRow
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// maxUsageReportDays caps the range of a usage report
+const maxUsageReportDays = 366
+
+// usageStore is the part of UsageRepo that usage accounting needs
+type usageStore interface {
+	RecordUsage(ctx context.Context, params repository.UsageRecordParams) error
+	UsageReport(ctx context.Context, params repository.UsageReportParams) ([]models.UsageRow, error)
+}
+
+// UsageService records every LLM call with what it cost and reports the totals
+type UsageService struct {
+	repo   usageStore
+	models *ModelService
+}
+
+func NewUsageService(repo *repository.UsageRepo, modelService *ModelService) *UsageService {
+	return &UsageService{
+		repo:   repo,
+		models: modelService,
+	}
+}
+
+// Record stores a call priced at the current registry prices of its model. The call
+// has been made by then, so failures are logged rather than returned.
+func (s *UsageService) Record(ctx context.Context, params UsageRecordParams) {
+	if params.PromptTokens == 0 && params.CompletionTokens == 0 {
+		return
+	}
+	model, err := s.models.lookup(ctx, params.Model)
+	if err != nil {
+		log.Errorf("Error pricing usage of %s: %v", params.Model, err)
+	}
+	err = s.repo.RecordUsage(ctx, repository.UsageRecordParams{
+		UserID:           params.UserID,
+		OrgID:            params.OrgID,
+		ConversationID:   params.ConversationID,
+		Model:            params.Model,
+		Kind:             params.Kind,
+		PromptTokens:     params.PromptTokens,
+		CompletionTokens: params.CompletionTokens,
+		Cost:             model.Cost(params.PromptTokens, params.CompletionTokens),
+	})
+	if err != nil {
+		log.Errorf("Error recording usage of %s: %v", params.Model, err)
+	}
+}
+
+// Report sums the calls of a range per group. Admins see everyone's usage; others
+// only their own.
+func (s *UsageService) Report(ctx context.Context, params UsageReportParams) (UsageReport, error) {
+	if !params.To.After(params.From) {
+		return UsageReport{}, fmt.Errorf("%w: the end of the range must come after its start", ErrInvalidInput)
+	}
+	if params.To.Sub(params.From) > maxUsageReportDays*24*time.Hour {
+		return UsageReport{}, fmt.Errorf("%w: the range cannot exceed %d days", ErrInvalidInput, maxUsageReportDays)
+	}
+	var groups []string
+	for _, g := range params.GroupBy {
+		if !slices.Contains(models.UsageGroups, g) {
+			return UsageReport{}, fmt.Errorf("%w: usage can be grouped by %v", ErrInvalidInput, models.UsageGroups)
+		}
+		if !slices.Contains(groups, g) {
+			groups = append(groups, g)
+		}
+	}
+
+	if !params.Admin {
+		if params.OrgID != nil || (params.UserID != nil && *params.UserID != params.CallerID) {
+			return UsageReport{}, ErrForbidden
+		}
+		params.UserID = &params.CallerID
+	}
+	rows, err := s.repo.UsageReport(ctx, repository.UsageReportParams{
+		From:    params.From.UTC(),
+		To:      params.To.UTC(),
+		GroupBy: groups,
+		UserID:  params.UserID,
+		OrgID:   params.OrgID,
+	})
+	if err != nil {
+		return UsageReport{}, err
+	}
+	return UsageReport{GroupBy: groups, Rows: rows}, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+)
+
+// fakeUsage keeps recorded calls and the last report asked for in memory
+type fakeUsage struct {
+	events []repository.UsageRecordParams
+	report *repository.UsageReportParams
+}
+
+func (f *fakeUsage) RecordUsage(ctx context.Context, params repository.UsageRecordParams) error {
+	f.events = append(f.events, params)
+	return nil
+}
+
+func (f *fakeUsage) UsageReport(ctx context.Context, params repository.UsageReportParams) ([]models.UsageRow, error) {
+	f.report = &params
+	return []models.UsageRow{}, nil
+}
+
+func newTestUsage() (*UsageService, *fakeUsage) {
+	store := &fakeUsage{}
+	registry := fakeModels{"big": {ID: "big", InputPrice: 10, OutputPrice: 30}}
+	return &UsageService{repo: store, models: &ModelService{repo: registry}}, store
+}
+
+func TestUsageRecordPrices(t *testing.T) {
+	usage, store := newTestUsage()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	usage.Record(ctx, UsageRecordParams{UserID: &userID, Model: "big", Kind: models.UsageKindChat, PromptTokens: 1000, CompletionTokens: 500})
+	usage.Record(ctx, UsageRecordParams{UserID: &userID, Model: "unknown", Kind: models.UsageKindChat, PromptTokens: 1000})
+	usage.Record(ctx, UsageRecordParams{UserID: &userID, Model: "big", Kind: models.UsageKindChat})
+
+	if len(store.events) != 2 {
+		t.Fatalf("recorded %d calls, want 2 (calls without tokens are skipped)", len(store.events))
+	}
+	if want := (1000*10 + 500*30) / 1e6; math.Abs(store.events[0].Cost-want) > 1e-12 {
+		t.Fatalf("cost = %v, want %v", store.events[0].Cost, want)
+	}
+	if store.events[1].Cost != 0 {
+		t.Fatalf("a model missing from the registry cost %v", store.events[1].Cost)
+	}
+}
+
+func TestUsageReportAccess(t *testing.T) {
+	usage, store := newTestUsage()
+	ctx := context.Background()
+	caller, other, orgID := uuid.New(), uuid.New(), uuid.New()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	params := UsageReportParams{CallerID: caller, From: from, To: from.AddDate(0, 1, 0), GroupBy: []string{"model", "day", "model"}}
+
+	report, err := usage.Report(ctx, params)
+	if err != nil {
+		t.Fatalf("own report failed: %v", err)
+	}
+	if store.report.UserID == nil || *store.report.UserID != caller {
+		t.Fatalf("report of a non-admin was not narrowed to them: %+v", store.report)
+	}
+	if len(store.report.GroupBy) != 2 || !slices.Equal(report.GroupBy, []string{"model", "day"}) {
+		t.Fatalf("groups = %v, reported as %v, want duplicates dropped", store.report.GroupBy, report.GroupBy)
+	}
+
+	forbidden := []UsageReportParams{params, params}
+	forbidden[0].UserID = &other
+	forbidden[1].OrgID = &orgID
+	for _, p := range forbidden {
+		if _, err := usage.Report(ctx, p); !errors.Is(err, ErrForbidden) {
+			t.Fatalf("report on others: err = %v, want ErrForbidden", err)
+		}
+	}
+
+	admin := forbidden[1]
+	admin.Admin = true
+	if _, err := usage.Report(ctx, admin); err != nil {
+		t.Fatalf("admin report failed: %v", err)
+	}
+	if store.report.UserID != nil || store.report.OrgID == nil {
+		t.Fatalf("admin report was narrowed: %+v", store.report)
+	}
+}
+
+func TestUsageReportValidates(t *testing.T) {
+	usage, _ := newTestUsage()
+	ctx := context.Background()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	invalid := map[string]UsageReportParams{
+		"reversed range": {From: from, To: from},
+		"too long":       {From: from, To: from.AddDate(2, 0, 0)},
+		"unknown group":  {From: from, To: from.AddDate(0, 0, 1), GroupBy: []string{"workspace"}},
+	}
+	for name, p := range invalid {
+		if _, err := usage.Report(ctx, p); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("%s: err = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
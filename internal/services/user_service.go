@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+// dummyHash is compared against when an email is unknown, so a failed login takes
+// as long whether or not the account exists
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+type UserService struct {
+	repo       *repository.UserRepo
+	sessionTTL time.Duration
+}
+
+func NewUserService(repo *repository.UserRepo, sessionTTL time.Duration) *UserService {
+	return &UserService{
+		repo:       repo,
+		sessionTTL: sessionTTL,
+	}
+}
+
+// Register creates an account and logs it in
+func (s *UserService) Register(ctx context.Context, params UserRegisterParams) (AuthSession, error) {
+	email := strings.TrimSpace(params.Email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return AuthSession{}, fmt.Errorf("%w: invalid email", ErrInvalidInput)
+	}
+	if len(params.Password) < minPasswordLength {
+		return AuthSession{}, fmt.Errorf("%w: password must be at least %d characters", ErrInvalidInput, minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return AuthSession{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	user, err := s.repo.CreateUser(ctx, repository.UserCreateParams{
+		Email:        email,
+		Name:         strings.TrimSpace(params.Name),
+		PasswordHash: string(hash),
+	})
+	if err != nil {
+		return AuthSession{}, err
+	}
+
+	return s.newSession(ctx, user)
+}
+
+// Login checks a password and issues a bearer token
+func (s *UserService) Login(ctx context.Context, params UserLoginParams) (AuthSession, error) {
+	user, err := s.repo.GetUserByEmail(ctx, strings.TrimSpace(params.Email))
+	if errors.Is(err, repository.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(params.Password))
+		return AuthSession{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return AuthSession{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(params.Password)) != nil {
+		return AuthSession{}, ErrInvalidCredentials
+	}
+
+	s.repo.DeleteExpiredSessions(ctx, user.ID)
+	return s.newSession(ctx, user)
+}
+
+// Logout revokes a bearer token
+func (s *UserService) Logout(ctx context.Context, token string) error {
+	return s.repo.DeleteSession(ctx, hashToken(token))
+}
+
+// Authenticate resolves a bearer token into its user
+func (s *UserService) Authenticate(ctx context.Context, token string) (models.User, error) {
+	if token == "" {
+		return models.User{}, ErrUnauthenticated
+	}
+	user, err := s.repo.GetUserBySession(ctx, hashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.User{}, ErrUnauthenticated
+	}
+	return user, err
+}
+
+// GetProfile returns an account
+func (s *UserService) GetProfile(ctx context.Context, id uuid.UUID) (models.User, error) {
+	return s.repo.GetUserByID(ctx, id)
+}
+
+// UpdateProfile changes the name or the password of an account. A new password
+// requires the current one.
+func (s *UserService) UpdateProfile(ctx context.Context, params UserUpdateParams) (models.User, error) {
+	update := repository.UserUpdateParams{ID: params.ID}
+	if params.Name != nil {
+		name := strings.TrimSpace(*params.Name)
+		update.Name = &name
+	}
+
+	if params.NewPassword != nil {
+		if len(*params.NewPassword) < minPasswordLength {
+			return models.User{}, fmt.Errorf("%w: password must be at least %d characters", ErrInvalidInput, minPasswordLength)
+		}
+		user, err := s.repo.GetUserByID(ctx, params.ID)
+		if err != nil {
+			return models.User{}, err
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(params.CurrentPassword)) != nil {
+			return models.User{}, ErrInvalidCredentials
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(*params.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return models.User{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		hashed := string(hash)
+		update.PasswordHash = &hashed
+	}
+
+	return s.repo.UpdateUser(ctx, update)
+}
+
+// newSession issues a random bearer token for user. Only its hash is stored.
+func (s *UserService) newSession(ctx context.Context, user models.User) (AuthSession, error) {
+	var token string
+	// A token must never be mistaken for an API key
+	for token == "" || LooksLikeAPIKey(token) {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return AuthSession{}, err
+		}
+		token = base64.RawURLEncoding.EncodeToString(raw)
+	}
+	expiresAt := time.Now().Add(s.sessionTTL)
+
+	if err := s.repo.CreateSession(ctx, user.ID, hashToken(token), expiresAt); err != nil {
+		return AuthSession{}, err
+	}
+	return AuthSession{User: user, Token: token, ExpiresAt: expiresAt}, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
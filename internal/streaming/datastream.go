@@ -0,0 +1,142 @@
+package streaming
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/models"
+)
+
+// DataStreamMediaType selects the AI SDK data stream protocol in the Accept header
+const DataStreamMediaType = "text/x-vercel-ai-data-stream"
+
+// WantsDataStream reports whether the client asked for the AI SDK data stream protocol,
+// with the protocol=data query or the Accept header.
+func WantsDataStream(c *fiber.Ctx) bool {
+	return c.Query("protocol") == "data" || strings.Contains(c.Get(fiber.HeaderAccept), DataStreamMediaType)
+}
+
+// setDataStreamHeaders prepares the response for the AI SDK data stream protocol
+func setDataStreamHeaders(c *fiber.Ctx) {
+	c.Set("Content-Type", "text/plain; charset=utf-8")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("x-vercel-ai-data-stream", "v1")
+	c.Set("Access-Control-Allow-Origin", "*")
+}
+
+// dataStreamUsage is the usage object of finish parts
+type dataStreamUsage struct {
+	PromptTokens     int64 `json:"promptTokens"`
+	CompletionTokens int64 `json:"completionTokens"`
+}
+
+// dataStreamFormat encodes events as AI SDK data stream parts, one "<code>:<json>" line
+// each. Replies are steps of one message: a replay has a step per turn. Events without a
+// part of their own, such as message_start or conversation_title, are sent as data parts.
+type dataStreamFormat struct {
+	usage dataStreamUsage // of the current step
+	total dataStreamUsage // of the whole message
+}
+
+func (f *dataStreamFormat) encode(w *bufio.Writer, _ *generation.Generation, ev generation.Event) (int, bool) {
+	n := 0
+	part := func(code string, v any) {
+		data, _ := json.Marshal(v)
+		m, _ := fmt.Fprintf(w, "%s:%s\n", code, data)
+		n += m
+	}
+
+	switch EventType(ev.Name) {
+	case EventContentDelta:
+		var p ContentDelta
+		json.Unmarshal(ev.Data, &p)
+		part("0", p.Delta.Value)
+		return n, false
+	case EventToolCall:
+		var p ToolCall
+		json.Unmarshal(ev.Data, &p)
+		args := json.RawMessage(p.Arguments)
+		if !json.Valid(args) {
+			args = json.RawMessage("{}")
+		}
+		part("9", map[string]any{"toolCallId": p.ToolCallID, "toolName": p.Name, "args": args})
+	case EventUsage:
+		var p Usage
+		json.Unmarshal(ev.Data, &p)
+		f.usage = dataStreamUsage{PromptTokens: p.PromptTokens, CompletionTokens: p.CompletionTokens}
+		f.total.PromptTokens += p.PromptTokens
+		f.total.CompletionTokens += p.CompletionTokens
+		return n, false
+	case EventMessageStart:
+		var p MessageStart
+		json.Unmarshal(ev.Data, &p)
+		part("2", []json.RawMessage{ev.Data})
+		part("f", map[string]string{"messageId": p.AssistantMessageID})
+	case EventTurnStart:
+		var p TurnStart
+		json.Unmarshal(ev.Data, &p)
+		part("2", []json.RawMessage{ev.Data})
+		part("f", map[string]string{"messageId": p.AssistantMessageID})
+	case EventMessageComplete:
+		var p MessageComplete
+		json.Unmarshal(ev.Data, &p)
+		f.finishStep(part, p.FinishReason)
+		f.finishMessage(part, p.FinishReason)
+	case EventTurnComplete:
+		var p TurnComplete
+		json.Unmarshal(ev.Data, &p)
+		f.finishStep(part, p.FinishReason)
+	case EventReplayComplete:
+		var p ReplayComplete
+		json.Unmarshal(ev.Data, &p)
+		f.finishMessage(part, p.FinishReason)
+	case EventError:
+		var p Error
+		json.Unmarshal(ev.Data, &p)
+		part("3", p.Message)
+	default:
+		part("2", []json.RawMessage{ev.Data})
+	}
+	return n, true
+}
+
+func (f *dataStreamFormat) finishStep(part func(string, any), reason string) {
+	part("e", map[string]any{
+		"finishReason": dataStreamFinishReason(reason),
+		"usage":        f.usage,
+		"isContinued":  false,
+	})
+	f.usage = dataStreamUsage{}
+}
+
+func (f *dataStreamFormat) finishMessage(part func(string, any), reason string) {
+	part("d", map[string]any{
+		"finishReason": dataStreamFinishReason(reason),
+		"usage":        f.total,
+	})
+}
+
+func (*dataStreamFormat) heartbeat() string {
+	return ""
+}
+
+// dataStreamFinishReason maps a finish reason to the AI SDK's
+func dataStreamFinishReason(reason string) string {
+	switch reason {
+	case models.FinishReasonStop, "length", models.FinishReasonError:
+		return reason
+	case "tool_calls", "function_call":
+		return "tool-calls"
+	case "content_filter":
+		return "content-filter"
+	case "":
+		return "unknown"
+	default:
+		return "other"
+	}
+}
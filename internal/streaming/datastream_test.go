@@ -0,0 +1,45 @@
+package streaming
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/typescript-any/llm-playground/internal/generation"
+)
+
+func TestDataStreamFormat(t *testing.T) {
+	payloads := []Payload{
+		&MessageStart{AssistantMessageID: "m1"},
+		&ContentDelta{Delta: Delta{Type: "text_delta", Value: "Hi"}},
+		&ToolCall{ToolCallID: "c1", Name: "lookup", Arguments: `{"q":"x"}`},
+		&Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+		&MessageComplete{MessageID: "m1", FinishReason: "tool_calls", Status: "complete"},
+	}
+	events := make([]generation.Event, len(payloads))
+	for i, p := range payloads {
+		p.stamp(p.EventType())
+		data, _ := json.Marshal(p)
+		events[i] = generation.Event{ID: uint64(i + 1), Name: string(p.EventType()), Data: data}
+	}
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	out := newEventWriter(w, Options{}, &dataStreamFormat{})
+	defer out.stop()
+	if err := out.emit(nil, events); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `2:[` + string(events[0].Data) + `]
+f:{"messageId":"m1"}
+0:"Hi"
+9:{"args":{"q":"x"},"toolCallId":"c1","toolName":"lookup"}
+e:{"finishReason":"tool-calls","isContinued":false,"usage":{"promptTokens":3,"completionTokens":2}}
+d:{"finishReason":"tool-calls","usage":{"promptTokens":3,"completionTokens":2}}
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("unexpected data stream:\n%s\nwant:\n%s", got, want)
+	}
+}
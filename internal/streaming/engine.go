@@ -0,0 +1,274 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/models"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+// ErrUpstreamIdle is the cause of a provider stream aborted by the idle timeout
+var ErrUpstreamIdle = errors.New("provider stream stalled")
+
+// Engine runs assistant replies as generations and publishes them in the event schema.
+// Transports never read the upstream stream themselves; they follow generations.
+type Engine struct {
+	messages    *service.MessageService
+	generations *generation.Manager
+	limiter     *ratelimit.Limiter // caps the concurrent streams of a subject, nil for no cap
+	opts        Options
+}
+
+// Options tunes how replies are read from the provider and written to clients
+type Options struct {
+	// UpstreamIdleTimeout aborts a provider stream that sent nothing for this long, 0 to wait forever
+	UpstreamIdleTimeout time.Duration
+	// HeartbeatInterval is how long an SSE stream may stay silent before a comment is sent, 0 to disable
+	HeartbeatInterval time.Duration
+	// CoalesceWindow is how long SSE content deltas may wait to be flushed together, 0 to flush each one
+	CoalesceWindow time.Duration
+	// CoalesceBytes flushes buffered SSE content deltas early once this many bytes are waiting
+	CoalesceBytes int
+}
+
+func NewEngine(messages *service.MessageService, generations *generation.Manager, limiter *ratelimit.Limiter, opts Options) *Engine {
+	// Mirrors that miss relayed events end with an error that makes clients resume
+	lost := NewError(generation.ErrEventsLost)
+	lost.stamp(lost.EventType())
+	generations.SetLostEvent(string(lost.EventType()), lost)
+
+	return &Engine{
+		messages:    messages,
+		generations: generations,
+		limiter:     limiter,
+		opts:        opts,
+	}
+}
+
+// Opener opens the completion a reply streams from, using the generation's context
+type Opener func(ctx context.Context) (*service.MessageStream, error)
+
+// Hooks let a caller interleave its own events with a reply
+type Hooks struct {
+	AfterChunk     func(g *generation.Generation) // runs after every upstream chunk
+	BeforeComplete func(g *generation.Generation) // runs once the reply is persisted
+}
+
+// Outcome is the result of streaming one assistant reply
+type Outcome struct {
+	Message      *models.Message // persisted reply, partial if the stream did not complete
+	FinishReason string
+	Status       string
+	Err          error // upstream or persistence error, already recorded on the message if possible
+}
+
+// StartReply creates a generation, opens its completion and streams the reply in the background.
+// The generation holds a stream slot of subject until it finishes. Errors from open, and the
+// *ratelimit.Exceeded of a subject without a free slot, are returned before anything is
+// published so transports can report them their own way.
+func (e *Engine) StartReply(subject ratelimit.Subject, convID uuid.UUID, open Opener, hooks Hooks) (*generation.Generation, error) {
+	release, err := e.acquire(subject)
+	if err != nil {
+		return nil, err
+	}
+	g, err := e.generations.Create(convID)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	ms, err := open(g.Context())
+	if err != nil {
+		e.generations.Discard(g)
+		release()
+		return nil, err
+	}
+
+	e.generations.Run(g, func(g *generation.Generation) {
+		defer release()
+		e.Reply(g, ms, hooks)
+	})
+	return g, nil
+}
+
+// Start runs fn as a generation, for flows that stream several replies. Like StartReply
+// it holds a stream slot of subject until the generation finishes.
+func (e *Engine) Start(subject ratelimit.Subject, convID uuid.UUID, fn func(g *generation.Generation)) (*generation.Generation, error) {
+	release, err := e.acquire(subject)
+	if err != nil {
+		return nil, err
+	}
+	g, err := e.generations.Start(convID, func(g *generation.Generation) {
+		defer release()
+		fn(g)
+	})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return g, nil
+}
+
+// acquire takes a stream slot of subject
+func (e *Engine) acquire(subject ratelimit.Subject) (release func(), err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return e.limiter.AcquireStream(ctx, subject)
+}
+
+// Generation returns a running or recently finished generation
+func (e *Engine) Generation(id uuid.UUID) (*generation.Generation, bool) {
+	return e.generations.Get(id)
+}
+
+// Cancel aborts a running generation. It reports whether one was found.
+func (e *Engine) Cancel(id uuid.UUID) bool {
+	return e.generations.Cancel(id)
+}
+
+// PublishChange tells every watcher of a conversation, on any instance, that it changed
+func (e *Engine) PublishChange(convID uuid.UUID, change string) {
+	ev := &ConversationChanged{ConversationID: convID.String(), Change: change}
+	ev.stamp(ev.EventType())
+	e.generations.PublishChange(convID, string(ev.EventType()), ev)
+}
+
+// CancelConversation aborts the generation running in a conversation, if any
+func (e *Engine) CancelConversation(convID uuid.UUID) bool {
+	return e.generations.CancelConversation(convID)
+}
+
+// Reply announces a reply, streams and persists it, and publishes how it ended
+func (e *Engine) Reply(g *generation.Generation, ms *service.MessageStream, hooks Hooks) {
+	Publish(g, &MessageStart{
+		GenerationID:       g.ID.String(),
+		ConversationID:     ms.ConversationID.String(),
+		UserMessageID:      ms.UserMessageID.String(),
+		AssistantMessageID: ms.AssistantMessageID.String(),
+		Model:              ms.Model,
+		CreatedAt:          time.Now().Unix(),
+	})
+
+	var afterChunk func()
+	if hooks.AfterChunk != nil {
+		afterChunk = func() { hooks.AfterChunk(g) }
+	}
+	outcome := e.StreamTurn(g, ms, 0, afterChunk)
+	if outcome.Err != nil {
+		Publish(g, NewError(outcome.Err))
+		return
+	}
+	if hooks.BeforeComplete != nil {
+		hooks.BeforeComplete(g)
+	}
+
+	complete := &MessageComplete{
+		FinishReason: outcome.FinishReason,
+		Status:       outcome.Status,
+	}
+	if outcome.Message != nil {
+		complete.MessageID = outcome.Message.ID.String()
+	}
+	Publish(g, complete)
+}
+
+// StreamTurn publishes the content deltas and usage of a completion and always persists
+// the reply: complete, interrupted by a cancel or abandoned stream, or cut short by an error.
+// index tells replay turns apart. afterChunk, if set, runs after every chunk.
+func (e *Engine) StreamTurn(g *generation.Generation, ms *service.MessageStream, index int, afterChunk func()) Outcome {
+	stream, acc := ms.Stream, ms.Acc
+	defer stream.Close()
+	defer ms.Abort(nil)
+
+	// Abort a provider stream that stalls, e.g. a hung connection during a long pause
+	var (
+		watchdog *time.Timer
+		idle     atomic.Bool
+	)
+	if e.opts.UpstreamIdleTimeout > 0 {
+		watchdog = time.AfterFunc(e.opts.UpstreamIdleTimeout, func() {
+			idle.Store(true)
+			ms.Abort(ErrUpstreamIdle)
+		})
+		defer watchdog.Stop()
+	}
+
+	for stream.Next() {
+		if watchdog != nil {
+			watchdog.Reset(e.opts.UpstreamIdleTimeout)
+		}
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			Publish(g, &ContentDelta{
+				Index: index,
+				Delta: Delta{Type: "text_delta", Value: chunk.Choices[0].Delta.Content},
+			})
+		}
+		if call, ok := acc.JustFinishedToolCall(); ok {
+			Publish(g, &ToolCall{
+				Index:      index,
+				ToolCallID: call.ID,
+				Name:       call.Name,
+				Arguments:  call.Arguments,
+			})
+		}
+		// The final chunk carries the usage of the whole request and no choices
+		if chunk.Usage.TotalTokens > 0 {
+			Publish(g, &Usage{
+				Index:            index,
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			})
+		}
+		if afterChunk != nil {
+			afterChunk()
+		}
+	}
+
+	save := service.MessageSaveParams{
+		ID:             ms.AssistantMessageID,
+		ConversationID: ms.ConversationID,
+		Status:         models.MessageStatusComplete,
+	}
+	if len(acc.Choices) > 0 {
+		save.Content = acc.Choices[0].Message.Content
+		save.FinishReason = acc.Choices[0].FinishReason
+	}
+
+	var outcome Outcome
+	ctx := g.Context()
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		save.Status = models.MessageStatusInterrupted
+		save.FinishReason = models.FinishReasonCancelled
+		save.Error = context.Cause(ctx).Error()
+	case stream.Err() != nil:
+		err := stream.Err()
+		if idle.Load() {
+			err = fmt.Errorf("%w: nothing received for %s", ErrUpstreamIdle, e.opts.UpstreamIdleTimeout)
+		}
+		save.Status = models.MessageStatusError
+		save.FinishReason = models.FinishReasonError
+		save.Error = err.Error()
+		outcome.Err = &UpstreamError{Err: err}
+	}
+	outcome.FinishReason = save.FinishReason
+	outcome.Status = save.Status
+
+	e.messages.RecordUsage(context.Background(), ms, acc.Usage, save.Content)
+	message, err := e.messages.SaveAssistantMessage(context.Background(), save)
+	if err != nil {
+		outcome.Err = errors.Join(outcome.Err, &PersistenceError{Err: err})
+	}
+	outcome.Message = message
+	return outcome
+}
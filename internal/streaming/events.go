@@ -0,0 +1,261 @@
+package streaming
+
+import (
+	"errors"
+
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/typescript-any/llm-playground/internal/ratelimit"
+	"github.com/typescript-any/llm-playground/internal/repository"
+	service "github.com/typescript-any/llm-playground/internal/services"
+)
+
+// SchemaVersion is sent with every event. It is bumped whenever a payload changes incompatibly.
+const SchemaVersion = 1
+
+// EventType names an event. It is both the SSE event name and the "type" field of the payload.
+type EventType string
+
+const (
+	EventMessageStart       EventType = "message_start"
+	EventContentDelta       EventType = "content_block_delta"
+	EventToolCall           EventType = "tool_call"
+	EventUsage              EventType = "usage"
+	EventMessageComplete    EventType = "message_complete"
+	EventError              EventType = "error"
+	EventConversationTitle  EventType = "conversation_title"
+	EventReplayStart        EventType = "replay_start"
+	EventTurnStart          EventType = "turn_start"
+	EventTurnComplete       EventType = "turn_complete"
+	EventReplayComplete     EventType = "replay_complete"
+	EventConversationChange EventType = "conversation_changed"
+)
+
+// Conversation changes carried by conversation_changed events
+const (
+	ChangeUpdated  = "updated"  // title or settings
+	ChangeMessages = "messages" // messages added outside of a stream
+	ChangeSummary  = "summary"
+)
+
+// Error codes carried by error events
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeNotFound         = "not_found"
+	CodeForbidden        = "forbidden"
+	CodeConflict         = "conflict"
+	CodeUpstream         = "upstream_error"
+	CodePersistence      = "persistence_error"
+	CodeInternal         = "internal_error"
+	CodeGenerationActive = "generation_active"
+	CodeOverloaded       = "overloaded"
+	CodeTooManyClients   = "too_many_subscribers"
+	CodeRateLimited      = "rate_limited"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeEventsLost       = "events_lost" // resume from the last event id to catch up
+)
+
+// Header is embedded in every payload and filled in by Publish
+type Header struct {
+	Type    EventType `json:"type"`
+	Version int       `json:"v"`
+}
+
+func (h *Header) stamp(t EventType) {
+	h.Type = t
+	h.Version = SchemaVersion
+}
+
+// Payload is an event body of the streaming schema
+type Payload interface {
+	EventType() EventType
+	stamp(t EventType)
+}
+
+// Publish stamps the payload header and appends the event to the generation
+func Publish(g *generation.Generation, p Payload) {
+	p.stamp(p.EventType())
+	g.Publish(string(p.EventType()), p)
+}
+
+// MessageStart opens an assistant reply. Both message ids are final: the user
+// message is already stored and the reply is saved under AssistantMessageID.
+type MessageStart struct {
+	Header
+	GenerationID       string `json:"generation_id"`
+	ConversationID     string `json:"conversation_id"`
+	UserMessageID      string `json:"user_message_id"`
+	AssistantMessageID string `json:"assistant_message_id"`
+	Model              string `json:"model"`
+	CreatedAt          int64  `json:"created_at"`
+}
+
+func (*MessageStart) EventType() EventType { return EventMessageStart }
+
+// ContentDelta is a piece of reply text. Index is the turn it belongs to, 0 outside replays.
+type ContentDelta struct {
+	Header
+	Index int   `json:"index"`
+	Delta Delta `json:"delta"`
+}
+
+type Delta struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func (*ContentDelta) EventType() EventType { return EventContentDelta }
+
+// ToolCall is a function call requested by the model, sent once its arguments are complete
+type ToolCall struct {
+	Header
+	Index      int    `json:"index"`
+	ToolCallID string `json:"tool_call_id"`
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments"`
+}
+
+func (*ToolCall) EventType() EventType { return EventToolCall }
+
+// Usage reports the token counts of a reply once the provider sends them
+type Usage struct {
+	Header
+	Index            int   `json:"index"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+func (*Usage) EventType() EventType { return EventUsage }
+
+// MessageComplete closes an assistant reply. FinishReason is the provider's
+// (stop, length, tool_calls, ...) or cancelled / error for partial replies.
+type MessageComplete struct {
+	Header
+	MessageID    string `json:"message_id,omitempty"`
+	FinishReason string `json:"finish_reason"`
+	Status       string `json:"status"`
+}
+
+func (*MessageComplete) EventType() EventType { return EventMessageComplete }
+
+// Error ends a stream that could not complete. Code is one of the Code constants.
+type Error struct {
+	Header
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (*Error) EventType() EventType { return EventError }
+
+// ConversationTitle announces a generated conversation title
+type ConversationTitle struct {
+	Header
+	ConversationID string `json:"conversation_id"`
+	Title          string `json:"title"`
+}
+
+func (*ConversationTitle) EventType() EventType { return EventConversationTitle }
+
+type ReplayStart struct {
+	Header
+	GenerationID         string `json:"generation_id"`
+	ConversationID       string `json:"conversation_id"`
+	SourceConversationID string `json:"source_conversation_id"`
+	Model                string `json:"model"`
+	Turns                int    `json:"turns"`
+}
+
+func (*ReplayStart) EventType() EventType { return EventReplayStart }
+
+type TurnStart struct {
+	Header
+	Index              int    `json:"index"`
+	Content            string `json:"content"`
+	UserMessageID      string `json:"user_message_id"`
+	AssistantMessageID string `json:"assistant_message_id"`
+}
+
+func (*TurnStart) EventType() EventType { return EventTurnStart }
+
+type TurnComplete struct {
+	Header
+	Index        int    `json:"index"`
+	MessageID    string `json:"message_id,omitempty"`
+	FinishReason string `json:"finish_reason"`
+	Status       string `json:"status"`
+}
+
+func (*TurnComplete) EventType() EventType { return EventTurnComplete }
+
+type ReplayComplete struct {
+	Header
+	ConversationID       string `json:"conversation_id"`
+	SourceConversationID string `json:"source_conversation_id"`
+	FinishReason         string `json:"finish_reason"`
+}
+
+func (*ReplayComplete) EventType() EventType { return EventReplayComplete }
+
+// ConversationChanged tells conversation watchers to refetch what Change names.
+// It is only sent on conversation streams, between generations.
+type ConversationChanged struct {
+	Header
+	ConversationID string `json:"conversation_id"`
+	Change         string `json:"change"`
+}
+
+func (*ConversationChanged) EventType() EventType { return EventConversationChange }
+
+// NewError builds an error event, deriving the code from err
+func NewError(err error) *Error {
+	return &Error{Code: ErrorCode(err), Message: err.Error()}
+}
+
+// ErrorCode maps service and stream errors onto error codes
+func ErrorCode(err error) string {
+	var (
+		upstream    *UpstreamError
+		persistence *PersistenceError
+		exceeded    *ratelimit.Exceeded
+	)
+	switch {
+	case errors.As(err, &upstream):
+		return CodeUpstream
+	case errors.As(err, &persistence):
+		return CodePersistence
+	case errors.As(err, &exceeded):
+		return CodeRateLimited
+	case errors.Is(err, repository.ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, service.ErrForbidden):
+		return CodeForbidden
+	case errors.Is(err, service.ErrInvalidSettings), errors.Is(err, service.ErrInvalidInput), errors.Is(err, service.ErrContextTooLong):
+		return CodeInvalidRequest
+	case errors.Is(err, service.ErrNothingToRegenerate), errors.Is(err, service.ErrProviderKeyUnavailable):
+		return CodeConflict
+	case errors.Is(err, generation.ErrConversationBusy):
+		return CodeGenerationActive
+	case errors.Is(err, generation.ErrTooManyGenerations):
+		return CodeOverloaded
+	case errors.Is(err, generation.ErrTooManySubscribers):
+		return CodeTooManyClients
+	case errors.Is(err, service.ErrQuotaExceeded):
+		return CodeQuotaExceeded
+	case errors.Is(err, generation.ErrEventsLost):
+		return CodeEventsLost
+	default:
+		return CodeInternal
+	}
+}
+
+// UpstreamError wraps an error returned by the model provider mid-stream
+type UpstreamError struct{ Err error }
+
+func (e *UpstreamError) Error() string { return e.Err.Error() }
+func (e *UpstreamError) Unwrap() error { return e.Err }
+
+// PersistenceError wraps a failure to save the reply
+type PersistenceError struct{ Err error }
+
+func (e *PersistenceError) Error() string { return "failed to save reply: " + e.Err.Error() }
+func (e *PersistenceError) Unwrap() error { return e.Err }
@@ -0,0 +1,142 @@
+package streaming
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+)
+
+// output receives the events a client follows. Transports that need to act while no
+// event arrives, to flush a batch or send a heartbeat, return a timer channel from due.
+type output interface {
+	emit(g *generation.Generation, events []generation.Event) error
+	due() <-chan time.Time
+	tick() error
+}
+
+// emitFunc adapts an emit callback into an output with nothing to do between events
+type emitFunc func(g *generation.Generation, events []generation.Event) error
+
+func (f emitFunc) emit(g *generation.Generation, events []generation.Event) error {
+	return f(g, events)
+}
+
+func (emitFunc) due() <-chan time.Time { return nil }
+func (emitFunc) tick() error           { return nil }
+
+// Follow hands the events of g after lastID to emit, then follows the generation live
+// until it finishes, emit fails or stop is closed. Every transport reads generations
+// through it so they all see the same event stream.
+func (e *Engine) Follow(g *generation.Generation, lastID uint64, stop <-chan struct{}, emit func([]generation.Event) error) error {
+	release, err := e.generations.Subscribe(g)
+	if err != nil {
+		return err
+	}
+	follow(g, release, lastID, stop, emitFunc(func(_ *generation.Generation, events []generation.Event) error {
+		return emit(events)
+	}))
+	return nil
+}
+
+// follow runs a subscription taken on g until the generation finishes, out fails or stop is closed.
+// It reports whether the generation was followed to its end.
+func follow(g *generation.Generation, release func(disconnected bool), lastID uint64, stop <-chan struct{}, out output) bool {
+	for {
+		events, wake, finished := g.Events(lastID)
+		if len(events) > 0 {
+			if err := out.emit(g, events); err != nil {
+				release(true)
+				return false
+			}
+			lastID = events[len(events)-1].ID
+		}
+		if finished {
+			release(false)
+			return true
+		}
+
+		select {
+		case <-wake:
+		case <-out.due():
+			if err := out.tick(); err != nil {
+				release(true)
+				return false
+			}
+		case <-stop:
+			release(true)
+			return false
+		}
+	}
+}
+
+// FollowConversation hands emit the events of every generation of a conversation, in
+// order, until emit fails or stop is closed. Without a cursor it starts with the generation
+// in progress, if any; with one it resumes right after the cursor's event. Conversation
+// changes are handed over between generations with a nil generation.
+func (e *Engine) FollowConversation(convID uuid.UUID, from Cursor, stop <-chan struct{}, emit func(*generation.Generation, []generation.Event) error) error {
+	release, err := e.generations.Watch(convID)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	e.followConversation(convID, from, stop, emitFunc(emit))
+	return nil
+}
+
+// followConversation runs a watch taken on a conversation for FollowConversation
+func (e *Engine) followConversation(convID uuid.UUID, from Cursor, stop <-chan struct{}, out output) {
+	// Changes are only delivered while watching; earlier ones are never replayed
+	_, lastChange := e.generations.Changes(convID, 0)
+
+	var done uuid.UUID
+	if from.GenerationID != uuid.Nil {
+		if g, ok := e.generations.Get(from.GenerationID); ok && !e.followGeneration(g, from.EventID, stop, out) {
+			return
+		}
+		done = from.GenerationID
+	} else if g, _ := e.generations.Current(convID); g != nil && g.Finished() {
+		done = g.ID
+	}
+
+	for {
+		g, wake := e.generations.Current(convID)
+
+		var changes []generation.Event
+		changes, lastChange = e.generations.Changes(convID, lastChange)
+		if len(changes) > 0 {
+			if err := out.emit(nil, changes); err != nil {
+				return
+			}
+		}
+
+		if g != nil && g.ID != done {
+			if !e.followGeneration(g, 0, stop, out) {
+				return
+			}
+			done = g.ID
+			continue
+		}
+
+		select {
+		case <-wake:
+		case <-out.due():
+			if err := out.tick(); err != nil {
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// followGeneration follows one generation for FollowConversation. A generation that
+// already has too many subscribers is skipped rather than ending the watch.
+func (e *Engine) followGeneration(g *generation.Generation, lastID uint64, stop <-chan struct{}, out output) bool {
+	release, err := e.generations.Subscribe(g)
+	if err != nil {
+		return true
+	}
+	return follow(g, release, lastID, stop, out)
+}
@@ -0,0 +1,143 @@
+package streaming
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/typescript-any/llm-playground/internal/generation"
+	"github.com/valyala/fasthttp"
+)
+
+// Cursor is the position of a conversation stream client: an event of one generation.
+// On the wire it is the SSE id "<generation id>:<event id>".
+type Cursor struct {
+	GenerationID uuid.UUID
+	EventID      uint64
+}
+
+func (c Cursor) String() string {
+	return fmt.Sprintf("%s:%d", c.GenerationID, c.EventID)
+}
+
+// ParseCursor parses the id of a conversation stream event. An empty id is the zero cursor.
+func ParseCursor(raw string) (Cursor, error) {
+	if raw == "" {
+		return Cursor{}, nil
+	}
+	genID, eventID, ok := strings.Cut(raw, ":")
+	if !ok {
+		return Cursor{}, fmt.Errorf("invalid cursor %q", raw)
+	}
+	var (
+		c   Cursor
+		err error
+	)
+	if c.GenerationID, err = uuid.Parse(genID); err != nil {
+		return Cursor{}, err
+	}
+	if c.EventID, err = strconv.ParseUint(eventID, 10, 64); err != nil {
+		return Cursor{}, err
+	}
+	return c, nil
+}
+
+// setSSEHeaders prepares the response for Server-Sent Events
+func setSSEHeaders(c *fiber.Ctx) {
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("Access-Control-Allow-Origin", "*") // Add CORS if needed
+}
+
+// Serve answers the request with the events of g after lastID in the protocol the client
+// negotiated: the AI SDK data stream when WantsDataStream, Server-Sent Events otherwise.
+func (e *Engine) Serve(c *fiber.Ctx, g *generation.Generation, lastID uint64) error {
+	if WantsDataStream(c) {
+		return e.serve(c, g, lastID, setDataStreamHeaders, &dataStreamFormat{})
+	}
+	return e.ServeSSE(c, g, lastID)
+}
+
+// ServeSSE answers the request with the events of g after lastID as Server-Sent Events,
+// then follows the generation live until it finishes or the client goes away.
+func (e *Engine) ServeSSE(c *fiber.Ctx, g *generation.Generation, lastID uint64) error {
+	return e.serve(c, g, lastID, setSSEHeaders, sseFormat{})
+}
+
+func (e *Engine) serve(c *fiber.Ctx, g *generation.Generation, lastID uint64, setHeaders func(*fiber.Ctx), f format) error {
+	release, err := e.generations.Subscribe(g)
+	if err != nil {
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	}
+
+	setHeaders(c)
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		out := newEventWriter(w, e.opts, f)
+		defer out.stop()
+		if follow(g, release, lastID, nil, out) {
+			out.flush()
+		}
+	}))
+	return nil
+}
+
+// ServeConversationSSE answers the request with the events of every generation of a
+// conversation, resuming after from, until the client goes away. There is no data stream
+// counterpart, as that protocol has no room for several generations or cursors.
+func (e *Engine) ServeConversationSSE(c *fiber.Ctx, convID uuid.UUID, from Cursor) error {
+	release, err := e.generations.Watch(convID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	}
+
+	setSSEHeaders(c)
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer release()
+		out := newSSEWriter(w, e.opts, true)
+		defer out.stop()
+		e.followConversation(convID, from, nil, out)
+	}))
+	return nil
+}
+
+// sseFormat encodes events as Server-Sent Events. With cursors, ids are conversation
+// cursors rather than event ids.
+type sseFormat struct {
+	cursors bool
+}
+
+func newSSEWriter(w *bufio.Writer, opts Options, cursors bool) *eventWriter {
+	return newEventWriter(w, opts, sseFormat{cursors: cursors})
+}
+
+func (f sseFormat) encode(w *bufio.Writer, g *generation.Generation, ev generation.Event) (int, bool) {
+	var n int
+	switch {
+	case !f.cursors:
+		n, _ = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Name, ev.Data)
+	case g == nil:
+		// Conversation changes are not resumable and leave the cursor alone
+		n, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Name, ev.Data)
+	default:
+		n, _ = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", Cursor{g.ID, ev.ID}, ev.Name, ev.Data)
+	}
+	return n, ev.Name != string(EventContentDelta)
+}
+
+func (sseFormat) heartbeat() string {
+	return ": heartbeat\n\n"
+}
+
+// LastEventID reads the id of the last event a resuming client saw, from the
+// Last-Event-ID header or, for clients that cannot set headers, the last_event_id query.
+func LastEventID(c *fiber.Ctx) string {
+	raw := c.Get("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	return raw
+}
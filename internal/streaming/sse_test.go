@@ -0,0 +1,115 @@
+package streaming
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/typescript-any/llm-playground/internal/generation"
+)
+
+// countingWriter counts the writes that reach the underlying file, one syscall each
+type countingWriter struct {
+	w      io.Writer
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return c.w.Write(p)
+}
+
+// deltaEvents returns n token-sized content deltas
+func deltaEvents(n int) []generation.Event {
+	events := make([]generation.Event, n)
+	for i := range events {
+		data, _ := json.Marshal(&ContentDelta{
+			Header: Header{Type: EventContentDelta, Version: SchemaVersion},
+			Delta:  Delta{Type: "text_delta", Value: " token"},
+		})
+		events[i] = generation.Event{ID: uint64(i + 1), Name: string(EventContentDelta), Data: data}
+	}
+	return events
+}
+
+// benchmarkSSE writes deltas one at a time, as they arrive from a fast provider,
+// into a pipe drained by another goroutine.
+func benchmarkSSE(b *testing.B, opts Options) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer r.Close()
+	go io.Copy(io.Discard, r)
+
+	cw := &countingWriter{w: w}
+	out := newSSEWriter(bufio.NewWriterSize(cw, 8192), opts, false)
+	defer out.stop()
+
+	events := deltaEvents(1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range events {
+			if err := out.emit(nil, events[j:j+1]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := out.flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	w.Close()
+
+	b.ReportMetric(float64(cw.writes)/float64(b.N), "writes/op")
+	b.ReportMetric(float64(len(events)*b.N)/b.Elapsed().Seconds(), "deltas/s")
+}
+
+func BenchmarkSSEFlushEveryDelta(b *testing.B) {
+	benchmarkSSE(b, Options{})
+}
+
+func BenchmarkSSECoalesce4KB(b *testing.B) {
+	benchmarkSSE(b, Options{CoalesceWindow: 25 * time.Millisecond, CoalesceBytes: 4096})
+}
+
+func TestSSEWriterFlushesControlEventsImmediately(t *testing.T) {
+	cw := &countingWriter{w: io.Discard}
+	out := newSSEWriter(bufio.NewWriter(cw), Options{CoalesceWindow: time.Minute, CoalesceBytes: 1 << 20}, false)
+	defer out.stop()
+
+	if err := out.emit(nil, deltaEvents(3)); err != nil {
+		t.Fatal(err)
+	}
+	if cw.writes != 0 {
+		t.Fatalf("deltas were flushed before the window: %d writes", cw.writes)
+	}
+
+	complete := generation.Event{ID: 4, Name: string(EventMessageComplete), Data: []byte(`{}`)}
+	if err := out.emit(nil, []generation.Event{complete}); err != nil {
+		t.Fatal(err)
+	}
+	if cw.writes != 1 {
+		t.Fatalf("expected one flush for the batch, got %d writes", cw.writes)
+	}
+}
+
+func TestSSEWriterHeartbeat(t *testing.T) {
+	r, w := io.Pipe()
+	out := newSSEWriter(bufio.NewWriter(w), Options{HeartbeatInterval: 10 * time.Millisecond}, false)
+	defer out.stop()
+
+	go func() {
+		<-out.due()
+		out.tick()
+		w.Close()
+	}()
+
+	got, _ := io.ReadAll(r)
+	if string(got) != ": heartbeat\n\n" {
+		t.Fatalf("unexpected heartbeat %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package streaming
+
+import (
+	"bufio"
+	"time"
+
+	"github.com/typescript-any/llm-playground/internal/generation"
+)
+
+// format encodes events for one streaming HTTP protocol
+type format interface {
+	// encode writes ev and returns the bytes written and whether it must be flushed right away
+	encode(w *bufio.Writer, g *generation.Generation, ev generation.Event) (int, bool)
+	// heartbeat is written after a silence, empty when the protocol has no such thing
+	heartbeat() string
+}
+
+// eventWriter writes events to a streaming response. Content deltas are coalesced: they
+// are flushed once CoalesceBytes are buffered or CoalesceWindow has passed, while every
+// other event is flushed right away. A heartbeat is sent after HeartbeatInterval of silence
+// so proxies keep the connection open and a gone client is noticed.
+type eventWriter struct {
+	w      *bufio.Writer
+	opts   Options
+	format format
+
+	pending   int       // bytes written since the last flush
+	flushAt   time.Time // when pending deltas must be flushed, zero when nothing is pending
+	lastFlush time.Time
+	timer     *time.Timer
+}
+
+func newEventWriter(w *bufio.Writer, opts Options, f format) *eventWriter {
+	t := time.NewTimer(time.Hour)
+	t.Stop()
+	if f.heartbeat() == "" {
+		opts.HeartbeatInterval = 0
+	}
+	return &eventWriter{
+		w:         w,
+		opts:      opts,
+		format:    f,
+		lastFlush: time.Now(),
+		timer:     t,
+	}
+}
+
+func (s *eventWriter) emit(g *generation.Generation, events []generation.Event) error {
+	urgent := false
+	for _, ev := range events {
+		n, flush := s.format.encode(s.w, g, ev)
+		s.pending += n
+		urgent = urgent || flush
+	}
+
+	if urgent || s.opts.CoalesceWindow <= 0 || s.pending >= s.opts.CoalesceBytes {
+		return s.flush()
+	}
+	if s.flushAt.IsZero() {
+		s.flushAt = time.Now().Add(s.opts.CoalesceWindow)
+	}
+	return nil
+}
+
+// due returns a channel that fires when pending deltas must be flushed or a heartbeat is due
+func (s *eventWriter) due() <-chan time.Time {
+	var next time.Time
+	if !s.flushAt.IsZero() {
+		next = s.flushAt
+	} else if s.opts.HeartbeatInterval > 0 {
+		next = s.lastFlush.Add(s.opts.HeartbeatInterval)
+	} else {
+		return nil
+	}
+	s.timer.Reset(time.Until(next))
+	return s.timer.C
+}
+
+func (s *eventWriter) tick() error {
+	if s.flushAt.IsZero() {
+		if _, err := s.w.WriteString(s.format.heartbeat()); err != nil {
+			return err
+		}
+	}
+	return s.flush()
+}
+
+func (s *eventWriter) flush() error {
+	s.pending = 0
+	s.flushAt = time.Time{}
+	s.lastFlush = time.Now()
+	return s.w.Flush()
+}
+
+func (s *eventWriter) stop() {
+	s.timer.Stop()
+}
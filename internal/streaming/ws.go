@@ -0,0 +1,36 @@
+package streaming
+
+import (
+	"encoding/json"
+
+	"github.com/typescript-any/llm-playground/internal/generation"
+)
+
+// Frame is the WebSocket envelope of one generation event, or of a transport reply such as pong
+type Frame struct {
+	Event        string          `json:"event"`
+	ID           uint64          `json:"id,omitempty"`
+	GenerationID string          `json:"generation_id,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty"`
+}
+
+// NewFrame wraps an event of g, or a conversation change when g is nil
+func NewFrame(g *generation.Generation, ev generation.Event) Frame {
+	if g == nil {
+		return Frame{Event: ev.Name, Data: ev.Data}
+	}
+	return Frame{
+		Event:        ev.Name,
+		ID:           ev.ID,
+		GenerationID: g.ID.String(),
+		Data:         ev.Data,
+	}
+}
+
+// ErrorFrame is an error event that belongs to no generation, e.g. a rejected client frame
+func ErrorFrame(code, message string) Frame {
+	ev := &Error{Code: code, Message: message}
+	ev.stamp(ev.EventType())
+	data, _ := json.Marshal(ev)
+	return Frame{Event: string(EventError), Data: data}
+}
@@ -0,0 +1,80 @@
+// Package tokenizer counts the tokens of chat messages with the BPE encoding of the
+// model they are sent to. The encodings are embedded, so nothing is downloaded.
+package tokenizer
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+const (
+	// fallbackEncoding counts for models whose encoding is unknown, such as those of
+	// other providers. It is the encoding of current OpenAI models and a fair estimate.
+	fallbackEncoding = tiktoken.MODEL_O200K_BASE
+
+	// MessageOverhead is what framing one chat message takes besides its content
+	MessageOverhead = 3
+	// ReplyPriming is what priming the reply takes at the end of every prompt
+	ReplyPriming = 3
+)
+
+func init() {
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// Tokenizer counts tokens with one encoding
+type Tokenizer struct {
+	encoding *tiktoken.Tiktoken
+}
+
+var (
+	mu        sync.Mutex
+	encodings = map[string]*Tokenizer{}
+)
+
+// For returns the tokenizer of a model. Model ids may carry a provider prefix, as in
+// openai/gpt-4o. Encodings are loaded once and shared.
+func For(model string) *Tokenizer {
+	name := fallbackEncoding
+	model = model[strings.LastIndex(model, "/")+1:]
+	if enc, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		name = enc
+	} else {
+		for prefix, enc := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+			if strings.HasPrefix(model, prefix) {
+				name = enc
+				break
+			}
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if t, ok := encodings[name]; ok {
+		return t
+	}
+	encoding, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		// The embedded encodings always load; this is a broken build
+		panic("tokenizer: loading " + name + ": " + err.Error())
+	}
+	t := &Tokenizer{encoding: encoding}
+	encodings[name] = t
+	return t
+}
+
+// Count returns the tokens of text. Special tokens in text count as plain text.
+func (t *Tokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(t.encoding.EncodeOrdinary(text))
+}
+
+// Message returns the tokens one chat message with content takes in a prompt
+func (t *Tokenizer) Message(content string) int {
+	return t.Count(content) + MessageOverhead
+}
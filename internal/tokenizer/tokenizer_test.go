@@ -0,0 +1,42 @@
+package tokenizer
+
+import "testing"
+
+func TestCount(t *testing.T) {
+	cases := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		{"gpt-4o", "", 0},
+		{"gpt-4o", "hello world", 2},
+		{"openai/gpt-4o-mini", "tiktoken is great!", 6},
+		{"gpt-4", "tiktoken is great!", 6},
+		{"gpt-3.5-turbo-0125", "antidisestablishmentarianism", 6},
+	}
+	for _, c := range cases {
+		if got := For(c.model).Count(c.text); got != c.want {
+			t.Errorf("For(%q).Count(%q) = %d, want %d", c.model, c.text, got, c.want)
+		}
+	}
+}
+
+func TestForSharesEncodings(t *testing.T) {
+	if For("gpt-4o") != For("openai/gpt-4o-2024-08-06") {
+		t.Fatal("models of one encoding got different tokenizers")
+	}
+	if For("gpt-4o") == For("gpt-4") {
+		t.Fatal("o200k and cl100k models share a tokenizer")
+	}
+	// Models of other providers are counted with the fallback encoding
+	if For("anthropic/claude-3.5-sonnet") != For("gpt-4o") {
+		t.Fatal("unknown model did not fall back to o200k_base")
+	}
+}
+
+func TestMessage(t *testing.T) {
+	tok := For("gpt-4o")
+	if got, want := tok.Message("hello world"), tok.Count("hello world")+MessageOverhead; got != want {
+		t.Fatalf("Message = %d, want %d", got, want)
+	}
+}
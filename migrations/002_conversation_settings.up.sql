@@ -0,0 +1,2 @@
+ALTER TABLE conversations
+    ADD COLUMN settings JSONB NOT NULL DEFAULT '{}'::jsonb;
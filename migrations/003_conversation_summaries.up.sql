@@ -0,0 +1,7 @@
+CREATE TABLE conversation_summaries (
+    conversation_id UUID PRIMARY KEY REFERENCES conversations(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    summarized_until TIMESTAMP NOT NULL,
+    message_count INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+ALTER TABLE conversations
+    ADD COLUMN source_conversation_id UUID REFERENCES conversations(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN finish_reason TEXT;
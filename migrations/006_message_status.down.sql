@@ -0,0 +1,3 @@
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS error,
+    DROP COLUMN IF EXISTS status;
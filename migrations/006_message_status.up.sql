@@ -0,0 +1,3 @@
+ALTER TABLE messages
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'complete',
+    ADD COLUMN error TEXT;
@@ -0,0 +1,8 @@
+-- Notices too large for a NOTIFY payload are stored here and referenced by id
+CREATE TABLE stream_payloads (
+    id BIGSERIAL PRIMARY KEY,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_stream_payloads_created_at ON stream_payloads (created_at);
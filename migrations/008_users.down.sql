@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS user_sessions;
+DROP INDEX IF EXISTS idx_conversations_user_id;
+ALTER TABLE conversations DROP CONSTRAINT IF EXISTS fk_conversations_user;
+DROP TABLE IF EXISTS users;
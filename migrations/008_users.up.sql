@@ -0,0 +1,31 @@
+CREATE TABLE users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_users_email ON users (LOWER(email));
+
+-- Conversations created before accounts existed get a placeholder owner that cannot log in
+INSERT INTO users (id, email, password_hash)
+SELECT DISTINCT user_id, user_id::text || '@legacy.invalid', ''
+FROM conversations;
+
+ALTER TABLE conversations
+    ADD CONSTRAINT fk_conversations_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_conversations_user_id ON conversations (user_id, created_at DESC);
+
+-- Bearer tokens issued at login, stored as SHA-256 hashes
+CREATE TABLE user_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions (user_id);
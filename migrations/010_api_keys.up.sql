@@ -0,0 +1,18 @@
+-- Long-lived credentials for scripts and CI. The secret part of a key is only stored
+-- as a salted SHA-256 hash; prefix is the visible part that identifies the key.
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL DEFAULT 'personal',
+    prefix TEXT NOT NULL UNIQUE,
+    salt BYTEA NOT NULL,
+    key_hash BYTEA NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id, created_at DESC);
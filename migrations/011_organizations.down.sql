@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_conversations_workspace_id;
+ALTER TABLE conversations DROP COLUMN IF EXISTS workspace_id;
+DROP TABLE IF EXISTS workspaces;
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,38 @@
+-- Shared team spaces. Members of an organization reach the conversations of its
+-- workspaces according to their role.
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE memberships (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'viewer')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX idx_memberships_user_id ON memberships (user_id);
+
+-- allowed_models restricts the models conversations may use; empty allows any.
+-- default_settings sit under the settings of every conversation in the workspace.
+CREATE TABLE workspaces (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    allowed_models TEXT[] NOT NULL DEFAULT '{}',
+    default_settings JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_workspaces_org_id ON workspaces (org_id);
+
+ALTER TABLE conversations
+    ADD COLUMN workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_conversations_workspace_id ON conversations (workspace_id, created_at DESC);
@@ -0,0 +1,11 @@
+-- Sign-ins started against the OIDC provider and not yet completed by its callback.
+-- The state is stored as a SHA-256 hash; the nonce and PKCE verifier are single use.
+CREATE TABLE oidc_logins (
+    state_hash TEXT PRIMARY KEY,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_oidc_logins_expires_at ON oidc_logins (expires_at);
@@ -0,0 +1,19 @@
+-- Upstream provider keys that users and organizations bring themselves. The key is
+-- encrypted with AES-256-GCM under the master key named by key_id; hint is the end of
+-- the key, to recognise it.
+CREATE TABLE provider_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    org_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    key_id TEXT NOT NULL,
+    nonce BYTEA NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    hint TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((user_id IS NULL) <> (org_id IS NULL))
+);
+
+CREATE UNIQUE INDEX idx_provider_keys_user_id ON provider_keys (user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_provider_keys_org_id ON provider_keys (org_id) WHERE org_id IS NOT NULL;
+CREATE INDEX idx_provider_keys_key_id ON provider_keys (key_id);
@@ -0,0 +1,22 @@
+-- Shared state of the Postgres rate limit store, so every replica enforces the same
+-- limits. Losing it on a crash only resets the limits, hence UNLOGGED.
+-- A bucket is full again, the same as no bucket, at full_at.
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    full_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_full_at ON rate_limit_buckets (full_at);
+
+-- Streams running against a concurrency limit. The instance running a stream renews its
+-- lease, so the leases of an instance that died expire.
+CREATE UNLOGGED TABLE rate_limit_leases (
+    key TEXT NOT NULL,
+    lease UUID NOT NULL DEFAULT gen_random_uuid(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (key, lease)
+);
+
+CREATE INDEX idx_rate_limit_leases_expires_at ON rate_limit_leases (expires_at);
@@ -0,0 +1,48 @@
+-- Models the playground knows, with their prices in USD per million tokens and the
+-- size of their context window (0 when unknown). Calls to models missing here cost 0.
+CREATE TABLE model_registry (
+    id TEXT PRIMARY KEY,
+    input_price NUMERIC(12, 6) NOT NULL DEFAULT 0 CHECK (input_price >= 0),
+    output_price NUMERIC(12, 6) NOT NULL DEFAULT 0 CHECK (output_price >= 0),
+    context_window INTEGER NOT NULL DEFAULT 0 CHECK (context_window >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO model_registry (id, input_price, output_price, context_window) VALUES
+    ('gpt-4o', 2.5, 10, 128000),
+    ('gpt-4o-mini', 0.15, 0.6, 128000),
+    ('openai/gpt-4o', 2.5, 10, 128000),
+    ('openai/gpt-4o-mini', 0.15, 0.6, 128000);
+
+-- Daily and monthly token and cost budgets of a user or an organization; NULL limits
+-- do not apply. The row without user_id and org_id applies to every user without their
+-- own. Past a budget with a downgrade_model, requests go to that model instead.
+CREATE TABLE quotas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    org_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    daily_tokens BIGINT CHECK (daily_tokens >= 0),
+    monthly_tokens BIGINT CHECK (monthly_tokens >= 0),
+    daily_cost NUMERIC(14, 6) CHECK (daily_cost >= 0),
+    monthly_cost NUMERIC(14, 6) CHECK (monthly_cost >= 0),
+    downgrade_model TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (user_id IS NULL OR org_id IS NULL)
+);
+
+CREATE UNIQUE INDEX idx_quotas_user_id ON quotas (user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_quotas_org_id ON quotas (org_id) WHERE org_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_quotas_default ON quotas ((TRUE)) WHERE user_id IS NULL AND org_id IS NULL;
+
+-- Tokens and cost counted against quotas per UTC day and month. Estimates are charged
+-- before each call and corrected once its usage is known. subject is user:<id> or org:<id>.
+CREATE TABLE quota_usage (
+    subject TEXT NOT NULL,
+    period TEXT NOT NULL CHECK (period IN ('day', 'month')),
+    period_start DATE NOT NULL,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    cost NUMERIC(14, 6) NOT NULL DEFAULT 0,
+    PRIMARY KEY (subject, period, period_start)
+);
@@ -0,0 +1,19 @@
+-- Every LLM call with its token counts and its cost in USD, priced from the model
+-- registry when the call was made. kind is chat, title or summary. Rows outlive the
+-- users, organizations and conversations they refer to, so spending stays on the books.
+CREATE TABLE usage_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    org_id UUID REFERENCES organizations(id) ON DELETE SET NULL,
+    conversation_id UUID REFERENCES conversations(id) ON DELETE SET NULL,
+    model TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    cost NUMERIC(14, 8) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_usage_events_created_at ON usage_events (created_at);
+CREATE INDEX idx_usage_events_user_id ON usage_events (user_id, created_at);
+CREATE INDEX idx_usage_events_org_id ON usage_events (org_id, created_at);
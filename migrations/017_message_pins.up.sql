@@ -0,0 +1,5 @@
+-- Pinned messages are always sent to the model, however old, as long as they fit its
+-- context window
+ALTER TABLE messages ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_messages_pinned ON messages (conversation_id, created_at) WHERE pinned;